@@ -0,0 +1,113 @@
+package motospec
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// SiteAdapter is one source of specs. Its stages walk the site from a seed
+// down to Spec records, the last stage being the one emitting them.
+type SiteAdapter interface {
+	Name() string
+	// Stages names the stages in crawl order.
+	Stages() []string
+	// Inputs holds a zero value of the input type of every stage, in the
+	// order of Stages.
+	Inputs() []interface{}
+	StageFunc(stage string, config *Config) (ProcessFunc, error)
+	// ParseSeed maps a url onto the index of the stage consuming it.
+	ParseSeed(rawURL string) (Seed, error)
+}
+
+var Adapters = map[string]SiteAdapter{}
+
+func RegisterAdapter(adapter SiteAdapter) {
+	Adapters[adapter.Name()] = adapter
+}
+
+func AdapterNames() []string {
+	names := make([]string, 0, len(Adapters))
+	for name := range Adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AdapterStage returns the index of the stage taking item as input.
+func AdapterStage(adapter SiteAdapter, item interface{}) (int, bool) {
+	for i, input := range adapter.Inputs() {
+		if reflect.TypeOf(input) == reflect.TypeOf(item) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// DecodeInput restores a stage input recorded as JSON with its Go type
+// name, as Failure does.
+func DecodeInput(adapter SiteAdapter, typeName string, raw json.RawMessage) (Seed, error) {
+	for i, input := range adapter.Inputs() {
+		t := reflect.TypeOf(input)
+		if t.String() != typeName {
+			continue
+		}
+		value := reflect.New(t)
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return Seed{}, err
+		}
+		return Seed{Stage: i, Item: value.Elem().Interface()}, nil
+	}
+	return Seed{}, fmt.Errorf("%s takes no %s input", adapter.Name(), typeName)
+}
+
+func hasStage(adapter SiteAdapter, stage string) bool {
+	for _, name := range adapter.Stages() {
+		if name == stage {
+			return true
+		}
+	}
+	return false
+}
+
+type autoevolution struct{}
+
+func (autoevolution) Name() string {
+	return "autoevolution"
+}
+
+func (autoevolution) Stages() []string {
+	return StageNames
+}
+
+func (autoevolution) Inputs() []interface{} {
+	return []interface{}{"", BrandURL{}, ModelURL{}, MotoURL{}}
+}
+
+// StageFunc hands the spec stage an ImageStore when crawl.images_dir is
+// set.
+func (autoevolution) StageFunc(stage string, config *Config) (ProcessFunc, error) {
+	pf, ok := StageFuncs[stage]
+	if !ok {
+		return nil, fmt.Errorf("autoevolution has no stage %q", stage)
+	}
+	if stage == "spec" && config.Crawl.ImagesDir != "" {
+		store, err := NewImageStore(config.Crawl.ImagesDir)
+		if err != nil {
+			return nil, err
+		}
+		pf = NewSpecFunc(store)
+	}
+	return pf, nil
+}
+
+func (autoevolution) ParseSeed(rawURL string) (Seed, error) {
+	return ParseSeed(rawURL)
+}
+
+func init() {
+	RegisterAdapter(autoevolution{})
+	RegisterAdapter(motorcycleCom{})
+}
@@ -0,0 +1,71 @@
+package motospec
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAdapterNames(t *testing.T) {
+	if got := strings.Join(AdapterNames(), ","); got != "autoevolution,motorcycle.com" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestFailureSeed(t *testing.T) {
+	ducati := MakeOption{Name: "Ducati", Value: "12"}
+	trim := TrimOption{Year: YearOption{Category: CategoryOption{Make: ducati, Name: "Naked", Value: "3"}, Name: "2019", Value: "2019"}, Name: "Monster 821", Value: "77"}
+	tests := []struct {
+		name    string
+		adapter SiteAdapter
+		input   interface{}
+		stage   int
+	}{
+		{"autoevolution brand", Adapters["autoevolution"], BrandURL{Brand: "Ducati", URL: "https://www.autoevolution.com/moto/ducati/"}, ModelStage},
+		{"autoevolution spec", Adapters["autoevolution"], MotoURL{Brand: "Ducati", Model: "Ducati Monster", Moto: "Monster 821", Year: "2017",
+			URL: "https://www.autoevolution.com/moto/ducati-monster-821-2017.html"}, SpecStage},
+		{"autoevolution index", Adapters["autoevolution"], "https://www.autoevolution.com/moto/", BrandStage},
+		{"motorcycle.com make", Adapters["motorcycle.com"], ducati, 1},
+		{"motorcycle.com trim", Adapters["motorcycle.com"], trim, 4},
+		{"motorcycle.com search", Adapters["motorcycle.com"], "http://www.motorcycle.com/specs/", 0},
+	}
+	for _, test := range tests {
+		failure, err := NewFailure(&StageError{Input: test.input, Err: errors.New("timeout")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		seed, err := failure.Seed(test.adapter)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if seed.Stage != test.stage || !reflect.DeepEqual(seed.Item, test.input) {
+			t.Errorf("%s: got %d %#v, want %d %#v", test.name, seed.Stage, seed.Item, test.stage, test.input)
+		}
+		if stage, ok := AdapterStage(test.adapter, test.input); !ok || stage != test.stage {
+			t.Errorf("%s: AdapterStage %d %v", test.name, stage, ok)
+		}
+	}
+	if _, err := DecodeInput(Adapters["autoevolution"], "motospec.TrimOption", []byte(`{}`)); err == nil {
+		t.Error("autoevolution decoded a motorcycle.com input")
+	}
+}
+
+func TestMotorcycleParseSeed(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"http://www.motorcycle.com/specs/", true},
+		{"https://www.motorcycle.com/specs", true},
+		{"http://www.motorcycle.com/specs/ducati/", false},
+		{"/specs/", false},
+	}
+	for _, test := range tests {
+		seed, err := Adapters["motorcycle.com"].ParseSeed(test.url)
+		if (err == nil) != test.ok || err == nil && (seed.Stage != 0 || seed.Item != test.url) {
+			t.Errorf("%s: got %+v %v", test.url, seed, err)
+		}
+	}
+}
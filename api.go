@@ -0,0 +1,337 @@
+package motospec
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	DefaultPerPage = 50
+	MaxPerPage     = 500
+)
+
+// Page is the envelope of every list the API returns.
+type Page struct {
+	Items   interface{} `json:"items"`
+	Page    int         `json:"page"`
+	PerPage int         `json:"per_page"`
+	Total   int         `json:"total"`
+	Next    string      `json:"next,omitempty"`
+}
+
+type SearchPage struct {
+	Page
+	Facets map[string][]FacetCount `json:"facets"`
+}
+
+type SpecRecord struct {
+	ID         string     `json:"id"`
+	Normalized Normalized `json:"normalized"`
+	Spec
+}
+
+// API serves a Catalog read-only over HTTP:
+//
+//	GET /brands                                  brands
+//	GET /brands/<brand>/models                   models of a brand
+//	GET /brands/<brand>/models/<model>/variants  variants of a model
+//	GET /specs?brand=&model=&source=&year=&displacement=&power=&weight=
+//	GET /specs/<id>                              one full spec
+//	GET /search?q=&brand=&decade=&powertrain=&displacement=
+//	GET /compare?ids=<id>,<id>&units=metric      specs side by side
+//	GET, POST /graphql                           see NewGraphQLSchema
+//
+// Lists take page and per_page. The catalog is reloaded once the specs file
+// changes, checked at most every ReloadEvery.
+type API struct {
+	SpecsPath   string
+	BrandsPath  string
+	ModelsPath  string
+	ReloadEvery time.Duration
+
+	schema  graphql.Schema
+	mutex   sync.RWMutex
+	catalog *Catalog
+	modTime time.Time
+	checked time.Time
+}
+
+func NewAPI(specsPath, brandsPath, modelsPath string) (*API, error) {
+	api := &API{
+		SpecsPath:   specsPath,
+		BrandsPath:  brandsPath,
+		ModelsPath:  modelsPath,
+		ReloadEvery: 10 * time.Second,
+	}
+	var err error
+	if api.schema, err = NewGraphQLSchema(); err != nil {
+		return nil, err
+	}
+	if err := api.Reload(); err != nil {
+		return nil, err
+	}
+	return api, nil
+}
+
+func (a *API) Reload() error {
+	info, err := os.Stat(a.SpecsPath)
+	if err != nil {
+		return err
+	}
+	catalog, err := LoadCatalog(a.SpecsPath, a.BrandsPath, a.ModelsPath)
+	if err != nil {
+		return err
+	}
+	a.mutex.Lock()
+	a.catalog = catalog
+	a.modTime = info.ModTime()
+	a.checked = time.Now()
+	a.mutex.Unlock()
+	ProcessorLogger.Printf("API: loaded %d specs from %s", len(catalog.Specs), a.SpecsPath)
+	return nil
+}
+
+// Catalog returns the current catalog, reloading it first when the specs
+// file changed. A failing reload keeps serving the previous catalog.
+func (a *API) Catalog() *Catalog {
+	a.mutex.RLock()
+	catalog, due := a.catalog, time.Since(a.checked) >= a.ReloadEvery
+	a.mutex.RUnlock()
+	if !due {
+		return catalog
+	}
+	a.mutex.Lock()
+	a.checked = time.Now()
+	modTime := a.modTime
+	a.mutex.Unlock()
+	if info, err := os.Stat(a.SpecsPath); err == nil && !info.ModTime().Equal(modTime) {
+		if err := a.Reload(); err != nil {
+			ErrProcessorLogger.Printf("API: reload %s: %v", a.SpecsPath, err)
+		}
+	}
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.catalog
+}
+
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		writeError(w, http.StatusMethodNotAllowed, "only GET is supported")
+		return
+	}
+	catalog := a.Catalog()
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "brands":
+		writePage(w, r, catalog.Brands())
+	case len(parts) == 3 && parts[0] == "brands" && parts[2] == "models":
+		models, ok := catalog.Models(parts[1])
+		if !ok {
+			writeError(w, http.StatusNotFound, "no brand "+parts[1])
+			return
+		}
+		writePage(w, r, models)
+	case len(parts) == 5 && parts[0] == "brands" && parts[2] == "models" && parts[4] == "variants":
+		variants, ok := catalog.Variants(parts[1], parts[3])
+		if !ok {
+			writeError(w, http.StatusNotFound, "no model "+parts[1]+"/"+parts[3])
+			return
+		}
+		writePage(w, r, variants)
+	case len(parts) == 1 && parts[0] == "specs":
+		query, err := ParseSpecQuery(r.URL.Query())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writePage(w, r, catalog.variants(catalog.Query(query)))
+	case len(parts) == 2 && parts[0] == "specs":
+		index, ok := catalog.Lookup(parts[1])
+		if !ok {
+			writeError(w, http.StatusNotFound, "no spec "+parts[1])
+			return
+		}
+		writeJSON(w, r, specRecord(catalog, index))
+	case len(parts) == 1 && parts[0] == "search":
+		a.serveSearch(w, r, catalog)
+	case len(parts) == 1 && parts[0] == "compare":
+		indexes := []int{}
+		for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+			if id = strings.TrimSpace(id); id == "" {
+				continue
+			}
+			index, ok := catalog.Lookup(id)
+			if !ok {
+				writeError(w, http.StatusNotFound, "no spec "+id)
+				return
+			}
+			indexes = append(indexes, index)
+		}
+		units := r.URL.Query().Get("units")
+		if units == "" {
+			units = Metric
+		}
+		comparison, err := catalog.Compare(indexes, units)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, r, comparison)
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint")
+	}
+}
+
+// ParseSpecQuery reads the filters of /specs: brand, model, source, year
+// (a year or a range such as 1990-2005) and the displacement (cc), power
+// (hp) and weight (kg) ranges.
+func ParseSpecQuery(values url.Values) (SpecQuery, error) {
+	q := SpecQuery{
+		Brand:  values.Get("brand"),
+		Model:  values.Get("model"),
+		Source: values.Get("source"),
+	}
+	var err error
+	if q.YearFrom, q.YearTo, err = ParseYearRange(values.Get("year")); err != nil {
+		return q, err
+	}
+	for name, r := range map[string]*Range{"displacement": &q.Displacement, "power": &q.Power, "weight": &q.Weight} {
+		if *r, err = ParseRange(values.Get(name)); err != nil {
+			return q, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return q, nil
+}
+
+func pageParams(values url.Values) (page, perPage int, err error) {
+	page, perPage = 1, DefaultPerPage
+	if s := values.Get("page"); s != "" {
+		if page, err = strconv.Atoi(s); err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("page: must be a positive number")
+		}
+	}
+	if s := values.Get("per_page"); s != "" {
+		if perPage, err = strconv.Atoi(s); err != nil || perPage < 1 || perPage > MaxPerPage {
+			return 0, 0, fmt.Errorf("per_page: must be between 1 and %d", MaxPerPage)
+		}
+	}
+	return page, perPage, nil
+}
+
+// writePage slices items, a slice of entries, to the requested page.
+func writePage(w http.ResponseWriter, r *http.Request, items interface{}) {
+	page, perPage, err := pageParams(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var total int
+	var sliced interface{}
+	from := func(n int) (int, int) {
+		start := (page - 1) * perPage
+		if start > n {
+			start = n
+		}
+		end := start + perPage
+		if end > n {
+			end = n
+		}
+		return start, end
+	}
+	switch list := items.(type) {
+	case []BrandEntry:
+		start, end := from(len(list))
+		total, sliced = len(list), list[start:end]
+	case []ModelEntry:
+		start, end := from(len(list))
+		total, sliced = len(list), list[start:end]
+	case []VariantEntry:
+		start, end := from(len(list))
+		total, sliced = len(list), list[start:end]
+	default:
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot page %T", items))
+		return
+	}
+	writeJSON(w, r, Page{Items: sliced, Page: page, PerPage: perPage, Total: total, Next: nextPage(r, page, perPage, total)})
+}
+
+func nextPage(r *http.Request, page, perPage, total int) string {
+	if page*perPage >= total {
+		return ""
+	}
+	next := *r.URL
+	values := next.Query()
+	values.Set("page", strconv.Itoa(page+1))
+	values.Set("per_page", strconv.Itoa(perPage))
+	next.RawQuery = values.Encode()
+	return next.RequestURI()
+}
+
+// serveSearch runs the words of q against the search index, the other
+// parameters naming facet values the hits must have.
+func (a *API) serveSearch(w http.ResponseWriter, r *http.Request, catalog *Catalog) {
+	page, perPage, err := pageParams(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q := SearchQuery{
+		Text:    r.URL.Query().Get("q"),
+		Filters: make(map[string]string),
+		From:    (page - 1) * perPage,
+		Size:    perPage,
+	}
+	for _, facet := range Facets {
+		if value := r.URL.Query().Get(facet); value != "" {
+			q.Filters[facet] = value
+		}
+	}
+	result := catalog.SearchIndex().Search(q)
+	writeJSON(w, r, SearchPage{
+		Page:   Page{Items: result.Hits, Page: page, PerPage: perPage, Total: result.Total, Next: nextPage(r, page, perPage, result.Total)},
+		Facets: result.Facets,
+	})
+}
+
+// writeJSON answers with value and an ETag of its encoding, or with 304
+// when the client already has that version.
+func writeJSON(w http.ResponseWriter, r *http.Request, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sum := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	for _, match := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		if match = strings.TrimSpace(match); match == etag || match == "W/"+etag || match == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)+1))
+	if r.Method == "HEAD" {
+		return
+	}
+	w.Write(append(body, '\n'))
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,170 @@
+package motospec
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var apiSpecs = []Spec{
+	{Source: "autoevolution", Brand: "Ducati", Model: "Ducati Monster", Moto: "Ducati Monster 821", Year: "2014 - 2017",
+		URL: "https://www.autoevolution.com/moto/ducati-monster-821-2014.html", Specs: map[string]string{"Displacement": "821 cc", "Power": "109 hp"}},
+	{Source: "autoevolution", Brand: "Ducati", Model: "Ducati Monster", Moto: "Ducati Monster 1200", Year: "2014 - 2016",
+		URL: "https://www.autoevolution.com/moto/ducati-monster-1200-2014.html", Specs: map[string]string{"Displacement": "1198 cc", "Power": "135 hp"}},
+	{Source: "autoevolution", Brand: "Ducati", Model: "Ducati Scrambler", Moto: "Ducati Scrambler Icon", Year: "2015",
+		URL: "https://www.autoevolution.com/moto/ducati-scrambler-icon-2015.html", Specs: map[string]string{"Displacement": "803 cc", "Power": "73 hp"}},
+	{Source: "autoevolution", Brand: "BMW", Model: "BMW R", Moto: "BMW R 18", Year: "2020",
+		URL: "https://www.autoevolution.com/moto/bmw-r-18-2020.html", Specs: map[string]string{"Displacement": "1802 cc", "Power": "91 hp"}},
+}
+
+// writeSpecs writes specs to a specs file in a new directory, removed by
+// the returned function.
+func writeSpecs(t *testing.T, specs []Spec) (string, func()) {
+	dir, err := ioutil.TempDir("", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "motospecs.json")
+	sink, err := NewJSONSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, spec := range specs {
+		sink.Write(spec)
+	}
+	sink.Close()
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Range
+		ok   bool
+	}{
+		{"", Range{}, true},
+		{"750", Range{750, 750}, true},
+		{"600-1000", Range{600, 1000}, true},
+		{"600-", Range{600, 0}, true},
+		{"-1000", Range{0, 1000}, true},
+		{"big", Range{}, false},
+		{"600-big", Range{}, false},
+	}
+	for _, test := range tests {
+		got, err := ParseRange(test.s)
+		if got != test.want || (err == nil) != test.ok {
+			t.Errorf("%q: got %+v %v, want %+v", test.s, got, err, test.want)
+		}
+	}
+	if !(Range{}).Contains(0) || (Range{Min: 600}).Contains(0) || !(Range{Min: 600}).Contains(2000) || (Range{Max: 1000}).Contains(1200) {
+		t.Error("Contains")
+	}
+}
+
+func TestAPI(t *testing.T) {
+	path, remove := writeSpecs(t, apiSpecs)
+	defer remove()
+	brandsPath := filepath.Join(filepath.Dir(path), "brands.json")
+	modelsPath := filepath.Join(filepath.Dir(path), "models.json")
+	records := map[string][]interface{}{
+		brandsPath: {Brand{Name: "BMW", URL: "https://www.autoevolution.com/moto/bmw/"}},
+		modelsPath: {Model{Brand: "Ducati", Name: "Ducati Monster", URL: "https://www.autoevolution.com/moto/ducati/monster/"}},
+	}
+	for file, list := range records {
+		sink, err := NewJSONSink(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range list {
+			sink.Write(record)
+		}
+		sink.Close()
+	}
+	api, err := NewAPI(path, brandsPath, modelsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path   string
+		status int
+		total  int
+		next   string
+		url    string
+	}{
+		{"/brands", http.StatusOK, 2, "", "https://www.autoevolution.com/moto/bmw/"},
+		{"/brands/ducati/models", http.StatusOK, 2, "", "https://www.autoevolution.com/moto/ducati/monster/"},
+		{"/brands/ducati/models/monster/variants", http.StatusOK, 2, "", apiSpecs[0].URL},
+		{"/brands/honda/models", http.StatusNotFound, 0, "", ""},
+		{"/specs", http.StatusOK, 4, "", apiSpecs[0].URL},
+		{"/specs?per_page=3", http.StatusOK, 4, "/specs?page=2&per_page=3", ""},
+		{"/specs?page=2&per_page=3", http.StatusOK, 4, "", apiSpecs[3].URL},
+		{"/specs?brand=ducati&displacement=800-1000", http.StatusOK, 2, "", ""},
+		{"/specs?year=2016-&power=100-", http.StatusOK, 2, "", ""},
+		{"/specs?power=fast", http.StatusBadRequest, 0, "", ""},
+		{"/specs?per_page=501", http.StatusBadRequest, 0, "", ""},
+		{"/specs?page=0", http.StatusBadRequest, 0, "", ""},
+		{"/specs/" + SpecID(apiSpecs[3]), http.StatusOK, 0, "", ""},
+		{"/specs/000000000000", http.StatusNotFound, 0, "", ""},
+		{"/nothing", http.StatusNotFound, 0, "", ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.status {
+			t.Errorf("%s: status %d, want %d: %s", test.path, w.Code, test.status, w.Body)
+			continue
+		}
+		if w.Code != http.StatusOK || test.total == 0 {
+			continue
+		}
+		var page Page
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != test.total || page.Next != test.next {
+			t.Errorf("%s: total %d, next %q, want %d, %q", test.path, page.Total, page.Next, test.total, test.next)
+		}
+		if items, ok := page.Items.([]interface{}); test.url != "" && (!ok || len(items) == 0 || items[0].(map[string]interface{})["url"] != test.url) {
+			t.Errorf("%s: first item %v, want url %s", test.path, page.Items, test.url)
+		}
+	}
+}
+
+func TestAPIConditional(t *testing.T) {
+	path, remove := writeSpecs(t, apiSpecs)
+	defer remove()
+	api, err := NewAPI(path, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("GET", "/brands", nil))
+	etag := w.Header().Get("ETag")
+	tests := []struct {
+		method string
+		match  string
+		status int
+		body   bool
+	}{
+		{"GET", etag, http.StatusNotModified, false},
+		{"GET", `"other", W/` + etag, http.StatusNotModified, false},
+		{"GET", `"other"`, http.StatusOK, true},
+		{"HEAD", "", http.StatusOK, false},
+		{"POST", "", http.StatusMethodNotAllowed, true},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/brands", nil)
+		if test.match != "" {
+			r.Header.Set("If-None-Match", test.match)
+		}
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+		if w.Code != test.status || (w.Body.Len() > 0) != test.body {
+			t.Errorf("%s %s: status %d with %d bytes", test.method, test.match, w.Code, w.Body.Len())
+		}
+	}
+}
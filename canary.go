@@ -0,0 +1,99 @@
+package motospec
+
+import (
+	"context"
+	"fmt"
+)
+
+type CanaryResult struct {
+	Stage   string
+	Input   string
+	Outputs int
+	Errors  []string
+}
+
+func (r CanaryResult) OK() bool {
+	return r.Outputs > 0 && len(r.Errors) == 0
+}
+
+// Canary walks the stages of the current site from index first on, with a
+// single input each, feeding the first output of every stage into the next
+// one. It stops at the first stage that yields nothing, which is how a
+// layout change on the site usually shows up.
+func Canary(ctx context.Context, config *Config, first int, start interface{}, minSpecKeys int) ([]CanaryResult, error) {
+	stages, err := config.StageFuncList()
+	if err != nil {
+		return nil, err
+	}
+	names := config.CurrentSite().Stages
+	session, err := config.OpenSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	limiter := config.OpenLimiter()
+	robots := config.OpenRobots(session, limiter)
+	if session != nil {
+		session.Limiter = limiter
+	}
+	results := make([]CanaryResult, 0, len(stages))
+	input := start
+	for i := first; i < len(stages); i++ {
+		result := CanaryResult{Stage: names[i], Input: InputURL(input)}
+		stage := names[i]
+		outputs, errs := runOnce(ctx, stages[i], input, func(p *Processor) {
+			p.Configure(config, stage)
+			p.Interval = 0
+			p.Workers = 1
+			p.Session = session
+			p.Robots = robots
+			p.Limiter = limiter
+		})
+		result.Outputs = len(outputs)
+		for _, err := range errs {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		if i == len(stages)-1 {
+			for _, output := range outputs {
+				if spec, ok := output.(Spec); ok && len(spec.Specs) < minSpecKeys {
+					result.Errors = append(result.Errors, fmt.Sprintf("%s has only %d spec keys", spec.URL, len(spec.Specs)))
+				}
+			}
+		}
+		results = append(results, result)
+		if len(outputs) == 0 {
+			return results, fmt.Errorf("stage %s produced nothing for %s", names[i], result.Input)
+		}
+		input = outputs[0]
+	}
+	for _, result := range results {
+		if !result.OK() {
+			return results, fmt.Errorf("stage %s reported %d errors", result.Stage, len(result.Errors))
+		}
+	}
+	return results, nil
+}
+
+func runOnce(ctx context.Context, pf ProcessFunc, input interface{}, configure func(*Processor)) ([]interface{}, []error) {
+	errChan := make(chan error)
+	processor := NewProcessor(make(chan interface{}, 1), errChan, ctx, pf, 0)
+	configure(processor)
+	processor.Input <- input
+	close(processor.Input)
+	go processor.Run()
+	errs := []error{}
+	errDone := make(chan struct{})
+	go func() {
+		for err := range errChan {
+			errs = append(errs, err)
+		}
+		close(errDone)
+	}()
+	outputs := []interface{}{}
+	for output := range processor.Output {
+		outputs = append(outputs, output)
+	}
+	<-processor.Done
+	close(errChan)
+	<-errDone
+	return outputs, errs
+}
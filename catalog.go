@@ -0,0 +1,425 @@
+package motospec
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SpecID identifies a spec by its Key, so it survives re-crawls and
+// reloads.
+func SpecID(spec Spec) string {
+	return keyID(spec.Key())
+}
+
+func keyID(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+// modelSlug leaves out the brand most model names start with.
+func modelSlug(brand, model string) string {
+	if id := slugID(NormalizeModel(brand, model)); id != "" {
+		return id
+	}
+	return slugID(model)
+}
+
+type BrandEntry struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	URL      string `json:"url,omitempty"`
+	Logo     string `json:"logo,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Founded  string `json:"founded,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Models   int    `json:"models"`
+	Variants int    `json:"variants"`
+}
+
+type ModelEntry struct {
+	ID       string `json:"id"`
+	Brand    string `json:"brand"`
+	Name     string `json:"name"`
+	URL      string `json:"url,omitempty"`
+	Image    string `json:"image,omitempty"`
+	Years    string `json:"years,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Variants int    `json:"variants"`
+}
+
+type VariantEntry struct {
+	ID     string `json:"id"`
+	Source string `json:"source,omitempty"`
+	Brand  string `json:"brand"`
+	Model  string `json:"model"`
+	Moto   string `json:"type"`
+	Year   string `json:"year"`
+	URL    string `json:"url,omitempty"`
+	Normalized
+}
+
+type catalogModel struct {
+	entry ModelEntry
+	specs []int
+}
+
+type catalogBrand struct {
+	entry  BrandEntry
+	models []*catalogModel
+	byID   map[string]*catalogModel
+}
+
+// Catalog indexes a crawled dataset by brand, model and spec id. Brand and
+// model records add their metadata when they are loaded along the specs.
+type Catalog struct {
+	Specs []Spec
+	IDs   []string
+	Norms []Normalized
+
+	brands    []*catalogBrand
+	byBrand   map[string]*catalogBrand
+	byID      map[string]int
+	index     *SearchIndex
+	indexOnce sync.Once
+}
+
+func NewCatalog(specs []Spec, brands []Brand, models []Model) *Catalog {
+	c := &Catalog{
+		Specs:   specs,
+		IDs:     make([]string, len(specs)),
+		Norms:   make([]Normalized, len(specs)),
+		byBrand: make(map[string]*catalogBrand),
+		byID:    make(map[string]int, len(specs)),
+	}
+	brandMeta := make(map[string]Brand)
+	for _, brand := range brands {
+		brandMeta[slugID(brand.Name)] = brand
+	}
+	modelMeta := make(map[string]Model)
+	for _, model := range models {
+		modelMeta[slugID(model.Brand)+"/"+modelSlug(model.Brand, model.Name)] = model
+	}
+	for i, spec := range specs {
+		c.IDs[i] = SpecID(spec)
+		c.Norms[i] = Normalize(spec)
+		c.byID[c.IDs[i]] = i
+		brandID := slugID(spec.Brand)
+		brand, ok := c.byBrand[brandID]
+		if !ok {
+			meta := brandMeta[brandID]
+			brand = &catalogBrand{
+				entry: BrandEntry{
+					ID:      brandID,
+					Name:    spec.Brand,
+					URL:     meta.URL,
+					Logo:    meta.Logo,
+					Country: meta.Country,
+					Founded: meta.Founded,
+					Status:  meta.Status,
+				},
+				byID: make(map[string]*catalogModel),
+			}
+			c.byBrand[brandID] = brand
+			c.brands = append(c.brands, brand)
+		}
+		modelID := modelSlug(spec.Brand, spec.Model)
+		model, ok := brand.byID[modelID]
+		if !ok {
+			meta := modelMeta[brandID+"/"+modelID]
+			model = &catalogModel{entry: ModelEntry{
+				ID:     modelID,
+				Brand:  spec.Brand,
+				Name:   spec.Model,
+				URL:    meta.URL,
+				Image:  meta.Image,
+				Years:  meta.Years,
+				Status: meta.Status,
+			}}
+			brand.byID[modelID] = model
+			brand.models = append(brand.models, model)
+		}
+		model.specs = append(model.specs, i)
+		model.entry.Variants++
+		brand.entry.Variants++
+	}
+	sort.Slice(c.brands, func(i, j int) bool { return c.brands[i].entry.ID < c.brands[j].entry.ID })
+	for _, brand := range c.brands {
+		brand.entry.Models = len(brand.models)
+		sort.Slice(brand.models, func(i, j int) bool { return brand.models[i].entry.ID < brand.models[j].entry.ID })
+	}
+	return c
+}
+
+// LoadCatalog reads the specs file and, when their paths are not empty and
+// the files exist, the brand and model files of a crawl.
+func LoadCatalog(specsPath, brandsPath, modelsPath string) (*Catalog, error) {
+	specs, err := LoadSpecs(specsPath)
+	if err != nil {
+		return nil, err
+	}
+	brands, err := LoadBrands(brandsPath)
+	if err != nil {
+		return nil, err
+	}
+	models, err := LoadModels(modelsPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewCatalog(specs, brands, models), nil
+}
+
+// LoadBrands reads a brands sink; an empty path or a missing file holds no
+// brands.
+func LoadBrands(path string) ([]Brand, error) {
+	var brands []Brand
+	err := loadRecords(path, func(raw json.RawMessage) error {
+		var brand Brand
+		err := json.Unmarshal(raw, &brand)
+		brands = append(brands, brand)
+		return err
+	})
+	return brands, err
+}
+
+// LoadModels reads a models sink like LoadBrands.
+func LoadModels(path string) ([]Model, error) {
+	var models []Model
+	err := loadRecords(path, func(raw json.RawMessage) error {
+		var model Model
+		err := json.Unmarshal(raw, &model)
+		models = append(models, model)
+		return err
+	})
+	return models, err
+}
+
+func (c *Catalog) Brands() []BrandEntry {
+	entries := make([]BrandEntry, len(c.brands))
+	for i, brand := range c.brands {
+		entries[i] = brand.entry
+	}
+	return entries
+}
+
+func (c *Catalog) Brand(brandID string) (BrandEntry, bool) {
+	brand, ok := c.byBrand[brandID]
+	if !ok {
+		return BrandEntry{}, false
+	}
+	return brand.entry, true
+}
+
+func (c *Catalog) Models(brandID string) ([]ModelEntry, bool) {
+	brand, ok := c.byBrand[brandID]
+	if !ok {
+		return nil, false
+	}
+	entries := make([]ModelEntry, len(brand.models))
+	for i, model := range brand.models {
+		entries[i] = model.entry
+	}
+	return entries, true
+}
+
+// ModelSpecs returns the indexes of the specs of a model.
+func (c *Catalog) ModelSpecs(brandID, modelID string) ([]int, bool) {
+	brand, ok := c.byBrand[brandID]
+	if !ok {
+		return nil, false
+	}
+	model, ok := brand.byID[modelID]
+	if !ok {
+		return nil, false
+	}
+	return model.specs, true
+}
+
+func (c *Catalog) Variants(brandID, modelID string) ([]VariantEntry, bool) {
+	indexes, ok := c.ModelSpecs(brandID, modelID)
+	if !ok {
+		return nil, false
+	}
+	return c.variants(indexes), true
+}
+
+func (c *Catalog) variants(indexes []int) []VariantEntry {
+	entries := make([]VariantEntry, len(indexes))
+	for i, index := range indexes {
+		entries[i] = c.Variant(index)
+	}
+	return entries
+}
+
+func (c *Catalog) Variant(index int) VariantEntry {
+	spec := c.Specs[index]
+	return VariantEntry{
+		ID:         c.IDs[index],
+		Source:     spec.Source,
+		Brand:      spec.Brand,
+		Model:      spec.Model,
+		Moto:       spec.Moto,
+		Year:       spec.Year,
+		URL:        spec.URL,
+		Normalized: c.Norms[index],
+	}
+}
+
+// SearchIndex returns the search index of the specs, built on first use.
+// Its docs are in the order of Specs.
+func (c *Catalog) SearchIndex() *SearchIndex {
+	c.indexOnce.Do(func() {
+		c.index = BuildSearchIndex(c.Specs)
+	})
+	return c.index
+}
+
+// Lookup returns the index of the spec with the given id.
+func (c *Catalog) Lookup(id string) (int, bool) {
+	index, ok := c.byID[id]
+	return index, ok
+}
+
+// Range bounds a normalized number; a zero bound is open.
+type Range struct {
+	Min float64
+	Max float64
+}
+
+// ParseRange reads "600-1000", "600-", "-1000" or a single "750".
+func ParseRange(s string) (Range, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Range{}, nil
+	}
+	parts := strings.SplitN(s, "-", 2)
+	var r Range
+	var err error
+	if parts[0] != "" {
+		if r.Min, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil {
+			return Range{}, fmt.Errorf("invalid range %q", s)
+		}
+	}
+	if len(parts) == 1 {
+		r.Max = r.Min
+		return r, nil
+	}
+	if parts[1] != "" {
+		if r.Max, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil {
+			return Range{}, fmt.Errorf("invalid range %q", s)
+		}
+	}
+	return r, nil
+}
+
+func (r Range) Open() bool {
+	return r.Min == 0 && r.Max == 0
+}
+
+// Contains is false for an unknown value unless the range is open.
+func (r Range) Contains(value float64) bool {
+	if r.Open() {
+		return true
+	}
+	if value == 0 {
+		return false
+	}
+	return value >= r.Min && (r.Max == 0 || value <= r.Max)
+}
+
+type SpecQuery struct {
+	Brand        string
+	Model        string
+	Source       string
+	YearFrom     int
+	YearTo       int
+	Displacement Range
+	Power        Range
+	Weight       Range
+}
+
+func (q SpecQuery) Match(spec Spec, n Normalized) bool {
+	if q.Brand != "" && n.Brand != NormalizeBrand(q.Brand) {
+		return false
+	}
+	if q.Model != "" && !strings.Contains(n.Model, NormalizeModel(spec.Brand, q.Model)) {
+		return false
+	}
+	if q.Source != "" && spec.Source != q.Source {
+		return false
+	}
+	if q.YearFrom != 0 || q.YearTo != 0 {
+		if n.YearFrom == 0 {
+			return false
+		}
+		if q.YearFrom != 0 && n.YearTo < q.YearFrom {
+			return false
+		}
+		if q.YearTo != 0 && n.YearFrom > q.YearTo {
+			return false
+		}
+	}
+	return q.Displacement.Contains(n.Displacement) && q.Power.Contains(n.Power) && q.Weight.Contains(n.Weight)
+}
+
+// Query returns the indexes of the specs matching q, in dataset order.
+func (c *Catalog) Query(q SpecQuery) []int {
+	indexes := []int{}
+	for i, spec := range c.Specs {
+		if q.Match(spec, c.Norms[i]) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// loadRecords calls add with every record of a sink file, NDJSON or a
+// JSON array. A missing file holds no records.
+func loadRecords(path string, add func(json.RawMessage) error) error {
+	if path == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	content = bytes.TrimSpace(content)
+	if len(content) > 0 && content[0] == '[' {
+		var raws []json.RawMessage
+		if err := json.Unmarshal(content, &raws); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		for _, raw := range raws {
+			if err := add(raw); err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+		}
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			return nil
+		}
+		if err == nil {
+			err = add(raw)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package motospec
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+)
+
+// A measure converts a base metric value to the unit shown for a unit
+// system.
+type measure struct {
+	metric   string
+	imperial string
+	factor   float64
+}
+
+var (
+	ccMeasure     = measure{"cc", "cu in", 1 / 16.387}
+	powerMeasure  = measure{"kW", "hp", 1.341}
+	torqueMeasure = measure{"Nm", "lb-ft", 0.7376}
+	weightMeasure = measure{"kg", "lbs", 2.2046}
+	lengthMeasure = measure{"mm", "in", 1 / 25.4}
+	volumeMeasure = measure{"L", "gal", 1 / 3.785}
+	speedMeasure  = measure{"km/h", "mph", 1 / 1.609}
+)
+
+func (m measure) unit(units string) string {
+	if units == Imperial {
+		return m.imperial
+	}
+	return m.metric
+}
+
+func (m measure) convert(value float64, units string) float64 {
+	if units == Imperial {
+		return value * m.factor
+	}
+	return value
+}
+
+var (
+	lengthUnits = []unit{{"mm", 1}, {"cm", 10}, {"in", 25.4}, {"inch", 25.4}, {"inches", 25.4}}
+	volumeUnits = []unit{{"l", 1}, {"liters", 1}, {"litres", 1}, {"gallons", 3.785}, {"gal", 3.785}}
+	speedUnits  = []unit{{"km", 1}, {"kmh", 1}, {"kph", 1}, {"mph", 1.609}}
+	torqueUnits = []unit{{"nm", 1}, {"lb", 1.3558}, {"ft", 1.3558}}
+
+	torqueRPMPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)/[\d.-]*\s*nm`)
+)
+
+// parseTorque also reads the "20/7000 lb-ft/RPM OR 27/7000 Nm/RPM" layout of
+// autoevolution.
+func parseTorque(value string) (float64, bool) {
+	lower := strings.ToLower(tagPattern.ReplaceAllString(value, " "))
+	if m := torqueRPMPattern.FindStringSubmatch(lower); m != nil {
+		if n, err := strconv.ParseFloat(m[1], 64); err == nil && n > 0 {
+			return n, true
+		}
+	}
+	return parseQuantity(value, torqueUnits)
+}
+
+// A CompareKey is a row of a comparison: a number read from the specs
+// named like Names, or their text when it has no measure.
+type CompareKey struct {
+	Name    string
+	Names   []string
+	measure *measure
+	read    func(Spec, []string, Normalized) (float64, bool)
+}
+
+func specQuantity(units []unit) func(Spec, []string, Normalized) (float64, bool) {
+	return func(spec Spec, names []string, n Normalized) (float64, bool) {
+		return parseQuantity(SpecValue(spec, names...), units)
+	}
+}
+
+// power is kept in kW, Normalized holding hp
+var CompareKeys = []CompareKey{
+	{Name: "engine", Names: []string{"type", "engine type", "engine"}},
+	{Name: "displacement", Names: []string{"displacement", "engine size"}, measure: &ccMeasure,
+		read: func(_ Spec, _ []string, n Normalized) (float64, bool) { return n.Displacement, n.Displacement > 0 }},
+	{Name: "power", Names: []string{"horsepower", "power"}, measure: &powerMeasure,
+		read: func(_ Spec, _ []string, n Normalized) (float64, bool) { return n.Power / 1.341, n.Power > 0 }},
+	{Name: "torque", Names: []string{"torque"}, measure: &torqueMeasure,
+		read: func(spec Spec, names []string, _ Normalized) (float64, bool) {
+			return parseTorque(SpecValue(spec, names...))
+		}},
+	{Name: "gearbox", Names: []string{"gearbox", "transmission"}},
+	{Name: "final drive", Names: []string{"final drive"}},
+	{Name: "front brake", Names: []string{"front brake", "front brakes"}},
+	{Name: "rear brake", Names: []string{"rear brake", "rear brakes"}},
+	{Name: "front suspension", Names: []string{"front suspension"}},
+	{Name: "rear suspension", Names: []string{"rear suspension"}},
+	{Name: "weight", Names: []string{"wet weight", "weight"}, measure: &weightMeasure,
+		read: func(_ Spec, _ []string, n Normalized) (float64, bool) { return n.Weight, n.Weight > 0 }},
+	{Name: "seat height", Names: []string{"seat height"}, measure: &lengthMeasure, read: specQuantity(lengthUnits)},
+	{Name: "wheelbase", Names: []string{"wheelbase", "weelbase"}, measure: &lengthMeasure, read: specQuantity(lengthUnits)},
+	{Name: "fuel capacity", Names: []string{"fuel capacity", "fuel tank"}, measure: &volumeMeasure, read: specQuantity(volumeUnits)},
+	{Name: "top speed", Names: []string{"top speed"}, measure: &speedMeasure, read: specQuantity(speedUnits)},
+}
+
+// Numeric tells the keys read as numbers from the keys kept as text.
+func (k CompareKey) Numeric() bool {
+	return k.measure != nil
+}
+
+// Read returns the number of a numeric key in metric units.
+func (k CompareKey) Read(spec Spec, n Normalized) (float64, bool) {
+	if k.read == nil {
+		return 0, false
+	}
+	return k.read(spec, k.Names, n)
+}
+
+// A ratio is a derived row, read from what Derive works out in the metric
+// unit and multiplied by factor for the imperial one.
+type ratio struct {
+	name     string
+	metric   string
+	imperial string
+	factor   float64
+	read     func(*Derived) float64
+}
+
+var compareRatios = []ratio{
+	{"power-to-weight", "hp/kg", "hp/lb", 1 / 2.2046, func(d *Derived) float64 { return d.PowerToWeight }},
+	{"torque-to-weight", "Nm/kg", "lb-ft/lb", 0.7376 / 2.2046, func(d *Derived) float64 { return d.TorqueToWeight }},
+	{"power per litre", "hp/L", "hp/L", 1, func(d *Derived) float64 { return d.SpecificOutput }},
+}
+
+type ComparisonRow struct {
+	Key     string   `json:"key"`
+	Unit    string   `json:"unit,omitempty"`
+	Values  []string `json:"values"`
+	Differs bool     `json:"differs"`
+	Derived bool     `json:"derived,omitempty"`
+}
+
+type Comparison struct {
+	Units    string          `json:"units"`
+	Variants []VariantEntry  `json:"variants"`
+	Rows     []ComparisonRow `json:"rows"`
+}
+
+func formatNumber(value float64) string {
+	precision := 0
+	switch {
+	case value < 1:
+		precision = 3
+	case value < 10:
+		precision = 2
+	case value < 100:
+		precision = 1
+	}
+	formatted := strconv.FormatFloat(value, 'f', precision, 64)
+	if strings.Contains(formatted, ".") {
+		formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+	}
+	return formatted
+}
+
+// Compare lines up the canonical keys of the specs at indexes, numbers
+// converted to units, metric or imperial. A row differs when its values do.
+func (c *Catalog) Compare(indexes []int, units string) (Comparison, error) {
+	if units != Metric && units != Imperial {
+		return Comparison{}, fmt.Errorf("unknown unit system %q, want %s or %s", units, Metric, Imperial)
+	}
+	if len(indexes) < 2 {
+		return Comparison{}, fmt.Errorf("compare needs two specs or more")
+	}
+	comparison := Comparison{Units: units, Variants: c.variants(indexes)}
+	for _, key := range CompareKeys {
+		row := ComparisonRow{Key: key.Name, Values: make([]string, len(indexes))}
+		if key.measure != nil {
+			row.Unit = key.measure.unit(units)
+		}
+		for i, index := range indexes {
+			if key.measure == nil {
+				row.Values[i] = strings.Join(strings.Fields(tagPattern.ReplaceAllString(SpecValue(c.Specs[index], key.Names...), " ")), " ")
+				if row.Values[i] == "-" {
+					row.Values[i] = ""
+				}
+				continue
+			}
+			if value, ok := key.Read(c.Specs[index], c.Norms[index]); ok {
+				row.Values[i] = formatNumber(key.measure.convert(value, units))
+			}
+		}
+		comparison.addRow(row)
+	}
+	derived := make([]*Derived, len(indexes))
+	for i, index := range indexes {
+		if derived[i] = Derive(c.Specs[index]); derived[i] == nil {
+			derived[i] = &Derived{}
+		}
+	}
+	for _, r := range compareRatios {
+		row := ComparisonRow{Key: r.name, Unit: r.metric, Values: make([]string, len(indexes)), Derived: true}
+		if units == Imperial {
+			row.Unit = r.imperial
+		}
+		for i := range indexes {
+			value := r.read(derived[i])
+			if value == 0 {
+				continue
+			}
+			if units == Imperial {
+				value *= r.factor
+			}
+			row.Values[i] = formatNumber(value)
+		}
+		comparison.addRow(row)
+	}
+	return comparison, nil
+}
+
+// addRow skips the rows no spec has a value for.
+func (c *Comparison) addRow(row ComparisonRow) {
+	known := false
+	for _, value := range row.Values {
+		if value != "" {
+			known = true
+		}
+		if foldValue(value) != foldValue(row.Values[0]) {
+			row.Differs = true
+		}
+	}
+	if known {
+		c.Rows = append(c.Rows, row)
+	}
+}
@@ -0,0 +1,74 @@
+package motospec
+
+import (
+	"testing"
+)
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0.4056, "0.406"},
+		{0.5, "0.5"},
+		{4.25, "4.25"},
+		{90.91, "90.9"},
+		{98, "98"},
+		{803.4, "803"},
+	}
+	for _, test := range tests {
+		if got := formatNumber(test.value); got != test.want {
+			t.Errorf("%g: got %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	specs := []Spec{
+		{Brand: "Ducati", Model: "Scrambler", Moto: "Ducati Scrambler Icon", Year: "2019",
+			Specs: map[string]string{"Displacement": "803 cc", "Power": "73 hp", "Torque": "67 Nm", "Weight": "180 kg"}},
+		{Brand: "Ducati", Model: "Scrambler", Moto: "Ducati Scrambler Full Throttle", Year: "2019",
+			Specs: map[string]string{"Displacement": "803 cc", "Power": "73 hp", "Weight": "190 kg"}},
+	}
+	catalog := NewCatalog(specs, nil, nil)
+	tests := []struct {
+		units string
+		key   string
+		unit  string
+		want  []string
+	}{
+		{Metric, "weight", "kg", []string{"180", "190"}},
+		{Imperial, "weight", "lbs", []string{"397", "419"}},
+		{Metric, "power-to-weight", "hp/kg", []string{"0.406", "0.384"}},
+		{Imperial, "power-to-weight", "hp/lb", []string{"0.184", "0.174"}},
+		{Metric, "torque-to-weight", "Nm/kg", []string{"0.372", ""}},
+		{Imperial, "torque-to-weight", "lb-ft/lb", []string{"0.124", ""}},
+		{Metric, "power per litre", "hp/L", []string{"90.9", "90.9"}},
+		{Imperial, "power per litre", "hp/L", []string{"90.9", "90.9"}},
+	}
+	for _, test := range tests {
+		comparison, err := catalog.Compare([]int{0, 1}, test.units)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var row *ComparisonRow
+		for i := range comparison.Rows {
+			if comparison.Rows[i].Key == test.key {
+				row = &comparison.Rows[i]
+			}
+		}
+		if row == nil {
+			t.Errorf("%s %s: no row", test.units, test.key)
+			continue
+		}
+		if row.Unit != test.unit || row.Values[0] != test.want[0] || row.Values[1] != test.want[1] {
+			t.Errorf("%s %s: got %s %q, want %s %q", test.units, test.key, row.Unit, row.Values, test.unit, test.want)
+		}
+	}
+	if _, err := catalog.Compare([]int{0}, Metric); err == nil {
+		t.Error("compared a single spec")
+	}
+	if _, err := catalog.Compare([]int{0, 1}, "nautical"); err == nil {
+		t.Error("compared in an unknown unit system")
+	}
+}
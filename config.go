@@ -0,0 +1,823 @@
+package motospec
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const ConfigVersion = 1
+
+var StageFuncs = map[string]ProcessFunc{
+	"brand": BrandFunc,
+	"model": ModelFunc,
+	"moto":  MotoFunc,
+	"spec":  SpecFunc,
+}
+
+var StageNames = []string{"brand", "model", "moto", "spec"}
+
+type FilterConfig struct {
+	Brands        []string `yaml:"brands"`
+	ExcludeBrands []string `yaml:"exclude_brands"`
+	Models        []string `yaml:"models"`
+	ExcludeModels []string `yaml:"exclude_models"`
+	Years         string   `yaml:"years"`
+}
+
+func (c FilterConfig) Build() (*Filter, error) {
+	filter := &Filter{}
+	var err error
+	if filter.IncludeBrands, err = CompilePatterns(c.Brands); err != nil {
+		return nil, err
+	}
+	if filter.ExcludeBrands, err = CompilePatterns(c.ExcludeBrands); err != nil {
+		return nil, err
+	}
+	if filter.IncludeModels, err = CompilePatterns(c.Models); err != nil {
+		return nil, err
+	}
+	if filter.ExcludeModels, err = CompilePatterns(c.ExcludeModels); err != nil {
+		return nil, err
+	}
+	if filter.YearFrom, filter.YearTo, err = ParseYearRange(c.Years); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+type ProxyConfig struct {
+	URLs        []string `yaml:"urls"`
+	MaxFailures int      `yaml:"max_failures"`
+	Cooldown    int      `yaml:"cooldown"`
+}
+
+type RobotsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Agent   string `yaml:"agent"`
+}
+
+type ThrottleConfig struct {
+	Adaptive    bool    `yaml:"adaptive"`
+	MaxDelay    int     `yaml:"max_delay"`
+	Backoff     float64 `yaml:"backoff"`
+	Recovery    float64 `yaml:"recovery"`
+	SlowLatency int     `yaml:"slow_latency"`
+}
+
+type CrawlConfig struct {
+	Concurrency int            `yaml:"concurrency"`
+	Interval    int            `yaml:"interval"`
+	Retries     int            `yaml:"retries"`
+	Timeout     int            `yaml:"timeout"`
+	LogDir      string         `yaml:"log_dir"`
+	ImagesDir   string         `yaml:"images_dir"`
+	SnapshotDir string         `yaml:"snapshot_dir"`
+	Filter      FilterConfig   `yaml:"filter"`
+	Proxies     ProxyConfig    `yaml:"proxies"`
+	Robots      RobotsConfig   `yaml:"robots"`
+	Throttle    ThrottleConfig `yaml:"throttle"`
+	Post        []string       `yaml:"post"`
+}
+
+type SessionConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	CookieFile string   `yaml:"cookie_file"`
+	WarmUp     []string `yaml:"warm_up"`
+}
+
+// SitemapConfig lists the sitemaps seeding the spec stage directly; with
+// no urls they are discovered through robots.txt. Only pages matching one
+// of patterns are crawled.
+type SitemapConfig struct {
+	URLs     []string `yaml:"urls"`
+	Patterns []string `yaml:"patterns"`
+}
+
+// SiteConfig describes one site crawled by a SiteAdapter, the adapter of
+// the same name unless Adapter names another one. Endpoints holds the urls
+// an adapter needs besides its seeds.
+type SiteConfig struct {
+	Adapter      string                 `yaml:"adapter"`
+	Endpoints    map[string]string      `yaml:"endpoints"`
+	Seeds        []string               `yaml:"seeds"`
+	Stages       []string               `yaml:"stages"`
+	Header       string                 `yaml:"header"`
+	StageHeaders map[string]string      `yaml:"stage_headers"`
+	Selectors    Selectors              `yaml:"selectors"`
+	Session      SessionConfig          `yaml:"session"`
+	Sitemap      SitemapConfig          `yaml:"sitemap"`
+	Pagination   map[string]*Pagination `yaml:"pagination"`
+}
+
+// ResolveConfig tunes the merge of specs from several sources, see
+// Resolver. Sources lists the sources in order of preference.
+type ResolveConfig struct {
+	Threshold float64           `yaml:"threshold"`
+	Tolerance float64           `yaml:"tolerance"`
+	YearSlack int               `yaml:"year_slack"`
+	Sources   []string          `yaml:"sources"`
+	Rules     map[string]string `yaml:"rules"`
+}
+
+func (c ResolveConfig) Resolver() *Resolver {
+	resolver := NewResolver()
+	resolver.Threshold = c.Threshold
+	resolver.Tolerance = c.Tolerance
+	resolver.YearSlack = c.YearSlack
+	resolver.Sources = c.Sources
+	for field, rule := range c.Rules {
+		resolver.Rules[strings.ToLower(field)] = rule
+	}
+	return resolver
+}
+
+type SinkConfig struct {
+	Kind   string `yaml:"kind"`
+	Path   string `yaml:"path"`
+	Format string `yaml:"format"`
+}
+
+type Config struct {
+	Version  int                       `yaml:"version"`
+	Site     string                    `yaml:"site"`
+	Crawl    CrawlConfig               `yaml:"crawl"`
+	Headers  map[string]*HeaderProfile `yaml:"headers"`
+	Sites    map[string]*SiteConfig    `yaml:"sites"`
+	Sinks    []SinkConfig              `yaml:"sinks"`
+	Resolve  ResolveConfig             `yaml:"resolve"`
+	Report   QualityThresholds         `yaml:"report"`
+	Schema   SchemaConfig              `yaml:"schema"`
+	Events   EventsConfig              `yaml:"events"`
+	Daemon   DaemonConfig              `yaml:"daemon"`
+	Profiles map[string]interface{}    `yaml:"profiles"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Version: ConfigVersion,
+		Site:    "autoevolution",
+		Crawl: CrawlConfig{
+			Concurrency: 1,
+			Interval:    4,
+			Retries:     3,
+			Timeout:     10,
+			LogDir:      ".",
+			Robots:      RobotsConfig{Enabled: true, Agent: "motospec"},
+			Throttle: ThrottleConfig{
+				Adaptive:    true,
+				MaxDelay:    300,
+				Backoff:     2,
+				Recovery:    0.9,
+				SlowLatency: 5,
+			},
+			Post: []string{"derive", "validate"},
+		},
+		Sites: map[string]*SiteConfig{
+			"autoevolution": {
+				Seeds:     []string{"https://www.autoevolution.com/moto/"},
+				Stages:    append([]string{}, StageNames...),
+				Header:    "default",
+				Selectors: DefaultSelectors,
+				Sitemap:   SitemapConfig{Patterns: []string{`/moto/[^/]+\.html$`}},
+			},
+			"motorcycle.com": {
+				Seeds:     []string{DefaultMotorcycleSpecs},
+				Stages:    motorcycleCom{}.Stages(),
+				Header:    "default",
+				Selectors: DefaultSelectors,
+			},
+		},
+		Headers: copyHeaderProfiles(DefaultHeaderProfiles),
+		Resolve: ResolveConfig{
+			Threshold: 0.75,
+			Tolerance: 0.08,
+			YearSlack: 1,
+			Sources:   []string{"autoevolution", "motorcycle.com"},
+			Rules:     map[string]string{"*": RulePrefer, "description": RuleLongest},
+		},
+		Report: QualityThresholds{
+			MaxPlaceholders: 1,
+			MaxUnparseable:  1,
+			MaxDuplicates:   -1,
+			MaxOutliers:     -1,
+			MaxEmptyBrands:  -1,
+			MaxEmptyModels:  -1,
+		},
+		Schema: SchemaConfig{
+			Required: []string{"brand", "model", "type"},
+			MinSpecs: 1,
+			Powertrains: map[string]KeyRules{
+				"electric":    {Forbidden: []string{"fuel system", "compression ratio"}},
+				"two-stroke":  {Required: []string{"displacement"}},
+				"four-stroke": {Required: []string{"displacement"}},
+			},
+			Ranges: map[string]string{
+				"year":         "1885-2100",
+				"displacement": "20-3000",
+				"power":        "-400",
+				"weight":       "20-600",
+			},
+		},
+		Daemon: DaemonConfig{
+			Addr:     ":8090",
+			LockFile: "motospec.lock",
+			History:  "jobs.json",
+		},
+		Sinks: []SinkConfig{
+			{Kind: "specs", Path: "motospecs.json", Format: "ndjson"},
+			{Kind: "brands", Path: "brands.json", Format: "ndjson"},
+			{Kind: "models", Path: "models.json", Format: "ndjson"},
+			{Kind: "failures", Path: "failures.json", Format: "ndjson"},
+			{Kind: "quarantine", Path: "quarantine.json", Format: "ndjson"},
+		},
+	}
+}
+
+// LoadConfig reads path on top of DefaultConfig, applies the named profile
+// when one is given and validates the result.
+func LoadConfig(path, profile string) (*Config, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := DefaultConfig()
+	config.Headers = nil
+	config.Sites = nil
+	config.Sinks = nil
+	config.Resolve.Rules = nil
+	config.Schema.Powertrains = nil
+	config.Schema.Ranges = nil
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if config.Sites == nil {
+		config.Sites = DefaultConfig().Sites
+	}
+	if config.Sinks == nil {
+		config.Sinks = DefaultConfig().Sinks
+	}
+	if config.Headers == nil {
+		config.Headers = copyHeaderProfiles(DefaultHeaderProfiles)
+	}
+	if config.Resolve.Rules == nil {
+		config.Resolve.Rules = DefaultConfig().Resolve.Rules
+	}
+	if config.Schema.Powertrains == nil {
+		config.Schema.Powertrains = DefaultConfig().Schema.Powertrains
+	}
+	if config.Schema.Ranges == nil {
+		config.Schema.Ranges = DefaultConfig().Schema.Ranges
+	}
+	if profile != "" {
+		if err := config.ApplyProfile(profile); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	defaults := DefaultConfig().Sites
+	for name, site := range config.Sites {
+		if site == nil {
+			continue
+		}
+		if d, ok := defaults[name]; ok {
+			if len(site.Seeds) == 0 {
+				site.Seeds = d.Seeds
+			}
+			if len(site.Stages) == 0 {
+				site.Stages = d.Stages
+			}
+			if site.Header == "" {
+				site.Header = d.Header
+			}
+			if len(site.Sitemap.Patterns) == 0 {
+				site.Sitemap.Patterns = d.Sitemap.Patterns
+			}
+		}
+		site.Selectors = site.Selectors.WithDefaults()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return config, nil
+}
+
+// ApplyProfile overlays the profile onto the config. A profile has the same
+// layout as the config itself and only names what it changes: mappings,
+// sites and headers included, are merged key by key, lists and values are
+// replaced.
+func (c *Config) ApplyProfile(name string) error {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	var current interface{}
+	if err := yaml.Unmarshal(content, &current); err != nil {
+		return err
+	}
+	if content, err = yaml.Marshal(mergeYAML(current, profile)); err != nil {
+		return err
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return fmt.Errorf("profile %s: %v", name, err)
+	}
+	*c = *config
+	return nil
+}
+
+// mergeYAML returns overlay merged onto base, both decoded YAML documents.
+func mergeYAML(base, overlay interface{}) interface{} {
+	b, ok := base.(map[interface{}]interface{})
+	o, ok2 := overlay.(map[interface{}]interface{})
+	if !ok || !ok2 {
+		return overlay
+	}
+	merged := make(map[interface{}]interface{}, len(b)+len(o))
+	for key, value := range b {
+		merged[key] = value
+	}
+	for key, value := range o {
+		merged[key] = mergeYAML(b[key], value)
+	}
+	return merged
+}
+
+type ConfigError []string
+
+func (e ConfigError) Error() string {
+	return "invalid config:\n  " + strings.Join(e, "\n  ")
+}
+
+func (c *Config) Validate() error {
+	errs := ConfigError{}
+	headerNames := make([]string, 0, len(c.Headers))
+	for name := range c.Headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		if c.Headers[name] == nil {
+			errs = append(errs, "headers."+name+": empty profile")
+			continue
+		}
+		for _, e := range c.Headers[name].Validate() {
+			errs = append(errs, "headers."+name+"."+e)
+		}
+	}
+	if c.Version != ConfigVersion {
+		errs = append(errs, fmt.Sprintf("version: expected %d, got %d", ConfigVersion, c.Version))
+	}
+	if c.Crawl.Concurrency < 1 {
+		errs = append(errs, "crawl.concurrency: must be at least 1")
+	}
+	if c.Crawl.Interval < 0 {
+		errs = append(errs, "crawl.interval: must not be negative")
+	}
+	if c.Crawl.Retries < 0 {
+		errs = append(errs, "crawl.retries: must not be negative")
+	}
+	if c.Crawl.Timeout < 1 {
+		errs = append(errs, "crawl.timeout: must be at least 1")
+	}
+	for i, name := range c.Crawl.Post {
+		if _, ok := PostStages[name]; !ok {
+			errs = append(errs, fmt.Sprintf("crawl.post[%d]: unknown post-processing stage %q", i, name))
+		}
+	}
+	if _, err := c.Crawl.Filter.Build(); err != nil {
+		errs = append(errs, "crawl.filter: "+err.Error())
+	}
+	if t := c.Crawl.Throttle; t.Adaptive {
+		if t.Backoff <= 1 {
+			errs = append(errs, "crawl.throttle.backoff: must be greater than 1")
+		}
+		if t.Recovery <= 0 || t.Recovery >= 1 {
+			errs = append(errs, "crawl.throttle.recovery: must be between 0 and 1")
+		}
+		if t.MaxDelay < c.Crawl.Interval {
+			errs = append(errs, "crawl.throttle.max_delay: must not be below crawl.interval")
+		}
+		if t.SlowLatency < 0 {
+			errs = append(errs, "crawl.throttle.slow_latency: must not be negative")
+		}
+	}
+	if len(c.Crawl.Proxies.URLs) > 0 {
+		if _, err := NewProxyPool(c.Crawl.Proxies.URLs, 1, 0); err != nil {
+			errs = append(errs, "crawl.proxies: "+err.Error())
+		}
+		if site, ok := c.Sites[c.Site]; ok && site != nil && !site.Session.Enabled {
+			errs = append(errs, "crawl.proxies: site "+c.Site+" needs session.enabled to use proxies")
+		}
+		if c.Crawl.Proxies.MaxFailures < 0 || c.Crawl.Proxies.Cooldown < 0 {
+			errs = append(errs, "crawl.proxies: max_failures and cooldown must not be negative")
+		}
+	}
+	if _, ok := c.Sites[c.Site]; !ok {
+		errs = append(errs, fmt.Sprintf("site: %q is not defined in sites", c.Site))
+	}
+	names := make([]string, 0, len(c.Sites))
+	for name := range c.Sites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		site := c.Sites[name]
+		prefix := "sites." + name
+		if site == nil {
+			errs = append(errs, prefix+": empty site")
+			continue
+		}
+		adapter := site.adapter(name)
+		if adapter == nil {
+			errs = append(errs, fmt.Sprintf("%s.adapter: unknown adapter %q, known are %s", prefix, site.adapterName(name), strings.Join(AdapterNames(), ", ")))
+			continue
+		}
+		if len(site.Stages) == 0 {
+			errs = append(errs, prefix+".stages: at least one stage is required")
+		}
+		for i, stage := range site.Stages {
+			if !hasStage(adapter, stage) {
+				errs = append(errs, fmt.Sprintf("%s.stages[%d]: %s has no stage %q", prefix, i, adapter.Name(), stage))
+			}
+		}
+		if len(site.Seeds) == 0 {
+			errs = append(errs, prefix+".seeds: at least one seed is required")
+		}
+		for i, seed := range site.Seeds {
+			if _, err := adapter.ParseSeed(seed); err != nil {
+				errs = append(errs, fmt.Sprintf("%s.seeds[%d]: %v", prefix, i, err))
+			}
+		}
+		for key, u := range site.Endpoints {
+			if parsed, err := url.Parse(u); err != nil || parsed.Host == "" {
+				errs = append(errs, fmt.Sprintf("%s.endpoints.%s: %q is not an absolute url", prefix, key, u))
+			}
+		}
+		if site.Header == "" {
+			errs = append(errs, prefix+".header: a header profile is required")
+		} else if c.Headers[site.Header] == nil {
+			errs = append(errs, fmt.Sprintf("%s.header: unknown header profile %q", prefix, site.Header))
+		}
+		if !site.Session.Enabled && (site.Session.CookieFile != "" || len(site.Session.WarmUp) > 0) {
+			errs = append(errs, prefix+".session: cookie_file and warm_up need enabled: true")
+		}
+		for i, u := range site.Session.WarmUp {
+			if parsed, err := url.Parse(u); err != nil || parsed.Host == "" {
+				errs = append(errs, fmt.Sprintf("%s.session.warm_up[%d]: %q is not an absolute url", prefix, i, u))
+			}
+		}
+		for i, u := range site.Sitemap.URLs {
+			if parsed, err := url.Parse(u); err != nil || parsed.Host == "" {
+				errs = append(errs, fmt.Sprintf("%s.sitemap.urls[%d]: %q is not an absolute url", prefix, i, u))
+			}
+		}
+		if _, err := CompilePatterns(site.Sitemap.Patterns); err != nil {
+			errs = append(errs, prefix+".sitemap.patterns: "+err.Error())
+		}
+		paged := make([]string, 0, len(site.Pagination))
+		for stage := range site.Pagination {
+			paged = append(paged, stage)
+		}
+		sort.Strings(paged)
+		for _, stage := range paged {
+			pg := site.Pagination[stage]
+			stages := adapter.Stages()
+			if !hasStage(adapter, stage) || stage == stages[len(stages)-1] {
+				errs = append(errs, fmt.Sprintf("%s.pagination: stage %q has no listing to paginate", prefix, stage))
+				continue
+			}
+			if pg == nil {
+				errs = append(errs, fmt.Sprintf("%s.pagination.%s: empty", prefix, stage))
+				continue
+			}
+			for _, e := range pg.Validate() {
+				errs = append(errs, fmt.Sprintf("%s.pagination.%s.%s", prefix, stage, e))
+			}
+		}
+		for stage, header := range site.StageHeaders {
+			if !hasStage(adapter, stage) {
+				errs = append(errs, fmt.Sprintf("%s.stage_headers: unknown stage %q", prefix, stage))
+			}
+			if c.Headers[header] == nil {
+				errs = append(errs, fmt.Sprintf("%s.stage_headers.%s: unknown header profile %q", prefix, stage, header))
+			}
+		}
+	}
+	kinds := make(map[string]bool)
+	for i, sink := range c.Sinks {
+		prefix := fmt.Sprintf("sinks[%d]", i)
+		switch sink.Kind {
+		case "specs", "brands", "models", "failures", "quarantine":
+		default:
+			errs = append(errs, fmt.Sprintf("%s.kind: unknown kind %q", prefix, sink.Kind))
+		}
+		if kinds[sink.Kind] {
+			errs = append(errs, fmt.Sprintf("%s.kind: %s is configured twice", prefix, sink.Kind))
+		}
+		kinds[sink.Kind] = true
+		if sink.Path == "" {
+			errs = append(errs, prefix+".path: required")
+		}
+		switch sink.Format {
+		case "", "ndjson", "json":
+		default:
+			errs = append(errs, fmt.Sprintf("%s.format: unknown format %q", prefix, sink.Format))
+		}
+	}
+	if !kinds["specs"] {
+		errs = append(errs, "sinks: a specs sink is required")
+	}
+	if r := c.Resolve; r.Threshold <= 0 || r.Threshold > 1 {
+		errs = append(errs, "resolve.threshold: must be above 0 and at most 1")
+	}
+	if c.Resolve.Tolerance < 0 || c.Resolve.YearSlack < 0 {
+		errs = append(errs, "resolve: tolerance and year_slack must not be negative")
+	}
+	fields := make([]string, 0, len(c.Resolve.Rules))
+	for field := range c.Resolve.Rules {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		rule := c.Resolve.Rules[field]
+		known := false
+		for _, r := range ConflictRules {
+			known = known || r == rule
+		}
+		if !known {
+			errs = append(errs, fmt.Sprintf("resolve.rules.%s: unknown rule %q, known are %s", field, rule, strings.Join(ConflictRules, ", ")))
+		}
+	}
+	for _, e := range c.Report.Validate() {
+		errs = append(errs, "report."+e)
+	}
+	for _, e := range c.Schema.Validate() {
+		errs = append(errs, "schema."+e)
+	}
+	for _, e := range c.Events.Validate() {
+		errs = append(errs, "events."+e)
+	}
+	for _, e := range c.Daemon.Validate(c.Profiles) {
+		errs = append(errs, "daemon."+e)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (c *Config) CurrentSite() *SiteConfig {
+	return c.Sites[c.Site]
+}
+
+func (c *Config) Sink(kind string) (SinkConfig, bool) {
+	for _, sink := range c.Sinks {
+		if sink.Kind == kind {
+			return sink, true
+		}
+	}
+	return SinkConfig{}, false
+}
+
+func (s *SiteConfig) adapterName(name string) string {
+	if s.Adapter != "" {
+		return s.Adapter
+	}
+	return name
+}
+
+func (s *SiteConfig) adapter(name string) SiteAdapter {
+	return Adapters[s.adapterName(name)]
+}
+
+// Adapter returns the adapter crawling the current site.
+func (c *Config) Adapter() SiteAdapter {
+	return c.CurrentSite().adapter(c.Site)
+}
+
+// StageFuncList resolves the stage names of the current site through its
+// adapter.
+func (c *Config) StageFuncList() ([]ProcessFunc, error) {
+	adapter := c.Adapter()
+	site := c.CurrentSite()
+	funcs := make([]ProcessFunc, 0, len(site.Stages))
+	for _, name := range site.Stages {
+		pf, err := adapter.StageFunc(name, c)
+		if err != nil {
+			return nil, err
+		}
+		funcs = append(funcs, pf)
+	}
+	return funcs, nil
+}
+
+// PostStages builds the post-processing stages run on the specs of the
+// last stage, by name.
+var PostStages = map[string]func(*Config) (ProcessFunc, error){
+	"derive": func(*Config) (ProcessFunc, error) { return DeriveFunc, nil },
+	"validate": func(c *Config) (ProcessFunc, error) {
+		schema, err := c.Schema.Build()
+		if err != nil {
+			return nil, err
+		}
+		return ValidateFunc(schema), nil
+	},
+}
+
+// PostFuncList returns the post-processing stages of crawl.post in order.
+func (c *Config) PostFuncList() ([]ProcessFunc, error) {
+	funcs := make([]ProcessFunc, 0, len(c.Crawl.Post))
+	for _, name := range c.Crawl.Post {
+		build, ok := PostStages[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown post-processing stage %q", name)
+		}
+		pf, err := build(c)
+		if err != nil {
+			return nil, err
+		}
+		funcs = append(funcs, pf)
+	}
+	return funcs, nil
+}
+
+// StageIndex returns the position, in the current site's stage list, of the
+// stage of the adapter at index kind, e.g. the Stage of a Seed.
+func (c *Config) StageIndex(kind int) (int, error) {
+	stages := c.Adapter().Stages()
+	if kind < 0 || kind >= len(stages) {
+		return 0, fmt.Errorf("site %s has no stage %d", c.Site, kind)
+	}
+	for i, name := range c.CurrentSite().Stages {
+		if name == stages[kind] {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("site %s has no %s stage", c.Site, stages[kind])
+}
+
+func copyHeaderProfiles(profiles map[string]*HeaderProfile) map[string]*HeaderProfile {
+	copied := make(map[string]*HeaderProfile, len(profiles))
+	for name, profile := range profiles {
+		headers := make(map[string]string, len(profile.Headers))
+		for key, value := range profile.Headers {
+			headers[key] = value
+		}
+		copied[name] = &HeaderProfile{
+			Headers:     headers,
+			UserAgents:  append([]string{}, profile.UserAgents...),
+			DropCookies: profile.DropCookies,
+		}
+	}
+	return copied
+}
+
+// HeaderProfile resolves the header profile of a stage of the current site,
+// falling back to the site's profile.
+func (c *Config) HeaderProfile(stage string) *HeaderProfile {
+	site := c.CurrentSite()
+	name := site.Header
+	if override, ok := site.StageHeaders[stage]; ok {
+		name = override
+	}
+	profile := c.Headers[name]
+	if profile == nil {
+		return nil
+	}
+	// a copy, as the profiles of the config are shared by every stage
+	return &HeaderProfile{
+		Name:        name,
+		Headers:     profile.Headers,
+		UserAgents:  profile.UserAgents,
+		DropCookies: profile.DropCookies,
+	}
+}
+
+// Configure applies the crawl settings and the current site of config to
+// the processor running the named stage.
+func (p *Processor) Configure(config *Config, stage string) {
+	site := config.CurrentSite()
+	p.Selectors = &site.Selectors
+	p.Headers = config.HeaderProfile(stage)
+	p.Paging = site.Pagination[stage]
+	p.Interval = config.Crawl.Interval
+	p.Workers = config.Crawl.Concurrency
+	p.Retries = config.Crawl.Retries
+	p.Timeout = config.Crawl.Timeout
+}
+
+// Configure applies config to every processor; stages names the stage each
+// processor runs, in order.
+func (pl *Pipeline) Configure(config *Config, stages []string) {
+	for i, processor := range pl.ProcessorList {
+		processor.Configure(config, stages[i])
+	}
+}
+
+// OpenSession creates the session of the current site, loading its cookies
+// and running the warm-up requests. It returns nil when the site does not
+// use a session, unless the throttle is adaptive: only a session reports
+// the status and errors of every response to the limiter, so the crawl then
+// gets a session of its own, without cookies.
+func (c *Config) OpenSession(ctx context.Context) (*Session, error) {
+	site := c.CurrentSite()
+	if !site.Session.Enabled {
+		if !c.Crawl.Throttle.Adaptive {
+			return nil, nil
+		}
+		session, err := NewSession(c.Site, "", c.Crawl.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		session.Client.Jar = nil
+		return session, nil
+	}
+	session, err := NewSession(c.Site, site.Session.CookieFile, c.Crawl.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if proxies := c.Crawl.Proxies; len(proxies.URLs) > 0 {
+		maxFailures := proxies.MaxFailures
+		if maxFailures == 0 {
+			maxFailures = 3
+		}
+		cooldown := proxies.Cooldown
+		if cooldown == 0 {
+			cooldown = 300
+		}
+		pool, err := NewProxyPool(proxies.URLs, maxFailures, time.Duration(cooldown)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		session.UseProxies(pool)
+	}
+	if err := session.WarmUp(ctx, site.Session.WarmUp, c.HeaderProfile("")); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// OpenLimiter returns the rate limiter shared by every stage of a crawl.
+// crawl.interval is the spacing between two requests to one host; with an
+// adaptive throttle it is only the floor the spacing recovers to.
+func (c *Config) OpenLimiter() *RateLimiter {
+	limiter := NewRateLimiter(time.Duration(c.Crawl.Interval) * time.Second)
+	t := c.Crawl.Throttle
+	if !t.Adaptive {
+		limiter.Backoff = 1
+		limiter.Recovery = 1
+		limiter.SlowLatency = 0
+		return limiter
+	}
+	limiter.MaxDelay = time.Duration(t.MaxDelay) * time.Second
+	limiter.Backoff = t.Backoff
+	limiter.Recovery = t.Recovery
+	limiter.SlowLatency = time.Duration(t.SlowLatency) * time.Second
+	return limiter
+}
+
+// OpenRobots returns the robots.txt checker feeding Crawl-delay into
+// limiter, or nil when robots.txt is ignored.
+func (c *Config) OpenRobots(session *Session, limiter *RateLimiter) *Robots {
+	if !c.Crawl.Robots.Enabled {
+		return nil
+	}
+	var client *http.Client
+	if session != nil {
+		client = session.Client
+	}
+	robots := NewRobots(c.Crawl.Robots.Agent, client, limiter)
+	robots.Headers = c.HeaderProfile("")
+	return robots
+}
+
+// OpenSitemaps returns the sitemap reader of the current site. Without a
+// site session it uses a session of its own, without cookies.
+func (c *Config) OpenSitemaps(session *Session, robots *Robots, limiter *RateLimiter) (*SitemapReader, error) {
+	patterns, err := CompilePatterns(c.CurrentSite().Sitemap.Patterns)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		if session, err = NewSession(c.Site, "", c.Crawl.Timeout); err != nil {
+			return nil, err
+		}
+		session.Limiter = limiter
+	}
+	reader := NewSitemapReader(session, patterns)
+	reader.Headers = c.HeaderProfile("")
+	reader.Robots = robots
+	reader.Retries = c.Crawl.Retries
+	return reader, nil
+}
@@ -0,0 +1,96 @@
+package motospec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "motospec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "motospec.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0664); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const profileConfig = `
+version: 1
+crawl:
+  interval: 4
+resolve:
+  rules:
+    "*": prefer
+    description: longest
+profiles:
+  session:
+    crawl:
+      interval: 1
+    sites:
+      autoevolution:
+        session:
+          enabled: true
+          cookie_file: cookies.json
+    headers:
+      default:
+        drop_cookies: false
+    resolve:
+      rules:
+        "*": majority
+`
+
+func TestApplyProfile(t *testing.T) {
+	path := writeConfig(t, profileConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+	config, err := LoadConfig(path, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	site := config.Sites["autoevolution"]
+	if site == nil {
+		t.Fatal("site autoevolution is gone")
+	}
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"crawl.interval", config.Crawl.Interval, 1},
+		{"crawl.timeout", config.Crawl.Timeout, 10},
+		{"session.enabled", site.Session.Enabled, true},
+		{"session.cookie_file", site.Session.CookieFile, "cookies.json"},
+		{"seeds", len(site.Seeds), 1},
+		{"header", site.Header, "default"},
+		{"motorcycle.com", config.Sites["motorcycle.com"] != nil, true},
+		{"headers.default.drop_cookies", config.Headers["default"].DropCookies, false},
+		{"headers.default.user_agents", len(config.Headers["default"].UserAgents) > 0, true},
+		{"resolve.rules.*", config.Resolve.Rules["*"], RuleMajority},
+		{"resolve.rules.description", config.Resolve.Rules["description"], RuleLongest},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestApplyProfileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+	}{
+		{"unknown profile", "none"},
+		{"unknown key", "typo"},
+	}
+	path := writeConfig(t, profileConfig+"  typo:\n    crawl:\n      intervall: 1\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	for _, test := range tests {
+		if _, err := LoadConfig(path, test.profile); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package motospec
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type storedCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	HostOnly bool      `json:"host_only"`
+	Secure   bool      `json:"secure"`
+	Expires  time.Time `json:"expires,omitempty"`
+}
+
+func (c *storedCookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+func (c *storedCookie) matches(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if c.HostOnly {
+		if host != c.Domain {
+			return false
+		}
+	} else if host != c.Domain && !strings.HasSuffix(host, "."+c.Domain) {
+		return false
+	}
+	if c.Secure && u.Scheme != "https" {
+		return false
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	return path == c.Path || strings.HasPrefix(path, strings.TrimSuffix(c.Path, "/")+"/")
+}
+
+// cookieDomain returns the domain a cookie with the Domain attribute domain,
+// set by a response of host, is sent to and whether it is sent to that host
+// only. ok is false for a domain host does not match: a response may not
+// set cookies for another site. Without a public suffix list, a top-level
+// domain is taken for the host itself, like an ip address.
+func cookieDomain(host, domain string) (string, bool, bool) {
+	host = strings.ToLower(host)
+	domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+	switch {
+	case domain == "":
+		return host, true, true
+	case net.ParseIP(host) != nil || !strings.Contains(domain, "."):
+		return host, true, domain == host
+	case host != domain && !strings.HasSuffix(host, "."+domain):
+		return "", false, false
+	}
+	return domain, false, true
+}
+
+// Jar is an http.CookieJar that can be saved to and loaded from a file, so
+// a site session survives between runs. Session cookies without an expiry
+// are kept too: the crawler is the browser and never "closes".
+type Jar struct {
+	mutex   sync.Mutex
+	cookies map[string]*storedCookie
+}
+
+func NewJar() *Jar {
+	return &Jar{cookies: make(map[string]*storedCookie)}
+}
+
+func LoadJar(path string) (*Jar, error) {
+	jar := NewJar()
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return jar, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := []*storedCookie{}
+	if err := json.Unmarshal(content, &list); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, c := range list {
+		if !c.expired(now) {
+			jar.cookies[c.Domain+";"+c.Path+";"+c.Name] = c
+		}
+	}
+	return jar, nil
+}
+
+func (j *Jar) Save(path string) error {
+	j.mutex.Lock()
+	list := make([]*storedCookie, 0, len(j.cookies))
+	now := time.Now()
+	for _, c := range j.cookies {
+		if !c.expired(now) {
+			list = append(list, c)
+		}
+	}
+	j.mutex.Unlock()
+	content, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	now := time.Now()
+	for _, cookie := range cookies {
+		domain, hostOnly, ok := cookieDomain(u.Hostname(), cookie.Domain)
+		if !ok {
+			continue
+		}
+		c := &storedCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   domain,
+			Path:     cookie.Path,
+			HostOnly: hostOnly,
+			Secure:   cookie.Secure,
+		}
+		if c.Path == "" || !strings.HasPrefix(c.Path, "/") {
+			c.Path = "/"
+		}
+		switch {
+		case cookie.MaxAge < 0:
+			c.Expires = now
+		case cookie.MaxAge > 0:
+			c.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case !cookie.Expires.IsZero():
+			c.Expires = cookie.Expires
+		}
+		key := c.Domain + ";" + c.Path + ";" + c.Name
+		if c.expired(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = c
+	}
+}
+
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	now := time.Now()
+	cookies := []*http.Cookie{}
+	for key, c := range j.cookies {
+		if c.expired(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		if c.matches(u) {
+			cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value})
+		}
+	}
+	return cookies
+}
@@ -0,0 +1,99 @@
+package motospec
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCookieDomain(t *testing.T) {
+	tests := []struct {
+		host, domain string
+		want         string
+		hostOnly, ok bool
+	}{
+		{"www.autoevolution.com", "", "www.autoevolution.com", true, true},
+		{"WWW.Autoevolution.com", "", "www.autoevolution.com", true, true},
+		{"www.autoevolution.com", "autoevolution.com", "autoevolution.com", false, true},
+		{"www.autoevolution.com", ".Autoevolution.com", "autoevolution.com", false, true},
+		{"www.autoevolution.com", "www.autoevolution.com", "www.autoevolution.com", false, true},
+		{"www.autoevolution.com", "example.com", "", false, false},
+		{"www.autoevolution.com", "evolution.com", "", false, false},
+		{"www.autoevolution.com", "static.autoevolution.com", "", false, false},
+		{"www.autoevolution.com", "com", "", true, false},
+		{"localhost", "localhost", "localhost", true, true},
+		{"127.0.0.1", "127.0.0.1", "127.0.0.1", true, true},
+		{"127.0.0.1", "0.0.1", "", true, false},
+	}
+	for _, test := range tests {
+		domain, hostOnly, ok := cookieDomain(test.host, test.domain)
+		if ok != test.ok || ok && (domain != test.want || hostOnly != test.hostOnly) {
+			t.Errorf("%s %q: got %q %v %v, want %q %v %v", test.host, test.domain, domain, hostOnly, ok, test.want, test.hostOnly, test.ok)
+		}
+	}
+}
+
+func cookieNames(cookies []*http.Cookie) string {
+	names := []string{}
+	for _, c := range cookies {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestJar(t *testing.T) {
+	jar := NewJar()
+	origin, _ := url.Parse("https://www.autoevolution.com/moto/")
+	jar.SetCookies(origin, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "site", Value: "1", Domain: ".autoevolution.com"},
+		{Name: "secure", Value: "1", Secure: true},
+		{Name: "moto", Value: "1", Path: "/moto"},
+		{Name: "foreign", Value: "1", Domain: "example.com"},
+		{Name: "tld", Value: "1", Domain: "com"},
+		{Name: "gone", Value: "1", MaxAge: -1},
+		{Name: "expired", Value: "1", Expires: time.Now().Add(-time.Hour)},
+	})
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.autoevolution.com/moto/ducati.html", "host,moto,secure,site"},
+		{"http://www.autoevolution.com/moto/ducati.html", "host,moto,site"},
+		{"https://www.autoevolution.com/", "host,secure,site"},
+		{"https://www.autoevolution.com/motorcycles", "host,secure,site"},
+		{"https://static.autoevolution.com/", "site"},
+		{"https://example.com/", ""},
+		{"https://other.com/", ""},
+	}
+	check := func(jar *Jar) {
+		for _, test := range tests {
+			u, _ := url.Parse(test.url)
+			if got := cookieNames(jar.Cookies(u)); got != test.want {
+				t.Errorf("%s: got %q, want %q", test.url, got, test.want)
+			}
+		}
+	}
+	check(jar)
+	dir, err := ioutil.TempDir("", "jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cookies.json")
+	if err := jar.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(loaded)
+}
@@ -0,0 +1,178 @@
+package motospec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron expression: minute, hour, day of month, month and day
+// of week, each *, a value, a range a-b, a list a,b and a step */n or a-b/n.
+// Months and weekdays may be named by their first three letters, Sunday is 0
+// or 7. As in cron, when both day fields are restricted, neither starting
+// with *, a day matching either one fires.
+type Schedule struct {
+	expr    string
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool
+	dowStar bool
+}
+
+var scheduleAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{"day of week", 0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s: %q is not between %d and %d", f.name, s, f.min, f.max)
+	}
+	return n, nil
+}
+
+// parse returns the bits of the values s matches and whether it starts
+// with *, a step */n counting as * for the day fields as it does in cron.
+func (f cronField) parse(s string) (uint64, bool, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, false, fmt.Errorf("%s: invalid step in %q", f.name, part)
+			}
+			step, part = n, part[:i]
+		}
+		from, to := f.min, f.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = f.value(bounds[0]); err != nil {
+				return 0, false, err
+			}
+			if to, err = f.value(bounds[1]); err != nil {
+				return 0, false, err
+			}
+			if from > to {
+				return 0, false, fmt.Errorf("%s: empty range %q", f.name, part)
+			}
+		default:
+			var err error
+			if from, err = f.value(part); err != nil {
+				return 0, false, err
+			}
+			if step == 1 {
+				to = from
+			}
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, strings.HasPrefix(s, "*"), nil
+}
+
+func ParseSchedule(expr string) (*Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if alias, ok := scheduleAliases[strings.ToLower(spec)]; ok {
+		spec = alias
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("schedule %q: want %d fields or an @alias", expr, len(cronFields))
+	}
+	s := &Schedule{expr: expr}
+	bits := []*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, f := range cronFields {
+		var star bool
+		var err error
+		if *bits[i], star, err = f.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("schedule %q: %v", expr, err)
+		}
+		switch i {
+		case 2:
+			s.domStar = star
+		case 4:
+			s.dowStar = star
+		}
+	}
+	// Sunday is both 0 and 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t the schedule fires, in the location
+// of t, or the zero time when it never does, as for February 30.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		next := t.Add(time.Minute)
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			next = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+		default:
+			return t
+		}
+		// time.Date gives a wall clock skipped by a DST change an hour
+		// early, step through the gap instead
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}
+}
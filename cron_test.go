@@ -0,0 +1,97 @@
+package motospec
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		expr string
+		ok   bool
+	}{
+		{"*/15 * * * *", true},
+		{"0 9 * jan-mar mon-fri", true},
+		{"0,30 8-18/2 1,15 * 7", true},
+		{"@Daily", true},
+		{"", false},
+		{"* * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"* * * * 8", false},
+		{"5-1 * * * *", false},
+		{"*/0 * * * *", false},
+		{"a * * * *", false},
+		{"@often", false},
+	}
+	for _, test := range tests {
+		schedule, err := ParseSchedule(test.expr)
+		if (err == nil) != test.ok {
+			t.Errorf("%q: got %v", test.expr, err)
+		}
+		if err == nil && schedule.String() != test.expr {
+			t.Errorf("%q: string %q", test.expr, schedule.String())
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"minute step", "*/15 * * * *", date(2026, 10, 19, 10, 7), date(2026, 10, 19, 10, 15)},
+		{"strictly after", "*/15 * * * *", date(2026, 10, 19, 10, 15), date(2026, 10, 19, 10, 30)},
+		{"hour rollover", "0 * * * *", date(2026, 10, 19, 10, 59), date(2026, 10, 19, 11, 0)},
+		{"day rollover", "30 23 * * *", date(2026, 10, 19, 23, 45), date(2026, 10, 20, 23, 30)},
+		{"year rollover", "0 0 1 * *", date(2026, 12, 31, 12, 0), date(2027, 1, 1, 0, 0)},
+		{"month without the day", "0 0 31 * *", date(2026, 2, 1, 0, 0), date(2026, 3, 31, 0, 0)},
+		{"names", "0 9 * jan-mar mon-fri", date(2026, 10, 19, 12, 0), date(2027, 1, 1, 9, 0)},
+		{"weekday only", "0 0 * * 1", date(2026, 10, 19, 12, 0), date(2026, 10, 26, 0, 0)},
+		{"day of month or weekday", "0 0 20 * 1", date(2026, 10, 19, 12, 0), date(2026, 10, 20, 0, 0)},
+		{"stepped day of month and weekday", "0 0 */2 * 1", date(2026, 10, 19, 12, 0), date(2026, 11, 9, 0, 0)},
+		{"sunday as 7", "0 0 * * 7", date(2026, 10, 19, 12, 0), date(2026, 10, 25, 0, 0)},
+		{"alias", "@weekly", date(2026, 10, 19, 12, 0), date(2026, 10, 25, 0, 0)},
+		{"february 30", "0 0 30 2 *", date(2026, 10, 19, 12, 0), time.Time{}},
+	}
+	for _, test := range tests {
+		schedule, err := ParseSchedule(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := schedule.Next(test.from); !got.Equal(test.want) {
+			t.Errorf("%s: %q from %s: got %s, want %s", test.name, test.expr, test.from, got, test.want)
+		}
+	}
+}
+
+func TestScheduleNextLocation(t *testing.T) {
+	// a half hour zone keeps the wall clock of the schedule
+	india := time.FixedZone("IST", 5*3600+1800)
+	schedule, _ := ParseSchedule("0 9 * * *")
+	from := time.Date(2026, 10, 19, 8, 59, 0, 0, india)
+	if got, want := schedule.Next(from), time.Date(2026, 10, 19, 9, 0, 0, 0, india); !got.Equal(want) {
+		t.Errorf("IST: got %s, want %s", got, want)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// 2:30 does not exist on the day clocks spring forward
+	schedule, _ = ParseSchedule("30 2 * * *")
+	from = time.Date(2026, 3, 7, 12, 0, 0, 0, newYork)
+	got := schedule.Next(from)
+	if got.Before(from) || got.After(time.Date(2026, 3, 9, 2, 30, 0, 0, newYork)) {
+		t.Errorf("spring forward: got %s", got)
+	}
+	if next := schedule.Next(got); !next.After(got) {
+		t.Errorf("spring forward: %s after %s", next, got)
+	}
+}
@@ -0,0 +1,409 @@
+package motospec
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	JobOK          = "ok"
+	JobFailed      = "failed"
+	JobSkipped     = "skipped"
+	JobInterrupted = "interrupted"
+)
+
+// JobConfig is a crawl the daemon runs on Schedule, with the named profile
+// of the config applied. An incremental job crawls the sitemap pages changed
+// since its last successful run.
+type JobConfig struct {
+	Name        string `yaml:"name" json:"name"`
+	Schedule    string `yaml:"schedule" json:"schedule"`
+	Profile     string `yaml:"profile" json:"profile,omitempty"`
+	Incremental bool   `yaml:"incremental" json:"incremental,omitempty"`
+}
+
+type DaemonConfig struct {
+	Addr     string      `yaml:"addr"`
+	LockFile string      `yaml:"lock_file"`
+	History  string      `yaml:"history"`
+	Jobs     []JobConfig `yaml:"jobs"`
+}
+
+func (c DaemonConfig) Validate(profiles map[string]interface{}) []string {
+	errs := []string{}
+	if c.LockFile == "" || c.History == "" {
+		errs = append(errs, "lock_file and history are required")
+	}
+	names := make(map[string]bool)
+	for i, job := range c.Jobs {
+		prefix := fmt.Sprintf("jobs[%d]", i)
+		if job.Name == "" {
+			errs = append(errs, prefix+".name: required")
+		} else if names[job.Name] {
+			errs = append(errs, fmt.Sprintf("%s.name: %s is configured twice", prefix, job.Name))
+		}
+		names[job.Name] = true
+		if schedule, err := ParseSchedule(job.Schedule); err != nil {
+			errs = append(errs, prefix+".schedule: "+err.Error())
+		} else if schedule.Next(time.Now()).IsZero() {
+			errs = append(errs, fmt.Sprintf("%s.schedule: %q never fires", prefix, job.Schedule))
+		}
+		if _, ok := profiles[job.Profile]; job.Profile != "" && !ok {
+			errs = append(errs, fmt.Sprintf("%s.profile: unknown profile %q", prefix, job.Profile))
+		}
+	}
+	return errs
+}
+
+// JobRun is the outcome of a run of a job.
+type JobRun struct {
+	Job      string    `json:"job"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	Specs    int       `json:"specs"`
+	Snapshot *Snapshot `json:"snapshot,omitempty"`
+}
+
+// ErrLocked tells the lock file is held by a live process.
+type ErrLocked struct {
+	Path string
+	PID  int
+}
+
+func (e *ErrLocked) Error() string {
+	return fmt.Sprintf("%s is held by process %d", e.Path, e.PID)
+}
+
+// Lock is a lock file holding the id of the process owning it.
+type Lock struct {
+	path string
+}
+
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+// AcquireLock takes the lock file at path, taking over one left behind by
+// a process that is gone.
+func AcquireLock(path string) (*Lock, error) {
+	// the pid is written aside and linked in place, so the lock file is
+	// never seen empty
+	tmp := fmt.Sprintf("%s.%d", path, os.Getpid())
+	if err := ioutil.WriteFile(tmp, []byte(strconv.Itoa(os.Getpid())+"\n"), 0664); err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+	for attempt := 0; ; attempt++ {
+		err := os.Link(tmp, path)
+		if err == nil {
+			return &Lock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(content)))
+		if attempt > 0 || pid > 0 && processAlive(pid) {
+			return nil, &ErrLocked{Path: path, PID: pid}
+		}
+		ProcessorLogger.Printf("Daemon: removing the stale lock %s of process %d", path, pid)
+		os.Remove(path)
+	}
+}
+
+func (l *Lock) Release() error {
+	return os.Remove(l.path)
+}
+
+// JobHistory is the list of job runs, kept in memory and appended to an
+// NDJSON file.
+type JobHistory struct {
+	path  string
+	runs  []JobRun
+	mutex sync.RWMutex
+}
+
+func OpenJobHistory(path string) (*JobHistory, error) {
+	h := &JobHistory{path: path, runs: []JobRun{}}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var run JobRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		h.runs = append(h.runs, run)
+	}
+	return h, scanner.Err()
+}
+
+func (h *JobHistory) Add(run JobRun) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.runs = append(h.runs, run)
+	sink, err := NewJSONSink(h.path)
+	if err != nil {
+		return err
+	}
+	if err := sink.Write(run); err != nil {
+		sink.Close()
+		return err
+	}
+	return sink.Close()
+}
+
+// Runs returns the runs of job, or of all jobs when job is empty, newest
+// first, at most limit of them unless limit is 0.
+func (h *JobHistory) Runs(job string, limit int) []JobRun {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	runs := []JobRun{}
+	for i := len(h.runs) - 1; i >= 0 && (limit == 0 || len(runs) < limit); i-- {
+		if job == "" || h.runs[i].Job == job {
+			runs = append(runs, h.runs[i])
+		}
+	}
+	return runs
+}
+
+// Last returns the latest run of job with status, any status when empty.
+func (h *JobHistory) Last(job, status string) (JobRun, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for i := len(h.runs) - 1; i >= 0; i-- {
+		if h.runs[i].Job == job && (status == "" || h.runs[i].Status == status) {
+			return h.runs[i], true
+		}
+	}
+	return JobRun{}, false
+}
+
+// JobFunc runs a job, since being the start of its last successful run,
+// and returns the stats of the run.
+type JobFunc func(ctx context.Context, job JobConfig, since time.Time) (JobRun, error)
+
+type daemonJob struct {
+	config   JobConfig
+	schedule *Schedule
+	next     time.Time
+	running  time.Time
+}
+
+// Daemon runs jobs on their schedules. Runs never overlap: a run takes the
+// lock file first and is recorded as skipped when it cannot.
+type Daemon struct {
+	LockFile string
+	History  *JobHistory
+	Run      JobFunc
+
+	started time.Time
+	jobs    []*daemonJob
+	mutex   sync.Mutex
+	wg      sync.WaitGroup
+}
+
+func NewDaemon(config DaemonConfig, run JobFunc) (*Daemon, error) {
+	if len(config.Jobs) == 0 {
+		return nil, fmt.Errorf("daemon: no jobs configured")
+	}
+	history, err := OpenJobHistory(config.History)
+	if err != nil {
+		return nil, err
+	}
+	d := &Daemon{LockFile: config.LockFile, History: history, Run: run}
+	for _, job := range config.Jobs {
+		schedule, err := ParseSchedule(job.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %v", job.Name, err)
+		}
+		d.jobs = append(d.jobs, &daemonJob{config: job, schedule: schedule})
+	}
+	return d, nil
+}
+
+// Start runs the jobs until ctx is done, then waits for the running one.
+func (d *Daemon) Start(ctx context.Context) {
+	d.mutex.Lock()
+	d.started = time.Now()
+	for _, job := range d.jobs {
+		job.next = job.schedule.Next(d.started)
+	}
+	d.mutex.Unlock()
+	for {
+		d.mutex.Lock()
+		var due time.Time
+		for _, job := range d.jobs {
+			if !job.next.IsZero() && (due.IsZero() || job.next.Before(due)) {
+				due = job.next
+			}
+		}
+		d.mutex.Unlock()
+		if due.IsZero() {
+			ErrProcessorLogger.Println("Daemon: no job will ever run")
+			<-ctx.Done()
+			break
+		}
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.wg.Wait()
+			return
+		case now := <-timer.C:
+			d.mutex.Lock()
+			for _, job := range d.jobs {
+				if !job.next.IsZero() && !job.next.After(now) {
+					job.next = job.schedule.Next(now)
+					d.wg.Add(1)
+					go func(job *daemonJob) {
+						defer d.wg.Done()
+						d.runJob(ctx, job)
+					}(job)
+				}
+			}
+			d.mutex.Unlock()
+		}
+	}
+	d.wg.Wait()
+}
+
+// RunJob runs the named job once, now, as its schedule would.
+func (d *Daemon) RunJob(ctx context.Context, name string) (JobRun, error) {
+	for _, job := range d.jobs {
+		if job.config.Name == name {
+			return d.runJob(ctx, job), nil
+		}
+	}
+	return JobRun{}, fmt.Errorf("no job %q", name)
+}
+
+func (d *Daemon) runJob(ctx context.Context, job *daemonJob) JobRun {
+	run := JobRun{Job: job.config.Name, Start: time.Now()}
+	lock, err := AcquireLock(d.LockFile)
+	if err != nil {
+		run.End, run.Status, run.Error = time.Now(), JobSkipped, err.Error()
+		d.record(run)
+		return run
+	}
+	defer lock.Release()
+	d.mutex.Lock()
+	job.running = run.Start
+	d.mutex.Unlock()
+	var since time.Time
+	if last, ok := d.History.Last(job.config.Name, JobOK); ok {
+		since = last.Start
+	}
+	ProcessorLogger.Printf("Daemon: starting job %s", job.config.Name)
+	stats, err := d.Run(ctx, job.config, since)
+	run.Specs, run.Snapshot = stats.Specs, stats.Snapshot
+	run.End, run.Status = time.Now(), JobOK
+	switch {
+	case err != nil:
+		run.Status, run.Error = JobFailed, err.Error()
+	case ctx.Err() != nil:
+		run.Status = JobInterrupted
+	}
+	d.mutex.Lock()
+	job.running = time.Time{}
+	d.mutex.Unlock()
+	d.record(run)
+	return run
+}
+
+func (d *Daemon) record(run JobRun) {
+	ProcessorLogger.Printf("Daemon: job %s %s %s", run.Job, run.Status, run.Error)
+	if err := d.History.Add(run); err != nil {
+		ErrProcessorLogger.Printf("Daemon: job history: %v", err)
+	}
+}
+
+type JobStatus struct {
+	JobConfig
+	Next    *time.Time `json:"next,omitempty"`
+	Running *time.Time `json:"running_since,omitempty"`
+	Last    *JobRun    `json:"last,omitempty"`
+}
+
+type DaemonStatus struct {
+	Started time.Time   `json:"started"`
+	PID     int         `json:"pid"`
+	Jobs    []JobStatus `json:"jobs"`
+}
+
+func (d *Daemon) Status() DaemonStatus {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	status := DaemonStatus{Started: d.started, PID: os.Getpid(), Jobs: []JobStatus{}}
+	for _, job := range d.jobs {
+		s := JobStatus{JobConfig: job.config}
+		if next := job.next; !next.IsZero() {
+			s.Next = &next
+		}
+		if running := job.running; !running.IsZero() {
+			s.Running = &running
+		}
+		if last, ok := d.History.Last(job.config.Name, ""); ok {
+			s.Last = &last
+		}
+		status.Jobs = append(status.Jobs, s)
+	}
+	return status
+}
+
+// ServeHTTP serves the state of the daemon:
+//
+//	GET /status                   the jobs, their next and last runs
+//	GET /history?job=&limit=100   past runs, newest first
+func (d *Daemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		writeError(w, http.StatusMethodNotAllowed, "only GET is supported")
+		return
+	}
+	switch strings.Trim(r.URL.Path, "/") {
+	case "", "status":
+		writeJSON(w, r, d.Status())
+	case "history":
+		limit := 100
+		if s := r.URL.Query().Get("limit"); s != "" {
+			var err error
+			if limit, err = strconv.Atoi(s); err != nil || limit < 0 {
+				writeError(w, http.StatusBadRequest, "limit: must be a number, 0 for all")
+				return
+			}
+		}
+		writeJSON(w, r, d.History.Runs(r.URL.Query().Get("job"), limit))
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint")
+	}
+}
@@ -0,0 +1,136 @@
+package motospec
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "motospec.lock")
+
+	lock, err := AcquireLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadFile(path)
+	if strings.TrimSpace(string(content)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("lock file holds %q, want pid %d", content, os.Getpid())
+	}
+	if _, err := AcquireLock(path); err == nil {
+		t.Error("acquired a lock held by a live process")
+	} else if locked, ok := err.(*ErrLocked); !ok || locked.PID != os.Getpid() {
+		t.Errorf("held lock: got %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a process that exited leaves a stale lock behind
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	lock, err = AcquireLock(path)
+	if err != nil {
+		t.Fatalf("stale lock: %v", err)
+	}
+	lock.Release()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("released lock still there: %v", err)
+	}
+	if matches, _ := filepath.Glob(path + ".*"); len(matches) > 0 {
+		t.Errorf("left behind %v", matches)
+	}
+}
+
+func TestJobHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.json")
+	history, err := OpenJobHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	for i, run := range []JobRun{
+		{Job: "full", Status: JobOK},
+		{Job: "daily", Status: JobOK},
+		{Job: "daily", Status: JobFailed, Error: "timeout"},
+	} {
+		run.Start = start.Add(time.Duration(i) * time.Hour)
+		if err := history.Add(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reopened, err := OpenJobHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		job   string
+		limit int
+		want  []string
+	}{
+		{"", 0, []string{"daily failed", "daily ok", "full ok"}},
+		{"", 2, []string{"daily failed", "daily ok"}},
+		{"daily", 0, []string{"daily failed", "daily ok"}},
+		{"weekly", 0, []string{}},
+	}
+	for _, test := range tests {
+		got := []string{}
+		for _, run := range reopened.Runs(test.job, test.limit) {
+			got = append(got, run.Job+" "+run.Status)
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%q %d: got %q, want %q", test.job, test.limit, got, test.want)
+		}
+	}
+	if run, ok := reopened.Last("daily", JobOK); !ok || !run.Start.Equal(start.Add(time.Hour)) {
+		t.Errorf("last ok daily run: %+v %v", run, ok)
+	}
+	if _, ok := reopened.Last("weekly", ""); ok {
+		t.Error("found a run of a job never run")
+	}
+}
+
+func TestDaemonConfigValidate(t *testing.T) {
+	profiles := map[string]interface{}{"session": nil}
+	tests := []struct {
+		name string
+		jobs []JobConfig
+		errs int
+	}{
+		{"valid", []JobConfig{{Name: "daily", Schedule: "@daily", Profile: "session"}, {Name: "weekly", Schedule: "0 3 * * sun"}}, 0},
+		{"unnamed", []JobConfig{{Schedule: "@daily"}}, 1},
+		{"twice", []JobConfig{{Name: "daily", Schedule: "@daily"}, {Name: "daily", Schedule: "@hourly"}}, 1},
+		{"bad schedule", []JobConfig{{Name: "daily", Schedule: "daily"}}, 1},
+		{"never fires", []JobConfig{{Name: "leap", Schedule: "0 0 30 2 *"}}, 1},
+		{"unknown profile", []JobConfig{{Name: "daily", Schedule: "@daily", Profile: "fast"}}, 1},
+	}
+	for _, test := range tests {
+		config := DaemonConfig{LockFile: "motospec.lock", History: "history.json", Jobs: test.jobs}
+		if errs := config.Validate(profiles); len(errs) != test.errs {
+			t.Errorf("%s: got %q", test.name, errs)
+		}
+	}
+	if errs := (DaemonConfig{}).Validate(profiles); len(errs) != 1 {
+		t.Errorf("no lock file nor history: got %q", errs)
+	}
+}
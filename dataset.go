@@ -0,0 +1,221 @@
+package motospec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func (s Spec) Key() string {
+	if s.URL != "" {
+		return s.URL
+	}
+	key := strings.Join([]string{s.Brand, s.Model, s.Moto, s.Year}, "|")
+	if s.Source != "" {
+		key = s.Source + "|" + key
+	}
+	return key
+}
+
+func LoadSpecs(path string) ([]Spec, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadSpecs(file)
+}
+
+// ReadSpecs accepts both the line delimited output of a crawl and a single
+// JSON array of specs.
+func ReadSpecs(r io.Reader) ([]Spec, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimSpace(content)
+	specs := []Spec{}
+	if len(content) > 0 && content[0] == '[' {
+		if err := json.Unmarshal(content, &specs); err != nil {
+			return nil, err
+		}
+		return specs, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		var spec Spec
+		err := decoder.Decode(&spec)
+		if err == io.EOF {
+			return specs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+}
+
+func SpecKeys(specs []Spec) []string {
+	set := make(map[string]bool)
+	for _, spec := range specs {
+		for key := range spec.Specs {
+			set[key] = true
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var derivedColumns = []string{
+	"power_to_weight_hp_kg", "torque_to_weight_nm_kg", "specific_output_hp_l", "bore_stroke_ratio", "cylinders",
+	"estimated_displacement_cc", "range_km", "flags",
+}
+
+func derivedRow(d *Derived) []string {
+	if d == nil {
+		return make([]string, len(derivedColumns))
+	}
+	number := func(value float64) string {
+		if value == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	cylinders := ""
+	if d.Cylinders > 0 {
+		cylinders = strconv.Itoa(d.Cylinders)
+	}
+	return []string{
+		number(d.PowerToWeight), number(d.TorqueToWeight), number(d.SpecificOutput), number(d.BoreStrokeRatio), cylinders,
+		number(d.EstimatedDisplacement), number(d.Range), strings.Join(d.Flags, "; "),
+	}
+}
+
+// WriteCSV writes a row per spec, the derived columns last when any spec
+// has derived values.
+func WriteCSV(w io.Writer, specs []Spec) error {
+	keys := SpecKeys(specs)
+	derived := false
+	for _, spec := range specs {
+		if spec.Derived != nil {
+			derived = true
+			break
+		}
+	}
+	writer := csv.NewWriter(w)
+	header := append([]string{"brand", "model", "type", "year", "url", "source"}, keys...)
+	if derived {
+		header = append(header, derivedColumns...)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		row := []string{spec.Brand, spec.Model, spec.Moto, spec.Year, spec.URL, spec.Source}
+		for _, key := range keys {
+			row = append(row, strings.TrimSpace(spec.Specs[key]))
+		}
+		if derived {
+			row = append(row, derivedRow(spec.Derived)...)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+const (
+	SpecAdded   = "added"
+	SpecRemoved = "removed"
+	SpecChanged = "changed"
+)
+
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type SpecChange struct {
+	Kind    string        `json:"kind"`
+	Key     string        `json:"key"`
+	Old     *Spec         `json:"old,omitempty"`
+	New     *Spec         `json:"new,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+func DiffSpecs(olds, news []Spec) []SpecChange {
+	oldMap := make(map[string]Spec, len(olds))
+	for _, spec := range olds {
+		oldMap[spec.Key()] = spec
+	}
+	newMap := make(map[string]Spec, len(news))
+	for _, spec := range news {
+		newMap[spec.Key()] = spec
+	}
+	changes := []SpecChange{}
+	for key, n := range newMap {
+		n := n
+		o, ok := oldMap[key]
+		if !ok {
+			changes = append(changes, SpecChange{Kind: SpecAdded, Key: key, New: &n})
+			continue
+		}
+		if fields := diffFields(o, n); len(fields) > 0 {
+			o := o
+			changes = append(changes, SpecChange{Kind: SpecChanged, Key: key, Old: &o, New: &n, Changes: fields})
+		}
+	}
+	for key, o := range oldMap {
+		o := o
+		if _, ok := newMap[key]; !ok {
+			changes = append(changes, SpecChange{Kind: SpecRemoved, Key: key, Old: &o})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Key != changes[j].Key {
+			return changes[i].Key < changes[j].Key
+		}
+		return changes[i].Kind < changes[j].Kind
+	})
+	return changes
+}
+
+func diffFields(o, n Spec) []FieldChange {
+	fields := []FieldChange{}
+	identity := [][3]string{
+		{"brand", o.Brand, n.Brand},
+		{"model", o.Model, n.Model},
+		{"type", o.Moto, n.Moto},
+		{"year", o.Year, n.Year},
+		{"description", o.Description, n.Description},
+	}
+	for _, f := range identity {
+		if f[1] != f[2] {
+			fields = append(fields, FieldChange{Field: f[0], Old: f[1], New: f[2]})
+		}
+	}
+	keys := SpecKeys([]Spec{o, n})
+	for _, key := range keys {
+		if ov, nv := o.Specs[key], n.Specs[key]; ov != nv {
+			fields = append(fields, FieldChange{Field: "specs." + key, Old: ov, New: nv})
+		}
+	}
+	if !reflect.DeepEqual(o.Images, n.Images) {
+		fields = append(fields, FieldChange{Field: "images"})
+	}
+	return fields
+}
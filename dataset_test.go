@@ -0,0 +1,104 @@
+package motospec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadSpecs(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+		ok    bool
+	}{
+		{"ndjson", `{"brand":"Ducati","type":"Monster"}` + "\n\n" + `{"brand":"BMW","type":"R 18"}` + "\n", []string{"Monster", "R 18"}, true},
+		{"array", ` [{"brand":"Ducati","type":"Monster"},{"brand":"BMW","type":"R 18"}]`, []string{"Monster", "R 18"}, true},
+		{"empty", "\n", []string{}, true},
+		{"truncated", `{"brand":"Ducati","type":"Monster"}` + "\n" + `{"brand":`, nil, false},
+	}
+	for _, test := range tests {
+		specs, err := ReadSpecs(strings.NewReader(test.input))
+		if (err == nil) != test.ok {
+			t.Errorf("%s: got %v", test.name, err)
+			continue
+		}
+		got := []string{}
+		for _, spec := range specs {
+			got = append(got, spec.Moto)
+		}
+		if err == nil && strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSpecKey(t *testing.T) {
+	tests := []struct {
+		spec Spec
+		want string
+	}{
+		{Spec{Brand: "Ducati", Model: "Monster", Moto: "821", Year: "2017", URL: "https://example.com/821"}, "https://example.com/821"},
+		{Spec{Brand: "Ducati", Model: "Monster", Moto: "821", Year: "2017"}, "Ducati|Monster|821|2017"},
+		{Spec{Source: "motorcycle", Brand: "Ducati", Model: "Monster", Moto: "821", Year: "2017"}, "motorcycle|Ducati|Monster|821|2017"},
+	}
+	for _, test := range tests {
+		if got := test.spec.Key(); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	monster := Spec{Brand: "Ducati", Model: "Monster", Moto: "821", Year: "2017", URL: "https://example.com/821",
+		Specs: map[string]string{"Power": " 109 hp ", "Weight": "206 kg"}}
+	derived := monster
+	derived.Derived = &Derived{PowerToWeight: 0.529, Cylinders: 2, Flags: []string{"a", "b"}}
+	r18 := Spec{Brand: "BMW", Model: "R 18", Moto: "R 18", Year: "2020", Specs: map[string]string{"Torque": "158 Nm"}}
+	tests := []struct {
+		name  string
+		specs []Spec
+		want  string
+	}{
+		{"plain", []Spec{monster, r18},
+			"brand,model,type,year,url,source,Power,Torque,Weight\n" +
+				"Ducati,Monster,821,2017,https://example.com/821,,109 hp,,206 kg\n" +
+				"BMW,R 18,R 18,2020,,,,158 Nm,\n"},
+		{"derived", []Spec{derived, r18},
+			"brand,model,type,year,url,source,Power,Torque,Weight," + strings.Join(derivedColumns, ",") + "\n" +
+				"Ducati,Monster,821,2017,https://example.com/821,,109 hp,,206 kg,0.529,,,,2,,,a; b\n" +
+				"BMW,R 18,R 18,2020,,,,158 Nm,,,,,,,,,\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteCSV(&buf, test.specs); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, buf.String(), test.want)
+		}
+	}
+}
+
+func TestDiffSpecs(t *testing.T) {
+	spec := func(url, power string) Spec {
+		return Spec{Brand: "Ducati", Model: "Monster", Moto: url, Year: "2017", URL: url, Specs: map[string]string{"Power": power}}
+	}
+	olds := []Spec{spec("a", "100 hp"), spec("b", "110 hp"), spec("c", "120 hp")}
+	news := []Spec{spec("b", "111 hp"), spec("c", "120 hp"), spec("d", "130 hp")}
+	news[1].Images = []Image{{URL: "c.jpg"}}
+	changes := DiffSpecs(olds, news)
+	got := []string{}
+	for _, change := range changes {
+		fields := []string{}
+		for _, field := range change.Changes {
+			fields = append(fields, field.Field+" "+field.Old+">"+field.New)
+		}
+		got = append(got, change.Key+" "+change.Kind+" "+strings.Join(fields, ";"))
+	}
+	want := []string{"a removed ", "b changed specs.Power 110 hp>111 hp", "c changed images >", "d added "}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,171 @@
+package motospec
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DisplacementTolerance is how far, relative to the stated displacement,
+// the displacement worked out from bore, stroke and cylinders may be off
+// before a spec is flagged.
+const DisplacementTolerance = 0.1
+
+// An outlier rule flags numbers no production bike has.
+type outlierRule struct {
+	reason string
+	match  func(Normalized, *Derived) bool
+}
+
+var outlierRules = []outlierRule{
+	{"over 8 cc per kg", func(n Normalized, _ *Derived) bool { return n.Weight > 0 && n.Displacement/n.Weight > 8 }},
+	{"over 1.5 hp per kg", func(_ Normalized, d *Derived) bool { return d.PowerToWeight > 1.5 }},
+	{"over 350 hp per litre", func(_ Normalized, d *Derived) bool { return d.SpecificOutput > 350 }},
+	{"lighter than 40 kg", func(n Normalized, _ *Derived) bool { return n.Weight > 0 && n.Weight < 40 }},
+	{"heavier than 600 kg", func(n Normalized, _ *Derived) bool { return n.Weight > 600 }},
+	{"over 3000 cc", func(n Normalized, _ *Derived) bool { return n.Displacement > 3000 }},
+	{"over 400 hp", func(n Normalized, _ *Derived) bool { return n.Power > 400 }},
+}
+
+// Derived holds the values worked out from the parsed specs, and Flags the
+// inconsistencies found on the way. Its ratios are the ones compare shows.
+type Derived struct {
+	PowerToWeight         float64  `json:"power_to_weight_hp_kg,omitempty"`
+	TorqueToWeight        float64  `json:"torque_to_weight_nm_kg,omitempty"`
+	SpecificOutput        float64  `json:"specific_output_hp_l,omitempty"`
+	Bore                  float64  `json:"bore_mm,omitempty"`
+	Stroke                float64  `json:"stroke_mm,omitempty"`
+	BoreStrokeRatio       float64  `json:"bore_stroke_ratio,omitempty"`
+	Cylinders             int      `json:"cylinders,omitempty"`
+	EstimatedDisplacement float64  `json:"estimated_displacement_cc,omitempty"`
+	FuelCapacity          float64  `json:"fuel_capacity_l,omitempty"`
+	Consumption           float64  `json:"consumption_l_100km,omitempty"`
+	Range                 float64  `json:"range_km,omitempty"`
+	Flags                 []string `json:"flags,omitempty"`
+}
+
+var (
+	boreStrokePattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*x\s*(\d+(?:\.\d+)?)\s*(mm|in)`)
+	cylinderPattern   = regexp.MustCompile(`\b(single|one|two|twin|three|four|five|six|eight|\d)[- ]?cyl(?:inder)?s?\b`)
+	layoutPattern     = regexp.MustCompile(`\b(v-?twin|parallel[- ]twin|boxer|flat[- ]twin|twin|triple|v-?4|inline[- ]four|in-line[- ]four)\b`)
+
+	cylinderWords = map[string]int{
+		"single": 1, "one": 1, "two": 2, "twin": 2, "three": 3, "four": 4, "five": 5, "six": 6, "eight": 8,
+		"v-twin": 2, "vtwin": 2, "parallel twin": 2, "parallel-twin": 2, "boxer": 2, "flat twin": 2, "flat-twin": 2,
+		"triple": 3, "v4": 4, "v-4": 4, "inline four": 4, "inline-four": 4, "in-line four": 4, "in-line-four": 4,
+	}
+)
+
+// parseBoreStroke reads "2.1x2.1 in OR 53.3x53.3 mm" in mm, preferring the
+// values given in mm.
+func parseBoreStroke(value string) (bore, stroke float64, ok bool) {
+	value = strings.ToLower(tagPattern.ReplaceAllString(value, " "))
+	for _, m := range boreStrokePattern.FindAllStringSubmatch(value, -1) {
+		b, err1 := strconv.ParseFloat(m[1], 64)
+		s, err2 := strconv.ParseFloat(m[2], 64)
+		if err1 != nil || err2 != nil || b <= 0 || s <= 0 {
+			continue
+		}
+		if m[3] == "mm" {
+			return b, s, true
+		}
+		if !ok {
+			bore, stroke, ok = b*25.4, s*25.4, true
+		}
+	}
+	return bore, stroke, ok
+}
+
+// ParseCylinders reads the cylinder count of an engine description such as
+// "in-line two cylinder" or "liquid-cooled V-twin".
+func ParseCylinders(engine string) int {
+	engine = strings.ToLower(strings.Replace(engine, "- ", "-", -1))
+	if m := cylinderPattern.FindStringSubmatch(engine); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			return n
+		}
+		return cylinderWords[m[1]]
+	}
+	if m := layoutPattern.FindStringSubmatch(engine); m != nil {
+		return cylinderWords[m[1]]
+	}
+	return 0
+}
+
+// parseConsumption returns litres per 100 km, from "4.5 L/100km" or US
+// "52 mpg".
+func parseConsumption(value string) (float64, bool) {
+	lower := strings.ToLower(tagPattern.ReplaceAllString(value, " "))
+	if strings.Contains(lower, "mpg") {
+		if mpg, ok := parseQuantity(lower, []unit{{"mpg", 1}}); ok {
+			return 235.215 / mpg, true
+		}
+	}
+	return parseQuantity(lower, []unit{{"l", 1}})
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Floor(value*scale+0.5) / scale
+}
+
+// Derive works the derived values of spec out of its normalized numbers. It
+// returns nil when nothing could be derived.
+func Derive(spec Spec) *Derived {
+	n := Normalize(spec)
+	d := &Derived{}
+	if n.Power > 0 && n.Weight > 0 {
+		d.PowerToWeight = round(n.Power/n.Weight, 3)
+	}
+	if torque, ok := parseTorque(SpecValue(spec, "torque")); ok && n.Weight > 0 {
+		d.TorqueToWeight = round(torque/n.Weight, 3)
+	}
+	if n.Power > 0 && n.Displacement > 0 {
+		d.SpecificOutput = round(n.Power/(n.Displacement/1000), 1)
+	}
+	if bore, stroke, ok := parseBoreStroke(SpecValue(spec, "bore x stroke", "bore")); ok {
+		d.Bore, d.Stroke = round(bore, 1), round(stroke, 1)
+		d.BoreStrokeRatio = round(bore/stroke, 2)
+		d.Cylinders = ParseCylinders(SpecValue(spec, "type", "engine type", "engine", "cylinders"))
+		if d.Cylinders > 0 {
+			d.EstimatedDisplacement = round(math.Pi/4*bore*bore*stroke*float64(d.Cylinders)/1000, 0)
+			if n.Displacement > 0 && math.Abs(d.EstimatedDisplacement-n.Displacement) > DisplacementTolerance*n.Displacement {
+				d.Flags = append(d.Flags, fmt.Sprintf("displacement %.0f cc differs from bore x stroke x %d cylinders = %.0f cc", n.Displacement, d.Cylinders, d.EstimatedDisplacement))
+			}
+		}
+	}
+	if capacity, ok := parseQuantity(SpecValue(spec, "fuel capacity", "fuel tank"), volumeUnits); ok {
+		d.FuelCapacity = round(capacity, 1)
+		if consumption, ok := parseConsumption(SpecValue(spec, "fuel consumption", "consumption", "fuel economy")); ok {
+			d.Consumption = round(consumption, 1)
+			d.Range = round(capacity/consumption*100, 0)
+		}
+	}
+	for _, rule := range outlierRules {
+		if rule.match(n, d) {
+			d.Flags = append(d.Flags, rule.reason)
+		}
+	}
+	if d.PowerToWeight == 0 && d.TorqueToWeight == 0 && d.SpecificOutput == 0 && d.Bore == 0 && d.FuelCapacity == 0 && len(d.Flags) == 0 {
+		return nil
+	}
+	return d
+}
+
+// DeriveFunc is the post-processing stage adding Derived to every spec.
+func DeriveFunc(p *Processor, input interface{}) {
+	spec, ok := input.(Spec)
+	if !ok {
+		p.Error <- fmt.Errorf("%v is not a valid Spec", input)
+		return
+	}
+	spec.Derived = Derive(spec)
+	if spec.Derived != nil {
+		for _, flag := range spec.Derived.Flags {
+			ProcessorLogger.Printf("Derive Processor: %s %s %s: %s", spec.Brand, spec.Moto, spec.Year, flag)
+		}
+	}
+	p.Output <- spec
+}
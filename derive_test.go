@@ -0,0 +1,124 @@
+package motospec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBoreStroke(t *testing.T) {
+	tests := []struct {
+		value        string
+		bore, stroke float64
+		ok           bool
+	}{
+		{"2.1x2.1 in OR 53.3x53.3 mm", 53.3, 53.3, true},
+		{"76 x 55 mm", 76, 55, true},
+		{"3 x 2 in", 76.2, 50.8, true},
+		{"3<br>x 2 in", 76.2, 50.8, true},
+		{"-", 0, 0, false},
+		{"0x55 mm", 0, 0, false},
+	}
+	for _, test := range tests {
+		bore, stroke, ok := parseBoreStroke(test.value)
+		if ok != test.ok || round(bore, 1) != test.bore || round(stroke, 1) != test.stroke {
+			t.Errorf("%q: got %g x %g %v, want %g x %g %v", test.value, bore, stroke, ok, test.bore, test.stroke, test.ok)
+		}
+	}
+}
+
+func TestParseCylinders(t *testing.T) {
+	tests := []struct {
+		engine string
+		want   int
+	}{
+		{"Single cylinder, four-stroke", 1},
+		{"in-line two cylinder", 2},
+		{"liquid-cooled V-twin", 2},
+		{"Boxer, 4-stroke", 2},
+		{"Inline- four, 16 valves", 4},
+		{"4-cylinder, DOHC", 4},
+		{"triple", 3},
+		{"electric motor", 0},
+	}
+	for _, test := range tests {
+		if got := ParseCylinders(test.engine); got != test.want {
+			t.Errorf("%q: got %d, want %d", test.engine, got, test.want)
+		}
+	}
+}
+
+func TestParseConsumption(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+		ok    bool
+	}{
+		{"4.5 L/100km", 4.5, true},
+		{"47 mpg", 5, true},
+		{"-", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := parseConsumption(test.value)
+		if ok != test.ok || round(got, 1) != test.want {
+			t.Errorf("%q: got %g %v, want %g %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestDerive(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs map[string]string
+		want  *Derived
+	}{
+		{"nothing to derive", map[string]string{"Color": "red"}, nil},
+		{"ratios", map[string]string{
+			"Displacement": "803 cc",
+			"Power":        "73 hp",
+			"Torque":       "67 Nm",
+			"Weight":       "180 kg",
+		}, &Derived{PowerToWeight: 0.406, TorqueToWeight: 0.372, SpecificOutput: 90.9}},
+		{"bore and stroke", map[string]string{
+			"Type":          "Single cylinder",
+			"Displacement":  "124.5 cc",
+			"Bore x Stroke": "54 x 54.5 mm",
+		}, &Derived{Bore: 54, Stroke: 54.5, BoreStrokeRatio: 0.99, Cylinders: 1, EstimatedDisplacement: 125}},
+		{"displacement off", map[string]string{
+			"Type":          "Single cylinder",
+			"Displacement":  "250 cc",
+			"Bore x Stroke": "54 x 54.5 mm",
+		}, &Derived{Bore: 54, Stroke: 54.5, BoreStrokeRatio: 0.99, Cylinders: 1, EstimatedDisplacement: 125,
+			Flags: []string{"displacement 250 cc differs from bore x stroke x 1 cylinders = 125 cc"}}},
+		{"range", map[string]string{
+			"Fuel Capacity":    "15 L",
+			"Fuel Consumption": "5 L/100km",
+		}, &Derived{FuelCapacity: 15, Consumption: 5, Range: 300}},
+		{"outliers", map[string]string{
+			"Displacement": "998 cc",
+			"Power":        "400 hp",
+			"Weight":       "200 kg",
+		}, &Derived{PowerToWeight: 2, SpecificOutput: 400.8, Flags: []string{"over 1.5 hp per kg", "over 350 hp per litre"}}},
+		{"outlier only", map[string]string{"Weight": "30 kg"}, &Derived{Flags: []string{"lighter than 40 kg"}}},
+	}
+	for _, test := range tests {
+		got := Derive(Spec{Specs: test.specs})
+		if (got == nil) != (test.want == nil) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+			continue
+		}
+		if got == nil {
+			continue
+		}
+		g, w := *got, *test.want
+		if strings.Join(g.Flags, "; ") != strings.Join(w.Flags, "; ") {
+			t.Errorf("%s: flags %q, want %q", test.name, g.Flags, w.Flags)
+		}
+		g.Flags, w.Flags = nil, nil
+		if g.PowerToWeight != w.PowerToWeight || g.TorqueToWeight != w.TorqueToWeight || g.SpecificOutput != w.SpecificOutput ||
+			g.Bore != w.Bore || g.Stroke != w.Stroke || g.BoreStrokeRatio != w.BoreStrokeRatio || g.Cylinders != w.Cylinders ||
+			g.EstimatedDisplacement != w.EstimatedDisplacement || g.FuelCapacity != w.FuelCapacity ||
+			g.Consumption != w.Consumption || g.Range != w.Range {
+			t.Errorf("%s: got %+v, want %+v", test.name, g, w)
+		}
+	}
+}
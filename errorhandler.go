@@ -1,16 +1,150 @@
 package motospec
 
 import (
+	"encoding/json"
+	"fmt"
 	"notbearclient"
 )
 
-func HandleError(errChan chan error) {
+type StageError struct {
+	Input interface{}
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s: %v", InputURL(e.Input), e.Err)
+}
+
+const (
+	FailureError  = "error"
+	FailureRobots = "robots"
+)
+
+type Failure struct {
+	Type   string          `json:"type"`
+	URL    string          `json:"url"`
+	Input  json.RawMessage `json:"input"`
+	Reason string          `json:"reason"`
+	Error  string          `json:"error"`
+}
+
+func NewFailure(e *StageError) (Failure, error) {
+	input, err := json.Marshal(e.Input)
+	if err != nil {
+		return Failure{}, err
+	}
+	reason := FailureError
+	if _, ok := e.Err.(*ErrDisallowed); ok {
+		reason = FailureRobots
+	}
+	return Failure{
+		Type:   fmt.Sprintf("%T", e.Input),
+		URL:    InputURL(e.Input),
+		Input:  input,
+		Reason: reason,
+		Error:  e.Err.Error(),
+	}, nil
+}
+
+// Seed restores the failed input as a seed of adapter, falling back to the
+// recorded url for inputs the adapter does not know.
+func (f Failure) Seed(adapter SiteAdapter) (Seed, error) {
+	for _, input := range adapter.Inputs() {
+		if fmt.Sprintf("%T", input) == f.Type {
+			return DecodeInput(adapter, f.Type, f.Input)
+		}
+	}
+	return adapter.ParseSeed(f.URL)
+}
+
+// Missed holds what a run attempted and could not crawl: the inputs that
+// failed and the keys of the specs it quarantined.
+type Missed struct {
+	Inputs []interface{}
+	Keys   map[string]bool
+}
+
+func (m *Missed) add(err error) {
+	switch e := err.(type) {
+	case *ErrQuarantined:
+		if m.Keys == nil {
+			m.Keys = make(map[string]bool)
+		}
+		m.Keys[e.Spec.Key()] = true
+	case *StageError:
+		m.Inputs = append(m.Inputs, e.Input)
+	}
+}
+
+// Has tells spec is missing from the run because it was quarantined, or
+// because its page or a listing leading to it failed.
+func (m *Missed) Has(spec Spec) bool {
+	if m.Keys[spec.Key()] {
+		return true
+	}
+	for _, input := range m.Inputs {
+		switch i := input.(type) {
+		case string:
+			// the brand listing, every variant hangs under it
+			return true
+		case BrandURL:
+			if i.Brand == spec.Brand {
+				return true
+			}
+		case ModelURL:
+			if i.Brand == spec.Brand && i.Model == spec.Model {
+				return true
+			}
+		default:
+			if InputURL(input) == spec.URL {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HandleError logs the errors of errChan until it is closed. The inputs
+// of stage errors are recorded in failures and quarantined specs in
+// quarantine, when they are not nil, and both are added to missed. Robots
+// skips are logged only. It returns the number of the other errors, and
+// the number of quarantined specs.
+func HandleError(errChan chan error, failures, quarantine Sink, missed *Missed) (failed, quarantined int) {
 	for err := range errChan {
-		switch e := err.(type) {
-		case *notbearclient.ErrTimeout, *notbearclient.ErrNetwork, *notbearclient.ErrOther:
-			ErrClientLogger.Println(e)
+		cause := err
+		if e, ok := err.(*StageError); ok {
+			cause = e.Err
+			if failures != nil {
+				failure, ferr := NewFailure(e)
+				if ferr == nil {
+					ferr = failures.Write(failure)
+				}
+				if ferr != nil {
+					ErrProcessorLogger.Println(ferr)
+				}
+			}
+		}
+		if _, skipped := cause.(*ErrDisallowed); !skipped && missed != nil {
+			missed.add(err)
+		}
+		switch e := cause.(type) {
+		case *ErrQuarantined:
+			quarantined++
+			ProcessorLogger.Printf("Validate Processor: %v", err)
+			if quarantine != nil {
+				if qerr := quarantine.Write(Quarantined{Reasons: e.Reasons, Spec: e.Spec}); qerr != nil {
+					ErrProcessorLogger.Println(qerr)
+				}
+			}
+		case *ErrDisallowed:
+			ProcessorLogger.Printf("Skipped: %v", err)
+		case *notbearclient.ErrTimeout, *notbearclient.ErrNetwork, *notbearclient.ErrOther, *ErrFetch, *ErrStatus:
+			failed++
+			ErrClientLogger.Println(err)
 		default:
-			ErrProcessorLogger.Println(e)
+			failed++
+			ErrProcessorLogger.Println(err)
 		}
 	}
+	return failed, quarantined
 }
@@ -0,0 +1,68 @@
+package motospec
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHandleErrorSinks(t *testing.T) {
+	spec := Spec{Brand: "Adler", Moto: "MB 250", URL: "https://example.com/adler-mb-250"}
+	tests := []struct {
+		name                  string
+		err                   error
+		failures, quarantined int
+		failed                int
+		missed                bool
+	}{
+		{"stage error", &StageError{Input: MotoURL{URL: spec.URL}, Err: errors.New("timeout")}, 1, 0, 1, true},
+		{"disallowed", &StageError{Input: MotoURL{URL: spec.URL}, Err: &ErrDisallowed{URL: spec.URL}}, 1, 0, 0, false},
+		{"quarantined", &ErrQuarantined{Spec: spec, Reasons: []string{"model: required"}}, 0, 1, 0, true},
+		{"other", errors.New("not a spec"), 0, 0, 1, false},
+	}
+	for _, test := range tests {
+		failures, quarantine := &memorySink{}, &memorySink{}
+		errChan := make(chan error, 1)
+		errChan <- test.err
+		close(errChan)
+		missed := &Missed{}
+		failed, quarantined := HandleError(errChan, failures, quarantine, missed)
+		if len(failures.records) != test.failures || len(quarantine.records) != test.quarantined {
+			t.Errorf("%s: %d failures and %d quarantined, want %d and %d", test.name,
+				len(failures.records), len(quarantine.records), test.failures, test.quarantined)
+		}
+		if failed != test.failed || quarantined != test.quarantined {
+			t.Errorf("%s: counted %d failed and %d quarantined, want %d and %d", test.name,
+				failed, quarantined, test.failed, test.quarantined)
+		}
+		if missed.Has(spec) != test.missed {
+			t.Errorf("%s: missed %v, want %v", test.name, !test.missed, test.missed)
+		}
+	}
+	errChan := make(chan error, 1)
+	errChan <- &ErrQuarantined{Spec: spec}
+	close(errChan)
+	HandleError(errChan, nil, nil, nil)
+}
+
+func TestMissedHas(t *testing.T) {
+	spec := Spec{Brand: "Ducati", Model: "Monster", Moto: "821", URL: "https://example.com/ducati-monster-821.html"}
+	tests := []struct {
+		name  string
+		input interface{}
+		want  bool
+	}{
+		{"brand listing", "https://example.com/brands", true},
+		{"brand", BrandURL{Brand: "Ducati"}, true},
+		{"other brand", BrandURL{Brand: "Honda"}, false},
+		{"model", ModelURL{Brand: "Ducati", Model: "Monster"}, true},
+		{"other model", ModelURL{Brand: "Ducati", Model: "Panigale"}, false},
+		{"variant", MotoURL{URL: spec.URL}, true},
+		{"other variant", MotoURL{URL: "https://example.com/ducati-monster-1200.html"}, false},
+	}
+	for _, test := range tests {
+		missed := &Missed{Inputs: []interface{}{test.input}}
+		if got := missed.Has(spec); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package motospec
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	EventModelAdded   = "model.added"
+	EventModelRemoved = "model.removed"
+	EventSpecChanged  = "spec.changed"
+)
+
+var EventTypes = []string{EventModelAdded, EventModelRemoved, EventSpecChanged}
+
+// Event tells a variant appeared, disappeared or changed in a snapshot.
+// Spec is the new spec, or the last one for model.removed.
+type Event struct {
+	ID       string        `json:"id"`
+	Type     string        `json:"type"`
+	Time     time.Time     `json:"time"`
+	Snapshot string        `json:"snapshot"`
+	Variant  string        `json:"variant"`
+	Brand    string        `json:"brand"`
+	Spec     *Spec         `json:"spec"`
+	Changes  []FieldChange `json:"changes,omitempty"`
+}
+
+// SnapshotEvents turns the changes of snapshot into events. An event id is
+// the same every time the events of a snapshot are built, so receivers can
+// drop the ones delivered twice.
+func SnapshotEvents(snapshot Snapshot, changes []SpecChange) []Event {
+	events := make([]Event, 0, len(changes))
+	for _, change := range changes {
+		event := Event{Time: snapshot.Time, Snapshot: snapshot.ID, Variant: keyID(change.Key), Changes: change.Changes}
+		switch change.Kind {
+		case SpecAdded:
+			event.Type, event.Spec = EventModelAdded, change.New
+		case SpecRemoved:
+			event.Type, event.Spec = EventModelRemoved, change.Old
+		case SpecChanged:
+			event.Type, event.Spec = EventSpecChanged, change.New
+		default:
+			continue
+		}
+		event.Brand = event.Spec.Brand
+		sum := sha1.Sum([]byte(snapshot.ID + "|" + event.Type + "|" + change.Key))
+		event.ID = hex.EncodeToString(sum[:8])
+		events = append(events, event)
+	}
+	return events
+}
+
+// Changes returns what the snapshot id changed against its parent, in the
+// layout of DiffSpecs. Changes to fields diff does not compare are left out.
+func (s *SnapshotStore) Changes(id string) ([]SpecChange, error) {
+	_, index, err := s.Snapshot(id)
+	if err != nil {
+		return nil, err
+	}
+	previous := make(map[string]Spec)
+	if index > 0 {
+		if previous, err = s.replay(index-1, nil); err != nil {
+			return nil, err
+		}
+	}
+	records, err := s.records(s.snapshots[index].ID)
+	if err != nil {
+		return nil, err
+	}
+	changes := []SpecChange{}
+	for _, record := range records {
+		old, ok := previous[record.Key]
+		o := &old
+		if !ok {
+			o = nil
+		}
+		switch {
+		case record.Removed && o != nil:
+			changes = append(changes, SpecChange{Kind: SpecRemoved, Key: record.Key, Old: o})
+		case record.Spec == nil:
+		case o == nil:
+			changes = append(changes, SpecChange{Kind: SpecAdded, Key: record.Key, New: record.Spec})
+		default:
+			if fields := diffFields(*o, *record.Spec); len(fields) > 0 {
+				changes = append(changes, SpecChange{Kind: SpecChanged, Key: record.Key, Old: o, New: record.Spec, Changes: fields})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+// WebhookSink posts every event as JSON to URL. With a Secret the body is
+// signed in the X-Motospec-Signature header as sha256=<hex HMAC-SHA256>.
+// Network errors, 429 and 5xx answers are retried Retries times, waiting
+// Backoff, doubled every time, or what Retry-After asks.
+type WebhookSink struct {
+	URL     string
+	Secret  string
+	Retries int
+	Backoff time.Duration
+	Client  *http.Client
+}
+
+func NewWebhookSink(u, secret string, retries, timeout int) *WebhookSink {
+	return &WebhookSink{
+		URL:     u,
+		Secret:  secret,
+		Retries: retries,
+		Backoff: time.Second,
+		Client:  &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}
+}
+
+func (s *WebhookSink) Write(record interface{}) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	wait := s.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(body, record)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.Retries {
+			return err
+		}
+		if e, ok := err.(*ErrStatus); ok {
+			if after, ok := RetryAfter(e.Header, time.Now()); ok && after > wait {
+				wait = after
+			}
+		}
+		ErrProcessorLogger.Printf("Webhook: %v, retrying in %s", err, wait)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// post returns whether a failed delivery is worth retrying.
+func (s *WebhookSink) post(body []byte, record interface{}) (bool, error) {
+	req, err := http.NewRequest("POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "motospec")
+	if event, ok := record.(Event); ok {
+		req.Header.Set("X-Motospec-Event", event.Type)
+		req.Header.Set("X-Motospec-Delivery", event.ID)
+	}
+	if s.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Motospec-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return true, &ErrFetch{URL: s.URL, Err: err}
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+		&ErrStatus{URL: s.URL, StatusCode: resp.StatusCode, Header: resp.Header}
+}
+
+func (s *WebhookSink) Close() error {
+	return nil
+}
+
+// EventSinkConfig is a webhook, by url, or an NDJSON event log, by path.
+// The secret may name environment variables as $NAME.
+type EventSinkConfig struct {
+	Kind    string `yaml:"kind"`
+	URL     string `yaml:"url"`
+	Secret  string `yaml:"secret"`
+	Retries int    `yaml:"retries"`
+	Timeout int    `yaml:"timeout"`
+	Path    string `yaml:"path"`
+}
+
+// EventsConfig sends the events of every crawl snapshot of the brands
+// matching brands and not exclude_brands, and of types, to sinks.
+type EventsConfig struct {
+	Brands        []string          `yaml:"brands"`
+	ExcludeBrands []string          `yaml:"exclude_brands"`
+	Types         []string          `yaml:"types"`
+	Sinks         []EventSinkConfig `yaml:"sinks"`
+}
+
+func (c EventsConfig) Validate() []string {
+	errs := []string{}
+	if _, err := CompilePatterns(c.Brands); err != nil {
+		errs = append(errs, "brands: "+err.Error())
+	}
+	if _, err := CompilePatterns(c.ExcludeBrands); err != nil {
+		errs = append(errs, "exclude_brands: "+err.Error())
+	}
+	for i, t := range c.Types {
+		known := false
+		for _, e := range EventTypes {
+			known = known || e == t
+		}
+		if !known {
+			errs = append(errs, fmt.Sprintf("types[%d]: unknown event %q", i, t))
+		}
+	}
+	for i, sink := range c.Sinks {
+		prefix := fmt.Sprintf("sinks[%d]", i)
+		switch sink.Kind {
+		case "webhook":
+			if u, err := url.Parse(sink.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, prefix+".url: an http or https url is required")
+			}
+			if sink.Retries < 0 || sink.Timeout < 0 {
+				errs = append(errs, prefix+": retries and timeout must not be negative")
+			}
+		case "file":
+			if sink.Path == "" {
+				errs = append(errs, prefix+".path: required")
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("%s.kind: unknown kind %q, want webhook or file", prefix, sink.Kind))
+		}
+	}
+	return errs
+}
+
+// EventEmitter sends the events its filter lets through to every sink.
+type EventEmitter struct {
+	Sinks  []Sink
+	Filter *Filter
+	Types  map[string]bool
+}
+
+// Open returns nil when no sink is configured.
+func (c EventsConfig) Open() (*EventEmitter, error) {
+	if len(c.Sinks) == 0 {
+		return nil, nil
+	}
+	emitter := &EventEmitter{Filter: &Filter{}, Types: make(map[string]bool)}
+	var err error
+	if emitter.Filter.IncludeBrands, err = CompilePatterns(c.Brands); err != nil {
+		return nil, err
+	}
+	if emitter.Filter.ExcludeBrands, err = CompilePatterns(c.ExcludeBrands); err != nil {
+		return nil, err
+	}
+	for _, t := range c.Types {
+		emitter.Types[t] = true
+	}
+	for _, sc := range c.Sinks {
+		switch sc.Kind {
+		case "webhook":
+			timeout := sc.Timeout
+			if timeout == 0 {
+				timeout = 10
+			}
+			emitter.Sinks = append(emitter.Sinks, NewWebhookSink(sc.URL, os.ExpandEnv(sc.Secret), sc.Retries, timeout))
+		case "file":
+			sink, err := NewJSONSink(sc.Path)
+			if err != nil {
+				emitter.Close()
+				return nil, err
+			}
+			emitter.Sinks = append(emitter.Sinks, sink)
+		default:
+			emitter.Close()
+			return nil, fmt.Errorf("unknown event sink %q", sc.Kind)
+		}
+	}
+	return emitter, nil
+}
+
+// Emit sends events and returns how many passed the filter. A failing
+// delivery does not stop the others; the first error is returned.
+func (e *EventEmitter) Emit(events []Event) (int, error) {
+	var first error
+	sent := 0
+	for _, event := range events {
+		if len(e.Types) > 0 && !e.Types[event.Type] || !e.Filter.AllowBrand(event.Brand) {
+			continue
+		}
+		sent++
+		for _, sink := range e.Sinks {
+			if err := sink.Write(event); err != nil {
+				ErrProcessorLogger.Printf("Event %s %s: %v", event.Type, event.Variant, err)
+				if first == nil {
+					first = err
+				}
+			}
+		}
+	}
+	return sent, first
+}
+
+func (e *EventEmitter) Close() error {
+	var first error
+	for _, sink := range e.Sinks {
+		if err := sink.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
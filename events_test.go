@@ -0,0 +1,205 @@
+package motospec
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func countEvents(events []Event) map[string]int {
+	counts := make(map[string]int)
+	for _, event := range events {
+		counts[event.Type]++
+	}
+	return counts
+}
+
+// crawlVariants runs a pipeline turning the variant urls into specs, which
+// fails on the urls of failing.
+func crawlVariants(urls []string, failing map[string]bool) ([]Spec, *Missed) {
+	pipeline := NewPipeline(context.Background(), []ProcessFunc{func(p *Processor, input interface{}) {
+		moto := input.(MotoURL)
+		if failing[moto.URL] {
+			p.Fail(moto, errors.New("timeout"))
+			return
+		}
+		p.Output <- testSpec(strings.TrimPrefix(moto.URL, "https://example.com/"), "100 HP")
+	}}, 0)
+	go func() {
+		for _, u := range urls {
+			pipeline.Input <- MotoURL{URL: u}
+		}
+		close(pipeline.Input)
+	}()
+	go pipeline.Run()
+	specs := []Spec{}
+	for output := range pipeline.Output {
+		specs = append(specs, output.(Spec))
+	}
+	<-pipeline.Done
+	return specs, &pipeline.Missed
+}
+
+func TestCrawlEvents(t *testing.T) {
+	store, cleanup := openTestStore(t)
+	defer cleanup()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	a, b, c := "https://example.com/a", "https://example.com/b", "https://example.com/c"
+	runs := []struct {
+		name           string
+		urls           []string
+		failing        map[string]bool
+		partial        bool
+		added, removed int
+	}{
+		{"first crawl", []string{a, b, c}, nil, false, 3, 0},
+		{"filtered crawl", []string{a}, nil, true, 0, 0},
+		// b failed to fetch while c is gone from the site
+		{"crawl with a failure", []string{a, b}, map[string]bool{b: true}, false, 0, 1},
+		{"b is back", []string{a, b}, nil, false, 0, 0},
+	}
+	for i, run := range runs {
+		specs, missed := crawlVariants(run.urls, run.failing)
+		specs, err := store.CarryOver(specs, missed.Has)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshot, err := store.Commit(specs, start.Add(time.Duration(i)*time.Hour), run.partial)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes, err := store.Changes(snapshot.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := SnapshotEvents(snapshot, changes)
+		counts := countEvents(events)
+		if counts[EventModelAdded] != run.added || counts[EventModelRemoved] != run.removed || counts[EventSpecChanged] != 0 {
+			t.Errorf("%s: got %v, want %d added and %d removed", run.name, counts, run.added, run.removed)
+		}
+		for _, event := range events {
+			if event.Type == EventModelRemoved && event.Spec.URL != c {
+				t.Errorf("%s: removed %s", run.name, event.Spec.URL)
+			}
+		}
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		fail     bool
+		attempts int
+	}{
+		{"delivered", []int{200}, 2, false, 1},
+		{"retried 503", []int{503, 200}, 2, false, 2},
+		{"retried 429", []int{429, 204}, 1, false, 2},
+		{"not retried 400", []int{400, 200}, 2, true, 1},
+		{"retries exhausted", []int{500, 502, 503, 200}, 2, true, 3},
+	}
+	event := Event{ID: "e1", Type: EventModelAdded, Brand: "Ducati", Variant: "ducati-monster"}
+	for _, test := range tests {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mac := hmac.New(sha256.New, []byte("secret"))
+			mac.Write(body)
+			if got, want := r.Header.Get("X-Motospec-Signature"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+				t.Errorf("%s: signature %q, want %q", test.name, got, want)
+			}
+			if r.Header.Get("X-Motospec-Event") != event.Type || r.Header.Get("X-Motospec-Delivery") != event.ID {
+				t.Errorf("%s: event headers %v", test.name, r.Header)
+			}
+			w.WriteHeader(test.statuses[attempts])
+			attempts++
+		}))
+		sink := NewWebhookSink(server.URL, "secret", test.retries, 5)
+		sink.Backoff = time.Millisecond
+		err := sink.Write(event)
+		server.Close()
+		if (err != nil) != test.fail || attempts != test.attempts {
+			t.Errorf("%s: error %v after %d attempts, want %d", test.name, err, attempts, test.attempts)
+		}
+	}
+}
+
+func TestEventsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config EventsConfig
+		want   string
+	}{
+		{"valid", EventsConfig{Brands: []string{"^ducati$"}, Types: []string{EventSpecChanged},
+			Sinks: []EventSinkConfig{{Kind: "webhook", URL: "https://example.com/hook"}, {Kind: "file", Path: "events.ndjson"}}}, ""},
+		{"brands", EventsConfig{Brands: []string{"("}}, "brands: "},
+		{"types", EventsConfig{Types: []string{"model.renamed"}}, `types[0]: unknown event "model.renamed"`},
+		{"url", EventsConfig{Sinks: []EventSinkConfig{{Kind: "webhook", URL: "ftp://example.com"}}}, "sinks[0].url: "},
+		{"retries", EventsConfig{Sinks: []EventSinkConfig{{Kind: "webhook", URL: "http://example.com", Retries: -1}}}, "sinks[0]: retries"},
+		{"path", EventsConfig{Sinks: []EventSinkConfig{{Kind: "file"}}}, "sinks[0].path: required"},
+		{"kind", EventsConfig{Sinks: []EventSinkConfig{{Kind: "email"}}}, `sinks[0].kind: unknown kind "email"`},
+	}
+	for _, test := range tests {
+		errs := test.config.Validate()
+		if test.want == "" {
+			if len(errs) > 0 {
+				t.Errorf("%s: %v", test.name, errs)
+			}
+			continue
+		}
+		if len(errs) != 1 || !strings.HasPrefix(errs[0], test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, errs, test.want)
+		}
+	}
+}
+
+func TestEventEmitterEmit(t *testing.T) {
+	events := []Event{
+		{Type: EventModelAdded, Brand: "Ducati", Variant: "a"},
+		{Type: EventSpecChanged, Brand: "Ducati", Variant: "b"},
+		{Type: EventModelRemoved, Brand: "Honda", Variant: "c"},
+		{Type: EventSpecChanged, Brand: "Honda", Variant: "d"},
+	}
+	tests := []struct {
+		name   string
+		config EventsConfig
+		want   string
+	}{
+		{"all", EventsConfig{}, "a b c d"},
+		{"types", EventsConfig{Types: []string{EventSpecChanged}}, "b d"},
+		{"brands", EventsConfig{Brands: []string{"^ducati$"}}, "a b"},
+		{"exclude brands", EventsConfig{ExcludeBrands: []string{"honda"}, Types: []string{EventModelAdded, EventModelRemoved}}, "a"},
+	}
+	for _, test := range tests {
+		test.config.Sinks = []EventSinkConfig{{Kind: "webhook", URL: "http://example.com"}}
+		emitter, err := test.config.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sink := &memorySink{}
+		emitter.Sinks = []Sink{sink}
+		sent, err := emitter.Emit(events)
+		if err != nil {
+			t.Fatal(err)
+		}
+		variants := []string{}
+		for _, record := range sink.records {
+			variants = append(variants, record.(Event).Variant)
+		}
+		if got := strings.Join(variants, " "); got != test.want || sent != len(variants) {
+			t.Errorf("%s: sent %d %q, want %q", test.name, sent, got, test.want)
+		}
+	}
+	if emitter, err := (EventsConfig{}).Open(); emitter != nil || err != nil {
+		t.Errorf("no sinks: got %v, %v", emitter, err)
+	}
+}
@@ -0,0 +1,119 @@
+package motospec
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Filter struct {
+	IncludeBrands []*regexp.Regexp
+	ExcludeBrands []*regexp.Regexp
+	IncludeModels []*regexp.Regexp
+	ExcludeModels []*regexp.Regexp
+	YearFrom      int
+	YearTo        int
+}
+
+func CompilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	list := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		list = append(list, re)
+	}
+	return list, nil
+}
+
+func matchAny(list []*regexp.Regexp, s string) bool {
+	for _, re := range list {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func allow(include, exclude []*regexp.Regexp, s string) bool {
+	if len(include) > 0 && !matchAny(include, s) {
+		return false
+	}
+	return !matchAny(exclude, s)
+}
+
+func (f *Filter) AllowBrand(brand string) bool {
+	if f == nil {
+		return true
+	}
+	return allow(f.IncludeBrands, f.ExcludeBrands, brand)
+}
+
+func (f *Filter) AllowModel(model string) bool {
+	if f == nil {
+		return true
+	}
+	return allow(f.IncludeModels, f.ExcludeModels, model)
+}
+
+func (f *Filter) AllowYears(years string) bool {
+	if f == nil || (f.YearFrom == 0 && f.YearTo == 0) {
+		return true
+	}
+	from, to, ok := ParseYears(years)
+	if !ok {
+		return true
+	}
+	if f.YearFrom != 0 && to < f.YearFrom {
+		return false
+	}
+	if f.YearTo != 0 && from > f.YearTo {
+		return false
+	}
+	return true
+}
+
+// ParseYears understands "1957 - 1960", "2012 - Present" and a single "1957".
+// A range ending in Present is open, so its upper bound is a large year.
+func ParseYears(years string) (from, to int, ok bool) {
+	parts := strings.Split(years, "-")
+	from, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, false
+	}
+	if len(parts) == 1 {
+		return from, from, true
+	}
+	end := strings.TrimSpace(parts[len(parts)-1])
+	if strings.EqualFold(end, "present") {
+		return from, 9999, true
+	}
+	to, err = strconv.Atoi(end)
+	if err != nil {
+		return from, from, true
+	}
+	return from, to, true
+}
+
+func ParseYearRange(s string) (from, to int, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	parts := strings.SplitN(s, "-", 2)
+	if parts[0] != "" {
+		if from, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+			return 0, 0, fmt.Errorf("invalid year range %q", s)
+		}
+	}
+	if len(parts) == 1 {
+		return from, from, nil
+	}
+	if parts[1] != "" {
+		if to, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return 0, 0, fmt.Errorf("invalid year range %q", s)
+		}
+	}
+	return from, to, nil
+}
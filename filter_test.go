@@ -0,0 +1,93 @@
+package motospec
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestParseYears(t *testing.T) {
+	tests := []struct {
+		years    string
+		from, to int
+		ok       bool
+	}{
+		{"1957", 1957, 1957, true},
+		{"1957 - 1960", 1957, 1960, true},
+		{"2012 - Present", 2012, 9999, true},
+		{"2012 - ?", 2012, 2012, true},
+		{"", 0, 0, false},
+		{"sixties", 0, 0, false},
+	}
+	for _, test := range tests {
+		from, to, ok := ParseYears(test.years)
+		if from != test.from || to != test.to || ok != test.ok {
+			t.Errorf("%q: got %d %d %v, want %d %d %v", test.years, from, to, ok, test.from, test.to, test.ok)
+		}
+	}
+}
+
+func TestParseYearRange(t *testing.T) {
+	tests := []struct {
+		s        string
+		from, to int
+		ok       bool
+	}{
+		{"", 0, 0, true},
+		{"2010", 2010, 2010, true},
+		{"2010-2015", 2010, 2015, true},
+		{"2010-", 2010, 0, true},
+		{"-2015", 0, 2015, true},
+		{"2010-now", 0, 0, false},
+		{"late", 0, 0, false},
+	}
+	for _, test := range tests {
+		from, to, err := ParseYearRange(test.s)
+		if from != test.from || to != test.to || (err == nil) != test.ok {
+			t.Errorf("%q: got %d %d %v, want %d %d %v", test.s, from, to, err, test.from, test.to, test.ok)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	patterns := func(list ...string) []*regexp.Regexp {
+		compiled, err := CompilePatterns(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return compiled
+	}
+	filter := &Filter{
+		IncludeBrands: patterns("^ducati$", "^bmw"),
+		ExcludeModels: patterns("scrambler"),
+		YearFrom:      2010,
+		YearTo:        2015,
+	}
+	tests := []struct {
+		name   string
+		filter *Filter
+		allow  func(*Filter, string) bool
+		value  string
+		want   bool
+	}{
+		{"included brand", filter, (*Filter).AllowBrand, "Ducati", true},
+		{"brand prefix", filter, (*Filter).AllowBrand, "BMW Motorrad", true},
+		{"other brand", filter, (*Filter).AllowBrand, "Ducati Energia", false},
+		{"any model", filter, (*Filter).AllowModel, "Ducati Monster", true},
+		{"excluded model", filter, (*Filter).AllowModel, "Ducati Scrambler Icon", false},
+		{"overlapping years", filter, (*Filter).AllowYears, "2008 - 2011", true},
+		{"open range", filter, (*Filter).AllowYears, "2014 - Present", true},
+		{"earlier years", filter, (*Filter).AllowYears, "2001 - 2009", false},
+		{"later year", filter, (*Filter).AllowYears, "2016", false},
+		{"unknown years", filter, (*Filter).AllowYears, "-", true},
+		{"no filter", nil, (*Filter).AllowBrand, "Honda", true},
+		{"no filter years", nil, (*Filter).AllowYears, "1957", true},
+	}
+	for _, test := range tests {
+		if got := test.allow(test.filter, test.value); got != test.want {
+			t.Errorf("%s: %q got %v, want %v", test.name, test.value, got, test.want)
+		}
+	}
+	if _, err := CompilePatterns([]string{"ducati", "("}); err == nil {
+		t.Error("compiled an invalid pattern")
+	}
+}
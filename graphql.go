@@ -0,0 +1,508 @@
+package motospec
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+type catalogKey struct{}
+
+// gqlCatalog returns the catalog a query runs on, fixed when it starts so a
+// reload does not change the data halfway through.
+func gqlCatalog(p graphql.ResolveParams) *Catalog {
+	return p.Context.Value(catalogKey{}).(*Catalog)
+}
+
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(raw), "offset:") {
+		if offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), "offset:")); err == nil && offset >= 0 {
+			return offset, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid cursor %q", cursor)
+}
+
+type gqlEdge struct {
+	Cursor string
+	Node   interface{}
+}
+
+type gqlPageInfo struct {
+	HasNextPage bool
+	EndCursor   string
+}
+
+type gqlConnection struct {
+	Edges      []gqlEdge
+	PageInfo   gqlPageInfo
+	TotalCount int
+}
+
+// connect pages nodes after the cursor in the after argument, first at a
+// time.
+func connect(p graphql.ResolveParams, nodes []interface{}) (interface{}, error) {
+	start := 0
+	if after, ok := p.Args["after"].(string); ok && after != "" {
+		offset, err := decodeCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		start = offset + 1
+	}
+	first := DefaultPerPage
+	if n, ok := p.Args["first"].(int); ok {
+		if n < 0 || n > MaxPerPage {
+			return nil, fmt.Errorf("first must be between 0 and %d", MaxPerPage)
+		}
+		first = n
+	}
+	if start > len(nodes) {
+		start = len(nodes)
+	}
+	end := start + first
+	if end > len(nodes) {
+		end = len(nodes)
+	}
+	c := gqlConnection{Edges: []gqlEdge{}, TotalCount: len(nodes)}
+	for i := start; i < end; i++ {
+		c.Edges = append(c.Edges, gqlEdge{Cursor: encodeCursor(i), Node: nodes[i]})
+	}
+	c.PageInfo.HasNextPage = end < len(nodes)
+	if end > start {
+		c.PageInfo.EndCursor = encodeCursor(end - 1)
+	}
+	return c, nil
+}
+
+var gqlPageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"endCursor":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+func connectionType(node *graphql.Object) *graphql.Object {
+	edge := graphql.NewObject(graphql.ObjectConfig{
+		Name: node.Name() + "Edge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: graphql.NewNonNull(node)},
+		},
+	})
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: node.Name() + "Connection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edge)))},
+			"nodes": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(node))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c := p.Source.(gqlConnection)
+					nodes := make([]interface{}, len(c.Edges))
+					for i, edge := range c.Edges {
+						nodes[i] = edge.Node
+					}
+					return nodes, nil
+				},
+			},
+			"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(gqlPageInfoType)},
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+}
+
+var gqlPageArgs = graphql.FieldConfigArgument{
+	"first": &graphql.ArgumentConfig{Type: graphql.Int, Description: fmt.Sprintf("at most %d, %d by default", MaxPerPage, DefaultPerPage)},
+	"after": &graphql.ArgumentConfig{Type: graphql.String, Description: "the cursor of the last edge of the previous page"},
+}
+
+var gqlRangeType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "Range",
+	Description: "Bounds of a number; a missing bound is open.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"min": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"max": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+	},
+})
+
+var gqlSpecArgs = graphql.FieldConfigArgument{
+	"brand":        &graphql.ArgumentConfig{Type: graphql.String},
+	"model":        &graphql.ArgumentConfig{Type: graphql.String},
+	"source":       &graphql.ArgumentConfig{Type: graphql.String},
+	"yearFrom":     &graphql.ArgumentConfig{Type: graphql.Int},
+	"yearTo":       &graphql.ArgumentConfig{Type: graphql.Int},
+	"displacement": &graphql.ArgumentConfig{Type: gqlRangeType, Description: "in cc"},
+	"power":        &graphql.ArgumentConfig{Type: gqlRangeType, Description: "in hp"},
+	"weight":       &graphql.ArgumentConfig{Type: gqlRangeType, Description: "in kg"},
+}
+
+func withArgs(sets ...graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args := graphql.FieldConfigArgument{}
+	for _, set := range sets {
+		for name, arg := range set {
+			args[name] = arg
+		}
+	}
+	return args
+}
+
+func gqlRange(arg interface{}) Range {
+	var r Range
+	if values, ok := arg.(map[string]interface{}); ok {
+		r.Min, _ = values["min"].(float64)
+		r.Max, _ = values["max"].(float64)
+	}
+	return r
+}
+
+func gqlSpecQuery(args map[string]interface{}) SpecQuery {
+	q := SpecQuery{
+		Displacement: gqlRange(args["displacement"]),
+		Power:        gqlRange(args["power"]),
+		Weight:       gqlRange(args["weight"]),
+	}
+	q.Brand, _ = args["brand"].(string)
+	q.Model, _ = args["model"].(string)
+	q.Source, _ = args["source"].(string)
+	q.YearFrom, _ = args["yearFrom"].(int)
+	q.YearTo, _ = args["yearTo"].(int)
+	return q
+}
+
+// variantNodes keeps the specs among indexes matching the filter arguments.
+func variantNodes(p graphql.ResolveParams, indexes []int) []interface{} {
+	c, q := gqlCatalog(p), gqlSpecQuery(p.Args)
+	nodes := []interface{}{}
+	for _, index := range indexes {
+		if q.Match(c.Specs[index], c.Norms[index]) {
+			nodes = append(nodes, c.Variant(index))
+		}
+	}
+	return nodes
+}
+
+var gqlNormalizedType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Normalized",
+	Description: "The fields of a spec that compare across sources.",
+	Fields: graphql.Fields{
+		"brand":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"model":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"yearFrom":     &graphql.Field{Type: graphql.Int},
+		"yearTo":       &graphql.Field{Type: graphql.Int},
+		"displacement": &graphql.Field{Type: graphql.Float, Description: "in cc"},
+		"power":        &graphql.Field{Type: graphql.Float, Description: "in hp"},
+		"weight":       &graphql.Field{Type: graphql.Float, Description: "in kg"},
+	},
+})
+
+var gqlSpecValueType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "SpecValue",
+	Fields: graphql.Fields{
+		"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+var gqlImageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Image",
+	Fields: graphql.Fields{
+		"url":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"hash": &graphql.Field{Type: graphql.String},
+		"path": &graphql.Field{Type: graphql.String},
+	},
+})
+
+var gqlLinkType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Link",
+	Fields: graphql.Fields{
+		"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"url":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+type gqlSpecValue struct {
+	Name  string
+	Value string
+}
+
+func specField(get func(SpecRecord) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(SpecRecord)), nil
+	}
+}
+
+var gqlSpecType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Spec",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"normalized":  &graphql.Field{Type: graphql.NewNonNull(gqlNormalizedType)},
+		"source":      &graphql.Field{Type: graphql.String, Resolve: specField(func(s SpecRecord) interface{} { return s.Source })},
+		"brand":       &graphql.Field{Type: graphql.String, Resolve: specField(func(s SpecRecord) interface{} { return s.Brand })},
+		"model":       &graphql.Field{Type: graphql.String, Resolve: specField(func(s SpecRecord) interface{} { return s.Model })},
+		"type":        &graphql.Field{Type: graphql.String, Resolve: specField(func(s SpecRecord) interface{} { return s.Moto })},
+		"year":        &graphql.Field{Type: graphql.String, Resolve: specField(func(s SpecRecord) interface{} { return s.Year })},
+		"url":         &graphql.Field{Type: graphql.String, Resolve: specField(func(s SpecRecord) interface{} { return s.URL })},
+		"description": &graphql.Field{Type: graphql.String, Resolve: specField(func(s SpecRecord) interface{} { return s.Description })},
+		"segment":     &graphql.Field{Type: graphql.String, Resolve: specField(func(s SpecRecord) interface{} { return s.Segment })},
+		"bodyStyle":   &graphql.Field{Type: graphql.String, Resolve: specField(func(s SpecRecord) interface{} { return s.BodyStyle })},
+		"images":      &graphql.Field{Type: graphql.NewList(gqlImageType), Resolve: specField(func(s SpecRecord) interface{} { return s.Images })},
+		"related":     &graphql.Field{Type: graphql.NewList(gqlLinkType), Resolve: specField(func(s SpecRecord) interface{} { return s.Related })},
+		"value": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The spec named like name, or else the first one whose name contains it.",
+			Args:        graphql.FieldConfigArgument{"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				name, _ := p.Args["name"].(string)
+				if value := SpecValue(p.Source.(SpecRecord).Spec, strings.ToLower(name)); value != "" {
+					return value, nil
+				}
+				return nil, nil
+			},
+		},
+		"values": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(gqlSpecValueType))),
+			Description: "The raw specs by name, only the given names when names is set.",
+			Args:        graphql.FieldConfigArgument{"names": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				spec := p.Source.(SpecRecord)
+				wanted := make(map[string]bool)
+				if names, ok := p.Args["names"].([]interface{}); ok {
+					for _, name := range names {
+						wanted[strings.ToLower(fmt.Sprint(name))] = true
+					}
+				}
+				values := []gqlSpecValue{}
+				for key, value := range spec.Specs {
+					name := strings.Trim(key, ": ")
+					if len(wanted) == 0 || wanted[strings.ToLower(name)] {
+						values = append(values, gqlSpecValue{Name: name, Value: value})
+					}
+				}
+				sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+				return values, nil
+			},
+		},
+	},
+})
+
+func specRecord(c *Catalog, index int) SpecRecord {
+	return SpecRecord{ID: c.IDs[index], Normalized: c.Norms[index], Spec: c.Specs[index]}
+}
+
+var gqlVariantType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Variant",
+	Fields: graphql.Fields{
+		"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"source":     &graphql.Field{Type: graphql.String},
+		"brand":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"model":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"type":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"year":       &graphql.Field{Type: graphql.String},
+		"url":        &graphql.Field{Type: graphql.String},
+		"normalized": &graphql.Field{Type: graphql.NewNonNull(gqlNormalizedType)},
+		"spec": &graphql.Field{
+			Type: graphql.NewNonNull(gqlSpecType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				c := gqlCatalog(p)
+				index, _ := c.Lookup(p.Source.(VariantEntry).ID)
+				return specRecord(c, index), nil
+			},
+		},
+	},
+})
+
+var gqlVariantConnection = connectionType(gqlVariantType)
+
+var gqlModelType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Model",
+	Fields: graphql.Fields{
+		"id":     &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"brand":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"url":    &graphql.Field{Type: graphql.String},
+		"image":  &graphql.Field{Type: graphql.String},
+		"years":  &graphql.Field{Type: graphql.String},
+		"status": &graphql.Field{Type: graphql.String},
+		"variantCount": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(ModelEntry).Variants, nil
+			},
+		},
+		"variants": &graphql.Field{
+			Type: graphql.NewNonNull(gqlVariantConnection),
+			Args: withArgs(gqlPageArgs, gqlSpecArgs),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				model := p.Source.(ModelEntry)
+				indexes, _ := gqlCatalog(p).ModelSpecs(slugID(model.Brand), model.ID)
+				return connect(p, variantNodes(p, indexes))
+			},
+		},
+	},
+})
+
+var gqlBrandType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Brand",
+	Fields: graphql.Fields{
+		"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"name":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"url":     &graphql.Field{Type: graphql.String},
+		"logo":    &graphql.Field{Type: graphql.String},
+		"country": &graphql.Field{Type: graphql.String},
+		"founded": &graphql.Field{Type: graphql.String},
+		"status":  &graphql.Field{Type: graphql.String},
+		"modelCount": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(BrandEntry).Models, nil
+			},
+		},
+		"variantCount": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(BrandEntry).Variants, nil
+			},
+		},
+		"models": &graphql.Field{
+			Type: graphql.NewNonNull(connectionType(gqlModelType)),
+			Args: withArgs(gqlPageArgs, graphql.FieldConfigArgument{
+				"name": &graphql.ArgumentConfig{Type: graphql.String, Description: "part of the model name"},
+			}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				models, _ := gqlCatalog(p).Models(p.Source.(BrandEntry).ID)
+				name, _ := p.Args["name"].(string)
+				nodes := []interface{}{}
+				for _, model := range models {
+					if name == "" || strings.Contains(strings.ToLower(model.Name), strings.ToLower(name)) {
+						nodes = append(nodes, model)
+					}
+				}
+				return connect(p, nodes)
+			},
+		},
+	},
+})
+
+// NewGraphQLSchema returns the schema of the GraphQL endpoint:
+//
+//	brands(name, first, after) -> models(name, first, after) -> variants(filters, first, after) -> spec
+//	brand(id), spec(id) and specs(filters, first, after)
+//
+// The filters are brand, model, source, yearFrom, yearTo and the
+// displacement, power and weight ranges.
+func NewGraphQLSchema() (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"brands": &graphql.Field{
+				Type: graphql.NewNonNull(connectionType(gqlBrandType)),
+				Args: withArgs(gqlPageArgs, graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.String, Description: "part of the brand name"},
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					name, _ := p.Args["name"].(string)
+					nodes := []interface{}{}
+					for _, brand := range gqlCatalog(p).Brands() {
+						if name == "" || strings.Contains(strings.ToLower(brand.Name), strings.ToLower(name)) {
+							nodes = append(nodes, brand)
+						}
+					}
+					return connect(p, nodes)
+				},
+			},
+			"brand": &graphql.Field{
+				Type: gqlBrandType,
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _ := p.Args["id"].(string)
+					if brand, ok := gqlCatalog(p).Brand(id); ok {
+						return brand, nil
+					}
+					return nil, nil
+				},
+			},
+			"specs": &graphql.Field{
+				Type: graphql.NewNonNull(gqlVariantConnection),
+				Args: withArgs(gqlPageArgs, gqlSpecArgs),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c := gqlCatalog(p)
+					indexes := make([]int, len(c.Specs))
+					for i := range indexes {
+						indexes[i] = i
+					}
+					return connect(p, variantNodes(p, indexes))
+				},
+			},
+			"spec": &graphql.Field{
+				Type: gqlSpecType,
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c := gqlCatalog(p)
+					id, _ := p.Args["id"].(string)
+					if index, ok := c.Lookup(id); ok {
+						return specRecord(c, index), nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeGraphQL runs a query given as the query parameter of a GET or as the
+// JSON body of a POST.
+func (a *API) ServeGraphQL(w http.ResponseWriter, r *http.Request) {
+	var request graphQLRequest
+	switch r.Method {
+	case "GET":
+		request.Query = r.URL.Query().Get("query")
+		request.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, "variables: "+err.Error())
+				return
+			}
+		}
+	case "POST":
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "only GET and POST are supported")
+		return
+	}
+	if request.Query == "" {
+		writeError(w, http.StatusBadRequest, "no query")
+		return
+	}
+	result := graphql.Do(graphql.Params{
+		Schema:         a.schema,
+		RequestString:  request.Query,
+		OperationName:  request.OperationName,
+		VariableValues: request.Variables,
+		Context:        context.WithValue(r.Context(), catalogKey{}, a.Catalog()),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,111 @@
+package motospec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	for _, offset := range []int{0, 1, 499} {
+		if got, err := decodeCursor(encodeCursor(offset)); got != offset || err != nil {
+			t.Errorf("%d: got %d %v", offset, got, err)
+		}
+	}
+	for _, cursor := range []string{"", "b2Zmc2V0", "b2Zmc2V0Oi0x", "not base64"} {
+		if _, err := decodeCursor(cursor); err == nil {
+			t.Errorf("%q decoded", cursor)
+		}
+	}
+}
+
+func TestServeGraphQL(t *testing.T) {
+	path, remove := writeSpecs(t, apiSpecs)
+	defer remove()
+	api, err := NewAPI(path, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bmw := SpecID(apiSpecs[3])
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"brands", `{brands{totalCount nodes{id modelCount variantCount}}}`,
+			`{"brands":{"nodes":[{"id":"bmw","modelCount":1,"variantCount":1},{"id":"ducati","modelCount":2,"variantCount":3}],"totalCount":2}}`},
+		{"brand name", `{brands(name:"DUC"){nodes{name}}}`, `{"brands":{"nodes":[{"name":"Ducati"}]}}`},
+		{"first page", `{brand(id:"ducati"){models(first:1){pageInfo{hasNextPage endCursor} nodes{id}}}}`,
+			`{"brand":{"models":{"nodes":[{"id":"monster"}],"pageInfo":{"endCursor":"` + encodeCursor(0) + `","hasNextPage":true}}}}`},
+		{"next page", `{brand(id:"ducati"){models(first:1, after:"` + encodeCursor(0) + `"){pageInfo{hasNextPage} nodes{id}}}}`,
+			`{"brand":{"models":{"nodes":[{"id":"scrambler"}],"pageInfo":{"hasNextPage":false}}}}`},
+		{"filters", `{specs(brand:"ducati", displacement:{min:800, max:1000}, yearFrom:2015){totalCount nodes{type}}}`,
+			`{"specs":{"nodes":[{"type":"Ducati Monster 821"},{"type":"Ducati Scrambler Icon"}],"totalCount":2}}`},
+		{"variants of a model", `{brand(id:"ducati"){models(name:"monster"){nodes{variants(power:{min:120}){nodes{spec{value(name:"Displacement")}}}}}}}`,
+			`{"brand":{"models":{"nodes":[{"variants":{"nodes":[{"spec":{"value":"1198 cc"}}]}}]}}}`},
+		{"variant url", `{brand(id:"bmw"){models{nodes{variants{nodes{url}}}}}}`,
+			`{"brand":{"models":{"nodes":[{"variants":{"nodes":[{"url":"` + apiSpecs[3].URL + `"}]}}]}}}`},
+		{"spec", `{spec(id:"` + bmw + `"){brand normalized{power} values(names:["power"]){name value}}}`,
+			`{"spec":{"brand":"BMW","normalized":{"power":91},"values":[{"name":"Power","value":"91 hp"}]}}`},
+		{"unknown spec", `{spec(id:"000000000000"){brand}}`, `{"spec":null}`},
+	}
+	for _, test := range tests {
+		for _, method := range []string{"GET", "POST"} {
+			var r *http.Request
+			if method == "GET" {
+				r = httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape(test.query), nil)
+			} else {
+				body, _ := json.Marshal(graphQLRequest{Query: test.query})
+				r = httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body)))
+			}
+			w := httptest.NewRecorder()
+			api.ServeGraphQL(w, r)
+			var result struct {
+				Data   json.RawMessage
+				Errors []interface{}
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || len(result.Errors) > 0 {
+				t.Errorf("%s %s: %v %s", test.name, method, err, w.Body)
+				continue
+			}
+			var data interface{}
+			json.Unmarshal(result.Data, &data)
+			if got, _ := json.Marshal(data); string(got) != test.want {
+				t.Errorf("%s %s: got %s, want %s", test.name, method, got, test.want)
+			}
+		}
+	}
+}
+
+func TestServeGraphQLErrors(t *testing.T) {
+	path, remove := writeSpecs(t, apiSpecs)
+	defer remove()
+	api, err := NewAPI(path, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+		errors bool
+	}{
+		{"no query", "GET", "/graphql", http.StatusBadRequest, false},
+		{"bad variables", "GET", "/graphql?query=%7Bbrands%7BtotalCount%7D%7D&variables=%7B", http.StatusBadRequest, false},
+		{"method", "DELETE", "/graphql", http.StatusMethodNotAllowed, false},
+		{"bad cursor", "GET", "/graphql?query=" + url.QueryEscape(`{brands(after:"x"){totalCount}}`), http.StatusOK, true},
+		{"first too large", "GET", "/graphql?query=" + url.QueryEscape(`{specs(first:501){totalCount}}`), http.StatusOK, true},
+		{"unknown field", "GET", "/graphql?query=" + url.QueryEscape(`{bikes{id}}`), http.StatusOK, true},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		api.ServeGraphQL(w, httptest.NewRequest(test.method, test.target, nil))
+		if w.Code != test.status || strings.Contains(w.Body.String(), `"errors"`) != test.errors {
+			t.Errorf("%s: status %d: %s", test.name, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package motospec
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+type HeaderProfile struct {
+	Name        string            `yaml:"-"`
+	Headers     map[string]string `yaml:"headers"`
+	UserAgents  []string          `yaml:"user_agents"`
+	DropCookies bool              `yaml:"drop_cookies"`
+
+	next uint64
+}
+
+var DefaultHeaderProfiles = map[string]*HeaderProfile{
+	"default": {
+		Headers: map[string]string{
+			"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			"Accept-Language":           "en-US,en;q=0.9",
+			"Cache-Control":             "max-age=0",
+			"Upgrade-Insecure-Requests": "1",
+		},
+		UserAgents: []string{
+			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15",
+			"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+		},
+		DropCookies: true,
+	},
+}
+
+// UserAgent returns the next agent of the pool, rotating on every call.
+func (h *HeaderProfile) UserAgent() string {
+	if len(h.UserAgents) == 0 {
+		return ""
+	}
+	n := atomic.AddUint64(&h.next, 1) - 1
+	return h.UserAgents[n%uint64(len(h.UserAgents))]
+}
+
+func (h *HeaderProfile) Apply(req *http.Request) {
+	for key, value := range h.Headers {
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+	if ua := h.UserAgent(); ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	if h.DropCookies {
+		req.Header.Del("Cookie")
+	}
+}
+
+func (h *HeaderProfile) Validate() []string {
+	errs := []string{}
+	for key, value := range h.Headers {
+		if !validHeaderName(key) {
+			errs = append(errs, fmt.Sprintf("headers: %q is not a valid header name", key))
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			errs = append(errs, fmt.Sprintf("headers.%s: value contains a line break", key))
+		}
+		if strings.EqualFold(key, "User-Agent") && len(h.UserAgents) > 0 {
+			errs = append(errs, "headers.User-Agent: conflicts with user_agents")
+		}
+		if strings.EqualFold(key, "Cookie") && h.DropCookies {
+			errs = append(errs, "headers.Cookie: conflicts with drop_cookies")
+		}
+	}
+	for i, ua := range h.UserAgents {
+		if strings.TrimSpace(ua) == "" || strings.ContainsAny(ua, "\r\n") {
+			errs = append(errs, fmt.Sprintf("user_agents[%d]: invalid user agent", i))
+		}
+	}
+	return errs
+}
+
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 127 || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
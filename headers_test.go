@@ -0,0 +1,78 @@
+package motospec
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHeaderProfileApply(t *testing.T) {
+	profile := &HeaderProfile{
+		Headers:     map[string]string{"Accept-Language": "de-DE", "Host": "www.autoevolution.com"},
+		UserAgents:  []string{"agent a", "agent b"},
+		DropCookies: true,
+	}
+	want := []string{"agent a", "agent b", "agent a"}
+	for i, agent := range want {
+		req, _ := http.NewRequest("GET", "https://127.0.0.1/moto/", nil)
+		req.Header.Set("Cookie", "session=1")
+		profile.Apply(req)
+		if got := req.Header.Get("User-Agent"); got != agent {
+			t.Errorf("request %d: user agent %q, want %q", i, got, agent)
+		}
+		if req.Header.Get("Accept-Language") != "de-DE" || req.Host != "www.autoevolution.com" || req.Header.Get("Cookie") != "" {
+			t.Errorf("request %d: headers %v, host %s", i, req.Header, req.Host)
+		}
+	}
+}
+
+func TestHeaderProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile HeaderProfile
+		want    string
+	}{
+		{"default", *DefaultHeaderProfiles["default"], ""},
+		{"header name", HeaderProfile{Headers: map[string]string{"Accept Language": "en"}}, `headers: "Accept Language" is not a valid header name`},
+		{"line break", HeaderProfile{Headers: map[string]string{"Accept": "text/html\r\nX-Injected: 1"}}, "headers.Accept: value contains a line break"},
+		{"user agent twice", HeaderProfile{Headers: map[string]string{"User-Agent": "a"}, UserAgents: []string{"b"}}, "headers.User-Agent: conflicts with user_agents"},
+		{"cookie dropped", HeaderProfile{Headers: map[string]string{"Cookie": "a=1"}, DropCookies: true}, "headers.Cookie: conflicts with drop_cookies"},
+		{"empty user agent", HeaderProfile{UserAgents: []string{"a", " "}}, "user_agents[1]: invalid user agent"},
+	}
+	for _, test := range tests {
+		if got := strings.Join(test.profile.Validate(), "; "); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestConfigHeaderProfile(t *testing.T) {
+	config := DefaultConfig()
+	config.Headers["mobile"] = &HeaderProfile{UserAgents: []string{"agent m"}}
+	config.CurrentSite().StageHeaders = map[string]string{"spec": "mobile", "model": "none"}
+	tests := []struct {
+		stage string
+		want  string
+	}{
+		{"brand", "default"},
+		{"spec", "mobile"},
+		{"model", ""},
+	}
+	for _, test := range tests {
+		profile := config.HeaderProfile(test.stage)
+		if test.want == "" {
+			if profile != nil {
+				t.Errorf("%s: got profile %q", test.stage, profile.Name)
+			}
+			continue
+		}
+		if profile == nil || profile.Name != test.want {
+			t.Errorf("%s: got %+v, want %s", test.stage, profile, test.want)
+		}
+	}
+	for name, profile := range config.Headers {
+		if profile.Name != "" {
+			t.Errorf("%s: shared profile named %q", name, profile.Name)
+		}
+	}
+}
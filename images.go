@@ -0,0 +1,90 @@
+package motospec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxImageSize is the largest image downloaded, unless an ImageStore sets
+// its own MaxSize.
+const MaxImageSize = 20 * 1024 * 1024
+
+type ImageStore struct {
+	Dir     string
+	Client  *http.Client
+	MaxSize int64
+
+	mutex sync.Mutex
+	saved map[string]Image
+}
+
+func NewImageStore(dir string) (*ImageStore, error) {
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return nil, err
+	}
+	return &ImageStore{
+		Dir:     dir,
+		Client:  &http.Client{Timeout: 30 * time.Second},
+		MaxSize: MaxImageSize,
+		saved:   make(map[string]Image),
+	}, nil
+}
+
+func (s *ImageStore) Save(url string) (Image, error) {
+	s.mutex.Lock()
+	img, ok := s.saved[url]
+	s.mutex.Unlock()
+	if ok {
+		return img, nil
+	}
+	resp, err := s.Client.Get(url)
+	if err != nil {
+		return Image{URL: url}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Image{URL: url}, fmt.Errorf("download image %s: %s", url, resp.Status)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, s.MaxSize+1))
+	if err != nil {
+		return Image{URL: url}, err
+	}
+	if int64(len(content)) > s.MaxSize {
+		return Image{URL: url}, fmt.Errorf("download image %s: larger than %d bytes", url, s.MaxSize)
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	ext := strings.ToLower(path.Ext(resp.Request.URL.Path))
+	if len(ext) > 5 {
+		ext = ""
+	}
+	rel := filepath.Join(hash[:2], hash+ext)
+	full := filepath.Join(s.Dir, rel)
+	if _, err := os.Stat(full); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(full), 0775); err != nil {
+			return Image{URL: url}, err
+		}
+		tmp := full + ".tmp"
+		if err := ioutil.WriteFile(tmp, content, 0664); err != nil {
+			return Image{URL: url}, err
+		}
+		if err := os.Rename(tmp, full); err != nil {
+			return Image{URL: url}, err
+		}
+	}
+	img = Image{URL: url, Hash: hash, Path: rel}
+	s.mutex.Lock()
+	s.saved[url] = img
+	s.mutex.Unlock()
+	return img, nil
+}
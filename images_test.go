@@ -0,0 +1,64 @@
+package motospec
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImageStoreSave(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		if strings.HasPrefix(r.URL.Path, "/missing") {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/large") {
+			w.Write([]byte("a larger image"))
+			return
+		}
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "images")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewImageStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.MaxSize = int64(len("image"))
+	const hash = "6105d6cc76af400325e94d588ce511be5bfdbb73b437dc51eca43917d7a43e3d"
+	tests := []struct {
+		path      string
+		want      string
+		downloads int
+		ok        bool
+	}{
+		{"/a.JPG", filepath.Join("61", hash+".jpg"), 1, true},
+		{"/a.JPG", filepath.Join("61", hash+".jpg"), 1, true},
+		{"/b.png", filepath.Join("61", hash+".png"), 2, true},
+		{"/c.gallery-image", filepath.Join("61", hash), 3, true},
+		{"/missing.jpg", "", 4, false},
+		{"/large.jpg", "", 5, false},
+	}
+	for _, test := range tests {
+		img, err := store.Save(server.URL + test.path)
+		if (err == nil) != test.ok || img.Path != test.want || downloads != test.downloads {
+			t.Errorf("%s: got %+v %v after %d downloads, want %s after %d", test.path, img, err, downloads, test.want, test.downloads)
+		}
+		if err == nil && img.Hash != hash {
+			t.Errorf("%s: hash %s", test.path, img.Hash)
+		}
+		if content, err := ioutil.ReadFile(filepath.Join(dir, test.want)); test.ok && (err != nil || string(content) != "image") {
+			t.Errorf("%s: saved %q %v", test.path, content, err)
+		}
+	}
+}
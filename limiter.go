@@ -0,0 +1,164 @@
+package motospec
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter spaces requests to the same host across every processor
+// sharing it. The spacing of a host starts at Base and adapts to how the
+// host responds: it is multiplied by Backoff when the host answers 429 or
+// 503 or times out, stretched a little when responses get slower than
+// SlowLatency, and shrinks by Recovery with every healthy response until it
+// is back at Base. A Retry-After header holds the host back for at least
+// that long, and a robots.txt Crawl-delay is never undercut.
+type RateLimiter struct {
+	Base        time.Duration
+	MaxDelay    time.Duration
+	Backoff     float64
+	Recovery    float64
+	SlowLatency time.Duration
+
+	mutex sync.Mutex
+	hosts map[string]*hostLimit
+}
+
+type hostLimit struct {
+	minDelay time.Duration
+	delay    time.Duration
+	next     time.Time
+}
+
+func NewRateLimiter(base time.Duration) *RateLimiter {
+	return &RateLimiter{
+		Base:        base,
+		MaxDelay:    5 * time.Minute,
+		Backoff:     2,
+		Recovery:    0.9,
+		SlowLatency: 5 * time.Second,
+		hosts:       make(map[string]*hostLimit),
+	}
+}
+
+func (l *RateLimiter) host(host string) *hostLimit {
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostLimit{delay: l.Base}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+func (l *RateLimiter) SetMinDelay(host string, delay time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.host(host).minDelay = delay
+}
+
+func (l *RateLimiter) Delay(host string) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	h := l.host(host)
+	if h.minDelay > h.delay {
+		return h.minDelay
+	}
+	return h.delay
+}
+
+// Wait blocks until a request to host may be sent and reserves that slot.
+func (l *RateLimiter) Wait(ctx context.Context, host string) error {
+	l.mutex.Lock()
+	h := l.host(host)
+	now := time.Now()
+	at := h.next
+	if at.Before(now) {
+		at = now
+	}
+	spacing := h.delay
+	if h.minDelay > spacing {
+		spacing = h.minDelay
+	}
+	h.next = at.Add(spacing)
+	l.mutex.Unlock()
+	if wait := at.Sub(now); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return nil
+}
+
+// Observe feeds the outcome of a request to host back into its spacing.
+func (l *RateLimiter) Observe(host string, err error, latency time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	h := l.host(host)
+	before := h.delay
+	now := time.Now()
+	switch e := err.(type) {
+	case nil:
+		if l.SlowLatency > 0 && latency > l.SlowLatency {
+			h.delay = l.scale(h.delay, 1.25)
+		} else {
+			h.delay = time.Duration(float64(h.delay) * l.Recovery)
+			if h.delay < l.Base {
+				h.delay = l.Base
+			}
+		}
+	case *ErrStatus:
+		if e.StatusCode != http.StatusTooManyRequests && e.StatusCode != http.StatusServiceUnavailable {
+			return
+		}
+		h.delay = l.scale(h.delay, l.Backoff)
+		if wait, ok := RetryAfter(e.Header, now); ok {
+			if wait > l.MaxDelay {
+				wait = l.MaxDelay
+			}
+			if until := now.Add(wait); until.After(h.next) {
+				h.next = until
+			}
+		}
+	case *ErrFetch:
+		if e.Timeout {
+			h.delay = l.scale(h.delay, l.Backoff)
+		}
+	}
+	if h.delay != before && (h.delay > before || h.delay == l.Base) {
+		ProcessorLogger.Printf("Throttle: %s spacing %s -> %s", host, before, h.delay)
+	}
+}
+
+func (l *RateLimiter) scale(delay time.Duration, factor float64) time.Duration {
+	if delay < time.Second {
+		delay = time.Second
+	}
+	delay = time.Duration(float64(delay) * factor)
+	if l.MaxDelay > 0 && delay > l.MaxDelay {
+		delay = l.MaxDelay
+	}
+	return delay
+}
+
+// RetryAfter reads a Retry-After header given either in seconds or as an
+// HTTP date.
+func RetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,154 @@
+package motospec
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		wait  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"Thu, 01 Jan 2026 12:00:30 GMT", 30 * time.Second, true},
+		{"Thu, 01 Jan 2026 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, test := range tests {
+		header := http.Header{}
+		if test.value != "" {
+			header.Set("Retry-After", test.value)
+		}
+		wait, ok := RetryAfter(header, now)
+		if wait != test.wait || ok != test.ok {
+			t.Errorf("%q: got %s %v, want %s %v", test.value, wait, ok, test.wait, test.ok)
+		}
+	}
+}
+
+func TestRateLimiterObserve(t *testing.T) {
+	status := func(code int, retryAfter string) error {
+		header := http.Header{}
+		if retryAfter != "" {
+			header.Set("Retry-After", retryAfter)
+		}
+		return &ErrStatus{URL: "https://example.com/", StatusCode: code, Header: header}
+	}
+	tests := []struct {
+		name    string
+		delay   time.Duration
+		err     error
+		latency time.Duration
+		want    time.Duration
+		held    time.Duration
+	}{
+		{"healthy recovers", 10 * time.Second, nil, time.Second, 9 * time.Second, 0},
+		{"healthy stays at base", 4 * time.Second, nil, time.Second, 4 * time.Second, 0},
+		{"slow response", 4 * time.Second, nil, 10 * time.Second, 5 * time.Second, 0},
+		{"429", 4 * time.Second, status(http.StatusTooManyRequests, ""), time.Second, 8 * time.Second, 0},
+		{"503 with Retry-After", 4 * time.Second, status(http.StatusServiceUnavailable, "60"), time.Second, 8 * time.Second, time.Minute},
+		{"Retry-After capped", 4 * time.Second, status(http.StatusTooManyRequests, "3600"), time.Second, 8 * time.Second, 5 * time.Minute},
+		{"404 is ignored", 4 * time.Second, status(http.StatusNotFound, ""), time.Second, 4 * time.Second, 0},
+		{"timeout", 4 * time.Second, &ErrFetch{Err: errors.New("timeout"), Timeout: true}, time.Second, 8 * time.Second, 0},
+		{"network error", 4 * time.Second, &ErrFetch{Err: errors.New("refused")}, time.Second, 4 * time.Second, 0},
+		{"capped at max delay", 4 * time.Minute, status(http.StatusTooManyRequests, ""), time.Second, 5 * time.Minute, 0},
+	}
+	for _, test := range tests {
+		limiter := NewRateLimiter(4 * time.Second)
+		limiter.host("example.com").delay = test.delay
+		start := time.Now()
+		limiter.Observe("example.com", test.err, test.latency)
+		if got := limiter.Delay("example.com"); got != test.want {
+			t.Errorf("%s: delay %s, want %s", test.name, got, test.want)
+		}
+		held := limiter.host("example.com").next.Sub(start)
+		if test.held == 0 && held > 0 || test.held > 0 && (held < test.held-time.Second || held > test.held+time.Second) {
+			t.Errorf("%s: held for %s, want %s", test.name, held, test.held)
+		}
+	}
+}
+
+func TestRateLimiterMinDelay(t *testing.T) {
+	limiter := NewRateLimiter(time.Second)
+	limiter.SetMinDelay("example.com", 10*time.Second)
+	limiter.Observe("example.com", nil, 0)
+	if got := limiter.Delay("example.com"); got != 10*time.Second {
+		t.Errorf("crawl-delay undercut: %s", got)
+	}
+	if err := limiter.Wait(context.Background(), "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx, "example.com"); err != context.Canceled {
+		t.Errorf("second wait: got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestOpenSessionThrottle(t *testing.T) {
+	tests := []struct {
+		adaptive bool
+		session  bool
+	}{
+		{true, true},
+		{false, false},
+	}
+	for _, test := range tests {
+		config := DefaultConfig()
+		config.Crawl.Throttle.Adaptive = test.adaptive
+		session, err := config.OpenSession(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (session != nil) != test.session {
+			t.Errorf("adaptive %v: session %v, want %v", test.adaptive, session != nil, test.session)
+		}
+		if session != nil && session.Client.Jar != nil {
+			t.Errorf("adaptive %v: the session keeps cookies", test.adaptive)
+		}
+	}
+}
+
+func TestFetchThrottle(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		ok       bool
+		requests int
+		slower   bool
+	}{
+		{"ok", nil, true, 1, false},
+		{"429 then ok", []int{429}, true, 2, true},
+		{"503 beyond retries", []int{503, 503, 503}, false, 3, true},
+		{"404", []int{404}, false, 1, false},
+	}
+	for _, test := range tests {
+		server, requests := statusServer("1", test.statuses...)
+		session, err := NewSession("test", "", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		limiter := NewRateLimiter(time.Millisecond)
+		limiter.MaxDelay = 20 * time.Millisecond
+		session.Limiter = limiter
+		p := &Processor{Ctx: context.Background(), Session: session, Limiter: limiter, Retries: 2}
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		_, err = p.Fetch(req)
+		server.Close()
+		if (err == nil) != test.ok || *requests != test.requests {
+			t.Errorf("%s: error %v after %d requests, want %d", test.name, err, *requests, test.requests)
+		}
+		if slower := limiter.Delay(req.URL.Host) > limiter.Base; slower != test.slower {
+			t.Errorf("%s: spacing %s, slowed down %v, want %v", test.name, limiter.Delay(req.URL.Host), slower, test.slower)
+		}
+	}
+}
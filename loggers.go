@@ -1,38 +1,41 @@
 package motospec
 
 import (
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 )
 
-// var ErrTimeoutLogger *log.Logger
-// var ErrFailedLogger *log.Logger
-var ErrClientLogger *log.Logger
-var ErrProcessorLogger *log.Logger
-var ProcessorLogger *log.Logger
+var ErrClientLogger = log.New(ioutil.Discard, "Client error:", log.Ldate|log.Ltime)
+var ErrProcessorLogger = log.New(ioutil.Discard, "Processor error:", log.Ldate|log.Ltime)
+var ProcessorLogger = log.New(ioutil.Discard, "Processor:", log.Ldate|log.Ltime)
 
-func init() {
-	// errTimeoutFile, err := os.OpenFile("errTimeout.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0664)
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// errFailedFile, err := os.OpenFile("errFailed.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0664)
-	// if err != nil {
-	// 	panic(err)
-	// }
-	errClientFile, err := os.OpenFile("errClient.log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0664)
-	if err != nil {
-		panic(err)
+// logFiles are closed when OpenLoggers is called again, as every daemon run
+// does.
+var logFiles []*os.File
+
+func OpenLoggers(dir string) error {
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return err
 	}
-	errProcessorFile, err := os.OpenFile("errProcessor.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0664)
-	if err != nil {
-		panic(err)
+	var files []*os.File
+	for _, name := range []string{"errClient.log", "errProcessor.log", "processor.log"} {
+		file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0664)
+		if err != nil {
+			for _, opened := range files {
+				opened.Close()
+			}
+			return err
+		}
+		files = append(files, file)
 	}
-	processorFile, err := os.OpenFile("processor.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0664)
-
-	// ErrTimeoutLogger = log.New(errTimeoutFile, "Client timeout:", log.Ldate|log.Ltime)
-	// ErrFailedLogger = log.New(errFailedFile, "Client failed:", log.Ldate|log.Ltime)
-	ErrClientLogger = log.New(errClientFile, "Client error:", log.Ldate|log.Ltime)
-	ErrProcessorLogger = log.New(errProcessorFile, "Processor error:", log.Ldate|log.Ltime)
-	ProcessorLogger = log.New(processorFile, "Processor:", log.Ldate|log.Ltime)
+	ErrClientLogger.SetOutput(files[0])
+	ErrProcessorLogger.SetOutput(files[1])
+	ProcessorLogger.SetOutput(files[2])
+	for _, file := range logFiles {
+		file.Close()
+	}
+	logFiles = files
+	return nil
 }
@@ -0,0 +1,44 @@
+package motospec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenLoggers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		for _, file := range logFiles {
+			file.Close()
+		}
+		logFiles = nil
+		ErrClientLogger.SetOutput(ioutil.Discard)
+		ErrProcessorLogger.SetOutput(ioutil.Discard)
+		ProcessorLogger.SetOutput(ioutil.Discard)
+	}()
+	broken := filepath.Join(dir, "broken")
+	if err := os.MkdirAll(filepath.Join(broken, "processor.log"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	if err := OpenLoggers(broken); err == nil {
+		t.Error("opened processor.log, a directory")
+	}
+	if len(logFiles) != 0 {
+		t.Errorf("kept %d log files of a failed open", len(logFiles))
+	}
+	if err := OpenLoggers(dir); err != nil {
+		t.Fatal(err)
+	}
+	ProcessorLogger.Println("opened")
+	content, err := ioutil.ReadFile(filepath.Join(dir, "processor.log"))
+	if err != nil || !strings.Contains(string(content), "opened") {
+		t.Errorf("processor.log holds %q, %v", content, err)
+	}
+}
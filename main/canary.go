@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"motospec"
+)
+
+func canaryCommand(args []string) error {
+	fs := flag.NewFlagSet("canary", flag.ExitOnError)
+	configFile := fs.String("config", "", "read the crawl configuration from `file`")
+	profile := fs.String("profile", "", "apply the named `profile` of the config file")
+	start := fs.String("url", "", "start the walk at `url` instead of the first configured seed")
+	minKeys := fs.Int("min-keys", 10, "fail when the spec page yields fewer than `n` spec keys")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	config := motospec.DefaultConfig()
+	if *configFile != "" {
+		var err error
+		if config, err = motospec.LoadConfig(*configFile, *profile); err != nil {
+			return err
+		}
+	}
+	if err := motospec.OpenLoggers(config.Crawl.LogDir); err != nil {
+		return err
+	}
+	if *start == "" {
+		*start = config.CurrentSite().Seeds[0]
+	}
+	seed, err := config.Adapter().ParseSeed(*start)
+	if err != nil {
+		return err
+	}
+	first, err := config.StageIndex(seed.Stage)
+	if err != nil {
+		return err
+	}
+	results, err := motospec.Canary(context.Background(), config, first, seed.Item, *minKeys)
+	for _, result := range results {
+		status := "ok"
+		if !result.OK() {
+			status = "FAIL"
+		}
+		fmt.Printf("%-4s stage %s %s: %d outputs\n", status, result.Stage, result.Input, result.Outputs)
+		for _, e := range result.Errors {
+			fmt.Printf("     %s\n", e)
+		}
+	}
+	return err
+}
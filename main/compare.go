@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"motospec"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+func compareCommand(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	in := fs.String("in", "motospecs.json", "read specs from `file`")
+	units := fs.String("units", motospec.Metric, "unit `system`: metric or imperial")
+	format := fs.String("format", "text", "output `format`: text or json")
+	width := fs.Int("width", 40, "cut the text values of the table to `n` characters, 0 keeps them whole")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return fmt.Errorf("usage: motospec compare [flags] id id...")
+	}
+	catalog, err := motospec.LoadCatalog(*in, "", "")
+	if err != nil {
+		return err
+	}
+	indexes := []int{}
+	for _, id := range fs.Args() {
+		index, ok := catalog.Lookup(id)
+		if !ok {
+			return fmt.Errorf("no spec %s in %s", id, *in)
+		}
+		indexes = append(indexes, index)
+	}
+	comparison, err := catalog.Compare(indexes, *units)
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(comparison)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := []string{"", ""}
+	for _, variant := range comparison.Variants {
+		header = append(header, strings.TrimSpace(variant.Brand+" "+variant.Moto+" "+variant.Year))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range comparison.Rows {
+		// a leading * marks the rows that differ
+		mark := " "
+		if row.Differs {
+			mark = "*"
+		}
+		key := row.Key
+		if row.Unit != "" {
+			key += " (" + row.Unit + ")"
+		}
+		cells := []string{mark, key}
+		for _, value := range row.Values {
+			if value == "" {
+				value = "-"
+			}
+			if runes := []rune(value); *width > 0 && len(runes) > *width {
+				value = string(runes[:*width-1]) + "…"
+			}
+			cells = append(cells, value)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
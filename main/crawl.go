@@ -0,0 +1,537 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"motospec"
+	"strings"
+	"time"
+)
+
+type crawlOptions struct {
+	config  string
+	profile string
+
+	specsOut    string
+	brandsOut   string
+	modelsOut   string
+	failuresOut string
+	format      string
+	imagesDir   string
+	snapshotDir string
+	logDir      string
+	interval    int
+	concurrency int
+
+	includeBrands listFlag
+	excludeBrands listFlag
+	includeModels listFlag
+	excludeModels listFlag
+	years         string
+	seedURLs      listFlag
+	urlsFile      string
+	sitemap       bool
+	sitemapURLs   listFlag
+	since         string
+}
+
+// sitemapSeeds feeds the spec stage from sitemaps; with no urls they are
+// those of the site config, then those robots.txt announces.
+type sitemapSeeds struct {
+	urls  []string
+	since time.Time
+}
+
+func (o *crawlOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.config, "config", "", "read the crawl configuration from `file`")
+	fs.StringVar(&o.profile, "profile", "", "apply the named `profile` of the config file")
+	fs.StringVar(&o.specsOut, "out", "motospecs.json", "write specs to `file`")
+	fs.StringVar(&o.brandsOut, "brands-out", "brands.json", "write brand records to `file`, empty to skip")
+	fs.StringVar(&o.modelsOut, "models-out", "models.json", "write model records to `file`, empty to skip")
+	fs.StringVar(&o.failuresOut, "failures-out", "failures.json", "record failed inputs in `file` for the retry command")
+	fs.StringVar(&o.format, "format", "ndjson", "output `format`: ndjson or json")
+	fs.StringVar(&o.imagesDir, "images-dir", "", "download gallery images into `dir`")
+	fs.StringVar(&o.snapshotDir, "snapshot-dir", "", "commit the crawled specs as a snapshot to the store in `dir`")
+	fs.StringVar(&o.logDir, "log-dir", ".", "write log files into `dir`")
+	fs.IntVar(&o.interval, "interval", 4, "at least `seconds` between two requests to one host")
+	fs.IntVar(&o.concurrency, "concurrency", 1, "number of workers per stage")
+	fs.Var(&o.includeBrands, "brand", "only crawl brands matching `pattern` (repeatable)")
+	fs.Var(&o.excludeBrands, "exclude-brand", "skip brands matching `pattern` (repeatable)")
+	fs.Var(&o.includeModels, "model", "only crawl models matching `pattern` (repeatable)")
+	fs.Var(&o.excludeModels, "exclude-model", "skip models matching `pattern` (repeatable)")
+	fs.StringVar(&o.years, "years", "", "only crawl variants produced within `from-to`, e.g. 1990-2005")
+	fs.Var(&o.seedURLs, "url", "start from a brand, model or spec `url` instead of the configured seeds (repeatable)")
+	fs.StringVar(&o.urlsFile, "urls-file", "", "read seed urls from `file`, one per line")
+	fs.BoolVar(&o.sitemap, "sitemap", false, "seed the spec stage from the site's sitemaps instead of its index pages")
+	fs.Var(&o.sitemapURLs, "sitemap-url", "read spec pages from the sitemap or sitemap index at `url` (repeatable, implies -sitemap)")
+	fs.StringVar(&o.since, "since", "", "with -sitemap, skip pages whose lastmod is before `date` (YYYY-MM-DD)")
+}
+
+func setSink(config *motospec.Config, kind, path, format string) {
+	for i := range config.Sinks {
+		if config.Sinks[i].Kind != kind {
+			continue
+		}
+		if path == "" {
+			config.Sinks = append(config.Sinks[:i], config.Sinks[i+1:]...)
+			return
+		}
+		config.Sinks[i].Path = path
+		if format != "" {
+			config.Sinks[i].Format = format
+		}
+		return
+	}
+	if path != "" {
+		config.Sinks = append(config.Sinks, motospec.SinkConfig{Kind: kind, Path: path, Format: format})
+	}
+}
+
+// load builds the crawl configuration: the config file, or the defaults when
+// there is none, overridden by every flag given on the command line or in
+// the environment.
+func (o *crawlOptions) load(set map[string]bool) (*motospec.Config, error) {
+	config := motospec.DefaultConfig()
+	if o.config != "" {
+		var err error
+		if config, err = motospec.LoadConfig(o.config, o.profile); err != nil {
+			return nil, err
+		}
+	} else if o.profile != "" {
+		return nil, fmt.Errorf("-profile needs a -config file")
+	}
+	format := ""
+	if set["format"] {
+		format = o.format
+		for i := range config.Sinks {
+			if config.Sinks[i].Kind != "failures" {
+				config.Sinks[i].Format = format
+			}
+		}
+	}
+	if set["out"] {
+		setSink(config, "specs", o.specsOut, format)
+	}
+	if set["brands-out"] {
+		setSink(config, "brands", o.brandsOut, format)
+	}
+	if set["models-out"] {
+		setSink(config, "models", o.modelsOut, format)
+	}
+	if set["failures-out"] {
+		setSink(config, "failures", o.failuresOut, "")
+	}
+	if set["images-dir"] {
+		config.Crawl.ImagesDir = o.imagesDir
+	}
+	if set["snapshot-dir"] {
+		config.Crawl.SnapshotDir = o.snapshotDir
+	}
+	if set["log-dir"] {
+		config.Crawl.LogDir = o.logDir
+	}
+	if set["interval"] {
+		config.Crawl.Interval = o.interval
+	}
+	if set["concurrency"] {
+		config.Crawl.Concurrency = o.concurrency
+	}
+	filter := &config.Crawl.Filter
+	if set["brand"] {
+		filter.Brands = o.includeBrands
+	}
+	if set["exclude-brand"] {
+		filter.ExcludeBrands = o.excludeBrands
+	}
+	if set["model"] {
+		filter.Models = o.includeModels
+	}
+	if set["exclude-model"] {
+		filter.ExcludeModels = o.excludeModels
+	}
+	if set["years"] {
+		filter.Years = o.years
+	}
+	return config, config.Validate()
+}
+
+func (o *crawlOptions) seeds(config *motospec.Config) ([]motospec.Seed, error) {
+	urls := append([]string{}, o.seedURLs...)
+	if o.urlsFile != "" {
+		content, err := ioutil.ReadFile(o.urlsFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				urls = append(urls, line)
+			}
+		}
+	}
+	if len(urls) == 0 {
+		if o.sitemap || len(o.sitemapURLs) > 0 {
+			return nil, nil
+		}
+		urls = config.CurrentSite().Seeds
+	}
+	return parseSeeds(config, urls)
+}
+
+func parseSeeds(config *motospec.Config, urls []string) ([]motospec.Seed, error) {
+	seeds := make([]motospec.Seed, 0, len(urls))
+	for _, u := range urls {
+		seed, err := config.Adapter().ParseSeed(u)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, seed)
+	}
+	return seeds, nil
+}
+
+// partial tells a crawl that leaves out part of the site, whose snapshot
+// must not remove the variants it did not visit.
+func (o *crawlOptions) partial(config *motospec.Config, sitemaps *sitemapSeeds) bool {
+	return len(o.seedURLs) > 0 || o.urlsFile != "" || partialCrawl(config, sitemaps)
+}
+
+func partialCrawl(config *motospec.Config, sitemaps *sitemapSeeds) bool {
+	f := config.Crawl.Filter
+	if len(f.Brands)+len(f.ExcludeBrands)+len(f.Models)+len(f.ExcludeModels) > 0 || f.Years != "" {
+		return true
+	}
+	return sitemaps != nil && !sitemaps.since.IsZero()
+}
+
+func (o *crawlOptions) sitemapSeeds(config *motospec.Config) (*sitemapSeeds, error) {
+	if !o.sitemap && len(o.sitemapURLs) == 0 {
+		if o.since != "" {
+			return nil, fmt.Errorf("-since needs -sitemap")
+		}
+		return nil, nil
+	}
+	seeds := &sitemapSeeds{urls: o.sitemapURLs}
+	if len(seeds.urls) == 0 {
+		seeds.urls = config.CurrentSite().Sitemap.URLs
+	}
+	if o.since != "" {
+		var err error
+		if seeds.since, err = time.Parse("2006-01-02", o.since); err != nil {
+			return nil, fmt.Errorf("-since: %v", err)
+		}
+	}
+	return seeds, nil
+}
+
+// injectSitemaps walks the sitemaps in the background and hands every page
+// the adapter takes for a spec page to the spec stage, its names derived
+// from the url.
+func injectSitemaps(ctx context.Context, pipeline *motospec.Pipeline, stage int, reader *motospec.SitemapReader, seeds *sitemapSeeds, config *motospec.Config) error {
+	adapter := config.Adapter()
+	last := len(adapter.Stages()) - 1
+	injector, err := pipeline.Inject(stage)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer close(injector)
+		urls := seeds.urls
+		if len(urls) == 0 {
+			for _, seed := range config.CurrentSite().Seeds {
+				discovered, err := reader.Discover(ctx, seed)
+				if err != nil {
+					motospec.ErrProcessorLogger.Printf("Sitemap: %v", err)
+					return
+				}
+				urls = append(urls, discovered...)
+			}
+		}
+		// a spec page url tells the brand slug, not the brand name
+		brands, err := motospec.SiteBrands(ctx, config, pipeline.ProcessorList[stage])
+		if err != nil {
+			motospec.ErrProcessorLogger.Printf("Sitemap: brand listing: %v", err)
+		}
+		queued := make(map[string]bool)
+		err = reader.Walk(ctx, urls, func(page motospec.SitemapEntry) error {
+			seed, err := adapter.ParseSeed(page.Loc)
+			if err != nil || seed.Stage != last || queued[page.Loc] {
+				return nil
+			}
+			queued[page.Loc] = true
+			if moto, ok := seed.Item.(motospec.MotoURL); ok {
+				seed.Item = brands.Resolve(moto)
+			}
+			select {
+			case injector <- seed.Item:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil && err != context.Canceled {
+			motospec.ErrProcessorLogger.Printf("Sitemap: %v", err)
+		}
+		motospec.ProcessorLogger.Printf("Sitemap: queued %d spec pages", len(queued))
+	}()
+	return nil
+}
+
+func openSinks(config *motospec.Config) (*motospec.EntitySink, error) {
+	sink := &motospec.EntitySink{}
+	for _, sc := range config.Sinks {
+		if sc.Kind == "failures" || sc.Kind == "quarantine" {
+			continue
+		}
+		s, err := motospec.NewFileSink(sc.Path, sc.Format)
+		if err != nil {
+			sink.Close()
+			return nil, err
+		}
+		switch sc.Kind {
+		case "specs":
+			sink.Specs = s
+		case "brands":
+			sink.Brands = s
+		case "models":
+			sink.Models = s
+		}
+	}
+	return sink, nil
+}
+
+func injectSeeds(pipeline *motospec.Pipeline, byStage map[int][]interface{}) error {
+	for stage, items := range byStage {
+		injector, err := pipeline.Inject(stage)
+		if err != nil {
+			return err
+		}
+		go func(injector chan<- interface{}, items []interface{}) {
+			for _, item := range items {
+				injector <- item
+			}
+			close(injector)
+		}(injector, items)
+	}
+	return nil
+}
+
+// crawlStats are what a crawl wrote: its number of specs and the snapshot
+// committed, if any.
+type crawlStats struct {
+	Specs    int
+	Snapshot *motospec.Snapshot
+}
+
+// runCrawl crawls until the seeds are exhausted or parent is done.
+func runCrawl(parent context.Context, config *motospec.Config, seeds []motospec.Seed, sitemaps *sitemapSeeds, partial bool) (crawlStats, error) {
+	stats := crawlStats{}
+	if err := motospec.OpenLoggers(config.Crawl.LogDir); err != nil {
+		return stats, err
+	}
+	filter, err := config.Crawl.Filter.Build()
+	if err != nil {
+		return stats, err
+	}
+	stages, err := config.StageFuncList()
+	if err != nil {
+		return stats, err
+	}
+	first := len(stages)
+	byStage := make(map[int][]interface{})
+	for _, seed := range seeds {
+		index, err := config.StageIndex(seed.Stage)
+		if err != nil {
+			return stats, err
+		}
+		if index < first {
+			first = index
+		}
+		byStage[index] = append(byStage[index], seed.Item)
+	}
+	specIndex := -1
+	if sitemaps != nil {
+		if specIndex, err = config.StageIndex(len(config.Adapter().Stages()) - 1); err != nil {
+			return stats, err
+		}
+		if specIndex < first {
+			first = specIndex
+		}
+	}
+	relative := make(map[int][]interface{})
+	for index, items := range byStage {
+		relative[index-first] = items
+	}
+	sink, err := openSinks(config)
+	if err != nil {
+		return stats, err
+	}
+	defer sink.Close()
+	var failures, quarantine motospec.Sink
+	if sc, ok := config.Sink("failures"); ok {
+		if failures, err = motospec.NewJSONSink(sc.Path); err != nil {
+			return stats, err
+		}
+		defer failures.Close()
+	}
+	if sc, ok := config.Sink("quarantine"); ok {
+		if quarantine, err = motospec.NewFileSink(sc.Path, sc.Format); err != nil {
+			return stats, err
+		}
+		defer quarantine.Close()
+	}
+	var store *motospec.SnapshotStore
+	if config.Crawl.SnapshotDir != "" {
+		if store, err = motospec.OpenSnapshotStore(config.Crawl.SnapshotDir); err != nil {
+			return stats, err
+		}
+	}
+	emitter, err := config.Events.Open()
+	if err != nil {
+		return stats, err
+	}
+	if emitter != nil {
+		defer emitter.Close()
+		if store == nil {
+			return stats, fmt.Errorf("events need a snapshot store, set crawl.snapshot_dir or -snapshot-dir")
+		}
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	pipeline := motospec.NewPipeline(ctx, stages[first:], config.Crawl.Interval)
+	pipeline.Configure(config, config.CurrentSite().Stages[first:])
+	post, err := config.PostFuncList()
+	if err != nil {
+		return stats, err
+	}
+	for _, pf := range post {
+		pipeline.Append(pf).Workers = config.Crawl.Concurrency
+	}
+	pipeline.SetFilter(filter)
+	pipeline.FailureSink, pipeline.QuarantineSink = failures, quarantine
+	session, err := config.OpenSession(ctx)
+	if err != nil {
+		return stats, err
+	}
+	limiter := config.OpenLimiter()
+	pipeline.SetLimiter(limiter)
+	robots := config.OpenRobots(session, limiter)
+	pipeline.SetRobots(robots)
+	if session != nil {
+		session.Limiter = limiter
+		pipeline.SetSession(session)
+		defer func() {
+			if err := session.Save(); err != nil {
+				motospec.ErrProcessorLogger.Printf("Session: %v", err)
+			}
+			if session.Proxies != nil {
+				printProxyStats(session.Proxies)
+			}
+		}()
+	}
+	if err := injectSeeds(pipeline, relative); err != nil {
+		return stats, err
+	}
+	if sitemaps != nil {
+		reader, err := config.OpenSitemaps(session, robots, limiter)
+		if err != nil {
+			return stats, err
+		}
+		reader.Since = sitemaps.since
+		if err := injectSitemaps(ctx, pipeline, specIndex-first, reader, sitemaps, config); err != nil {
+			return stats, err
+		}
+	}
+	close(pipeline.Input)
+	go pipeline.Run()
+	entitiesDone := make(chan struct{})
+	go func() {
+		for entity := range pipeline.Entities {
+			if err := sink.Write(entity); err != nil {
+				motospec.ErrProcessorLogger.Printf("Sink: %v", err)
+			}
+		}
+		close(entitiesDone)
+	}()
+	crawled := []motospec.Spec{}
+	for s := range pipeline.Output {
+		spec := s.(motospec.Spec)
+		if err := sink.Write(spec); err != nil {
+			motospec.ErrProcessorLogger.Printf("Sink: %v", err)
+		}
+		if store != nil {
+			crawled = append(crawled, spec)
+		}
+		stats.Specs++
+		motospec.ProcessorLogger.Printf("Crawl: %s %s %s %s", spec.Brand, spec.Model, spec.Moto, spec.Year)
+	}
+	<-pipeline.Done
+	<-entitiesDone
+	if store == nil {
+		return stats, nil
+	}
+	// an interrupted crawl would remove every variant it did not reach
+	if ctx.Err() != nil {
+		motospec.ProcessorLogger.Println("Snapshot: crawl interrupted, no snapshot committed")
+		return stats, nil
+	}
+	// a variant whose page failed or whose spec was quarantined is missing
+	// from crawled without being gone from the site, its last spec is kept
+	if !partial && (pipeline.Failed > 0 || pipeline.Quarantined > 0) {
+		n := len(crawled)
+		if crawled, err = store.CarryOver(crawled, pipeline.Missed.Has); err != nil {
+			return stats, err
+		}
+		motospec.ProcessorLogger.Printf("Snapshot: %d failures and %d quarantined specs, kept %d variants of the last snapshot", pipeline.Failed, pipeline.Quarantined, len(crawled)-n)
+	}
+	snapshot, err := store.Commit(crawled, time.Now(), partial)
+	if err != nil {
+		return stats, err
+	}
+	printSnapshot(snapshot)
+	stats.Snapshot = &snapshot
+	if emitter == nil {
+		return stats, nil
+	}
+	return stats, emitSnapshot(emitter, store, snapshot)
+}
+
+func printProxyStats(pool *motospec.ProxyPool) {
+	for _, stats := range pool.Stats() {
+		state := "healthy"
+		if stats.Demoted {
+			state = "demoted until " + stats.Until.Format("15:04:05")
+		}
+		line := fmt.Sprintf("proxy %s: %d ok, %d failed, %d demotions, %s", stats.Proxy, stats.Successes, stats.Failures, stats.Demotions, state)
+		fmt.Println(line)
+		motospec.ProcessorLogger.Println(line)
+	}
+}
+
+func crawlCommand(args []string) error {
+	fs := flag.NewFlagSet("crawl", flag.ExitOnError)
+	o := &crawlOptions{}
+	o.register(fs)
+	set, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	config, err := o.load(set)
+	if err != nil {
+		return err
+	}
+	seeds, err := o.seeds(config)
+	if err != nil {
+		return err
+	}
+	sitemaps, err := o.sitemapSeeds(config)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go HandleInterrupt(cancel)
+	_, err = runCrawl(ctx, config, seeds, sitemaps, o.partial(config, sitemaps))
+	return err
+}
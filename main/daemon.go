@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"motospec"
+	"net/http"
+	"time"
+)
+
+// crawlJob runs a daemon job: a crawl with the job's profile of the config
+// file, from the sitemap pages changed since the last successful run when
+// the job is incremental.
+func crawlJob(configFile string) motospec.JobFunc {
+	return func(ctx context.Context, job motospec.JobConfig, since time.Time) (motospec.JobRun, error) {
+		config, err := motospec.LoadConfig(configFile, job.Profile)
+		if err != nil {
+			return motospec.JobRun{}, err
+		}
+		var seeds []motospec.Seed
+		var sitemaps *sitemapSeeds
+		if job.Incremental {
+			sitemaps = &sitemapSeeds{urls: config.CurrentSite().Sitemap.URLs, since: since}
+		} else if seeds, err = parseSeeds(config, config.CurrentSite().Seeds); err != nil {
+			return motospec.JobRun{}, err
+		}
+		stats, err := runCrawl(ctx, config, seeds, sitemaps, partialCrawl(config, sitemaps))
+		return motospec.JobRun{Specs: stats.Specs, Snapshot: stats.Snapshot}, err
+	}
+}
+
+func daemonCommand(args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	configFile := fs.String("config", "", "read the jobs and their crawl configuration from `file`")
+	addr := fs.String("addr", "", "serve the status on `address` instead of daemon.addr, empty to use the config")
+	run := fs.String("run", "", "run the named `job` once now and exit")
+	set, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *configFile == "" {
+		return fmt.Errorf("usage: motospec daemon -config <file> [flags]")
+	}
+	config, err := motospec.LoadConfig(*configFile, "")
+	if err != nil {
+		return err
+	}
+	if set["addr"] {
+		config.Daemon.Addr = *addr
+	}
+	daemon, err := motospec.NewDaemon(config.Daemon, crawlJob(*configFile))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go HandleInterrupt(cancel)
+	if *run != "" {
+		result, err := daemon.RunJob(ctx, *run)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("job %s %s %s\n", result.Job, result.Status, result.Error)
+		if result.Status != motospec.JobOK {
+			return fmt.Errorf("job %s %s", result.Job, result.Status)
+		}
+		return nil
+	}
+	if config.Daemon.Addr != "" {
+		server := &http.Server{Addr: config.Daemon.Addr, Handler: daemon}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Println("status server:", err)
+			}
+		}()
+		defer server.Close()
+		fmt.Printf("status on %s\n", config.Daemon.Addr)
+	}
+	for _, job := range daemon.Status().Jobs {
+		fmt.Printf("job %s: %s\n", job.Name, job.Schedule)
+	}
+	daemon.Start(ctx)
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"motospec"
+	"os"
+)
+
+func diffCommand(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "text", "output `format`: text or json")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: motospec diff [flags] old.json new.json")
+	}
+	olds, err := motospec.LoadSpecs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	news, err := motospec.LoadSpecs(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	changes := motospec.DiffSpecs(olds, news)
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, change := range changes {
+			if err := encoder.Encode(change); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, change := range changes {
+		switch change.Kind {
+		case motospec.SpecAdded:
+			fmt.Printf("+ %s %s %s (%s)\n", change.New.Brand, change.New.Moto, change.New.Year, change.Key)
+		case motospec.SpecRemoved:
+			fmt.Printf("- %s %s %s (%s)\n", change.Old.Brand, change.Old.Moto, change.Old.Year, change.Key)
+		case motospec.SpecChanged:
+			fmt.Printf("~ %s %s %s (%s)\n", change.New.Brand, change.New.Moto, change.New.Year, change.Key)
+			for _, field := range change.Changes {
+				fmt.Printf("    %s: %q -> %q\n", field.Field, field.Old, field.New)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"motospec"
+	"os"
+)
+
+func exportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	in := fs.String("in", "motospecs.json", "read specs from `file`")
+	out := fs.String("out", "", "write to `file` instead of stdout")
+	format := fs.String("format", "csv", "output `format`: csv, json or ndjson")
+	snapshot := fs.String("snapshot", "", "export the dataset as of snapshot `id` (or latest) instead of -in")
+	snapshotDir := fs.String("snapshot-dir", "snapshots", "read snapshots from `dir`")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	var specs []motospec.Spec
+	if *snapshot != "" {
+		store, err := motospec.OpenSnapshotStore(*snapshotDir)
+		if err != nil {
+			return err
+		}
+		if specs, err = store.As(*snapshot); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if specs, err = motospec.LoadSpecs(*in); err != nil {
+			return err
+		}
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	switch *format {
+	case "csv":
+		return motospec.WriteCSV(w, specs)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(specs)
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+		for _, spec := range specs {
+			if err := encoder.Encode(spec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown export format %q", *format)
+}
@@ -2,45 +2,79 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"motospec"
 	"os"
 	"os/signal"
 )
 
 var StartURL = "https://www.autoevolution.com/moto/"
 
-func HandleInterrupt(pl *motospec.Pipeline, cancel context.CancelFunc) {
-	interruptChan := make(chan os.Signal)
+var commands = map[string]func([]string) error{
+	"crawl":    crawlCommand,
+	"canary":   canaryCommand,
+	"export":   exportCommand,
+	"diff":     diffCommand,
+	"serve":    serveCommand,
+	"retry":    retryCommand,
+	"merge":    mergeCommand,
+	"index":    indexCommand,
+	"search":   searchCommand,
+	"compare":  compareCommand,
+	"report":   reportCommand,
+	"snapshot": snapshotCommand,
+	"history":  historyCommand,
+	"notify":   notifyCommand,
+	"daemon":   daemonCommand,
+}
+
+func HandleInterrupt(cancel context.CancelFunc) {
+	interruptChan := make(chan os.Signal, 1)
 	signal.Notify(interruptChan, os.Interrupt)
 	<-interruptChan
 	fmt.Println("Got a interrupt signal, closing......")
 	cancel()
 }
 
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: motospec <command> [flags]
+
+commands:
+  crawl    crawl specs into the output files
+  canary   walk one input through every stage to check the selectors
+  export   convert a spec file to csv, json or ndjson
+  diff     compare two spec files
+  serve    serve the crawled files over http
+  retry    re-crawl the inputs recorded as failed by a previous run
+  merge    match specs of several sources and merge them into golden records
+  index    build a search index from spec files
+  search   search an index by words and facets
+  compare  print the specs of two variants or more side by side
+  report   check the quality of a crawled dataset
+  snapshot commit a spec file as a dataset snapshot, or list the snapshots
+  history  print the values a field of a variant took over the snapshots
+  notify   send the events of a snapshot to the configured event sinks
+  daemon   run the configured crawl jobs on their cron schedules
+
+Run "motospec <command> -h" for the flags of a command. Commands taking
+-config read a YAML config file, see main/motospec.yaml, and -profile applies
+one of the profiles it names on top of it. Flags given on the command line
+override the config; a flag left out is read from the MOTOSPEC_<NAME>
+environment variable, MOTOSPEC_SNAPSHOT_DIR for -snapshot-dir, when it is set.
+`)
+}
+
 func main() {
-	jsonFile, err := os.OpenFile("motospecs.json", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0664)
-	if err != nil {
-		fmt.Println(err)
-		return
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
 	}
-	jsonEncoder := json.NewEncoder(jsonFile)
-	ctx, cancel := context.WithCancel(context.Background())
-	pipeline := motospec.NewPipeline(ctx, []motospec.ProcessFunc{
-		motospec.BrandFunc,
-		motospec.ModelFunc,
-		motospec.MotoFunc,
-		motospec.SpecFunc,
-	}, 4)
-	go pipeline.Run()
-	pipeline.Input <- StartURL
-	close(pipeline.Input)
-	go HandleInterrupt(pipeline, cancel)
-	for s := range pipeline.Output {
-		spec := s.(motospec.Spec)
-		jsonEncoder.Encode(spec)
-		fmt.Println(spec.Brand, spec.Model, spec.Moto, spec.Year)
+	if err := command(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	<-pipeline.Done
 }
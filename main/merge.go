@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"motospec"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func mergeCommand(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	configFile := fs.String("config", "", "read the resolve settings from `file`")
+	profile := fs.String("profile", "", "apply the named `profile` of the config file")
+	out := fs.String("out", "", "write the golden records to `file` instead of stdout")
+	format := fs.String("format", "ndjson", "output `format`: ndjson or json")
+	conflicts := fs.Bool("conflicts", false, "only write records with conflicting fields")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: motospec merge [flags] specs.json...")
+	}
+	config := motospec.DefaultConfig()
+	if *configFile != "" {
+		var err error
+		if config, err = motospec.LoadConfig(*configFile, *profile); err != nil {
+			return err
+		}
+	}
+	specs := []motospec.Spec{}
+	for _, path := range fs.Args() {
+		loaded, err := motospec.LoadSpecs(path)
+		if err != nil {
+			return err
+		}
+		// files crawled before specs were tagged count as a source of their own
+		source := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		for i := range loaded {
+			if loaded[i].Source == "" {
+				loaded[i].Source = source
+			}
+		}
+		specs = append(specs, loaded...)
+	}
+	records := config.Resolve.Resolver().Resolve(specs)
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	merged, conflicted := 0, 0
+	kept := make([]motospec.GoldenRecord, 0, len(records))
+	for _, record := range records {
+		if len(record.Sources) > 1 {
+			merged++
+		}
+		if len(record.Conflicts) > 0 {
+			conflicted++
+		}
+		if *conflicts && len(record.Conflicts) == 0 {
+			continue
+		}
+		kept = append(kept, record)
+	}
+	fmt.Fprintf(os.Stderr, "%d specs -> %d records, %d merged from several sources, %d with conflicts\n", len(specs), len(records), merged, conflicted)
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(kept)
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+		for _, record := range kept {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown merge format %q", *format)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"motospec"
+	"os"
+	"strings"
+)
+
+func reportCommand(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	configFile := fs.String("config", "", "read the report thresholds from `file`")
+	profile := fs.String("profile", "", "apply the named `profile` of the config file")
+	in := fs.String("in", "motospecs.json", "read specs from `file`")
+	brands := fs.String("brands", "brands.json", "read brand records from `file`, if it exists")
+	models := fs.String("models", "models.json", "read model records from `file`, if it exists")
+	out := fs.String("out", "", "write the report to `file` instead of stdout")
+	format := fs.String("format", "html", "output `format`: html or json")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	config := motospec.DefaultConfig()
+	if *configFile != "" {
+		var err error
+		if config, err = motospec.LoadConfig(*configFile, *profile); err != nil {
+			return err
+		}
+	}
+	specs, err := motospec.LoadSpecs(*in)
+	if err != nil {
+		return err
+	}
+	brandRecords, err := motospec.LoadBrands(*brands)
+	if err != nil {
+		return err
+	}
+	modelRecords, err := motospec.LoadModels(*models)
+	if err != nil {
+		return err
+	}
+	report := motospec.BuildQualityReport(specs, brandRecords, modelRecords, config.Report)
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	switch *format {
+	case "html":
+		err = report.WriteHTML(w)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	default:
+		return fmt.Errorf("unknown report format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d specs, %d placeholders, %d duplicates, %d outliers\n", report.Specs, report.Placeholders, len(report.Duplicates), len(report.Outliers))
+	// a failed threshold fails the command, so CI can gate on it
+	if !report.OK() {
+		return fmt.Errorf("quality thresholds failed:\n  %s", strings.Join(report.Failures, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"motospec"
+	"os"
+)
+
+func readFailures(path string) ([]motospec.Failure, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	failures := []motospec.Failure{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var failure motospec.Failure
+		if err := json.Unmarshal(scanner.Bytes(), &failure); err != nil {
+			return nil, err
+		}
+		failures = append(failures, failure)
+	}
+	return failures, scanner.Err()
+}
+
+func retryCommand(args []string) error {
+	fs := flag.NewFlagSet("retry", flag.ExitOnError)
+	o := &crawlOptions{}
+	o.register(fs)
+	from := fs.String("from", "failures.json", "retry the inputs recorded in `file`")
+	set, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	config, err := o.load(set)
+	if err != nil {
+		return err
+	}
+	failures, err := readFailures(*from)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	seeds := []motospec.Seed{}
+	for _, failure := range failures {
+		if failure.Reason == motospec.FailureRobots || seen[failure.URL] {
+			continue
+		}
+		seen[failure.URL] = true
+		seed, err := failure.Seed(config.Adapter())
+		if err != nil {
+			return err
+		}
+		seeds = append(seeds, seed)
+	}
+	if len(seeds) == 0 {
+		fmt.Println("nothing to retry")
+		return nil
+	}
+	// failures of this run are recorded afresh, so keep the old list aside
+	// instead of appending to the file being retried
+	if sc, ok := config.Sink("failures"); ok && sc.Path == *from {
+		if err := os.Rename(*from, *from+".prev"); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("retrying %d inputs\n", len(seeds))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go HandleInterrupt(cancel)
+	_, err = runCrawl(ctx, config, seeds, nil, true)
+	return err
+}
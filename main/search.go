@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"motospec"
+	"os"
+	"strings"
+)
+
+func indexCommand(args []string) error {
+	fs := flag.NewFlagSet("index", flag.ExitOnError)
+	out := fs.String("out", "motospecs.idx", "write the index to `file`")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: motospec index [flags] specs.json...")
+	}
+	specs := []motospec.Spec{}
+	for _, path := range fs.Args() {
+		loaded, err := motospec.LoadSpecs(path)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, loaded...)
+	}
+	index := motospec.BuildSearchIndex(specs)
+	if err := index.Save(*out); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "indexed %d specs, %d terms into %s\n", len(index.Docs), len(index.Terms), *out)
+	return nil
+}
+
+func searchCommand(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	indexFile := fs.String("index", "motospecs.idx", "search the index in `file`")
+	var facets listFlag
+	fs.Var(&facets, "facet", "keep the hits whose facet has a value, as `name=value`, e.g. decade=1990s")
+	size := fs.Int("size", 20, "print at most `n` hits")
+	format := fs.String("format", "text", "output `format`: text or json")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	q := motospec.SearchQuery{Text: strings.Join(fs.Args(), " "), Filters: make(map[string]string), Size: *size}
+	for _, facet := range facets {
+		parts := strings.SplitN(facet, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("-facet %q: want name=value", facet)
+		}
+		q.Filters[parts[0]] = parts[1]
+	}
+	index, err := motospec.LoadSearchIndex(*indexFile)
+	if err != nil {
+		return err
+	}
+	result := index.Search(q)
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	for _, hit := range result.Hits {
+		fmt.Printf("%6.2f  %s %s %s (%s)\n", hit.Score, hit.Brand, hit.Moto, hit.Year, hit.ID)
+	}
+	fmt.Printf("%d hits\n", result.Total)
+	for _, name := range motospec.Facets {
+		values := []string{}
+		for _, count := range result.Facets[name] {
+			values = append(values, fmt.Sprintf("%s (%d)", count.Value, count.Count))
+		}
+		if len(values) > 0 {
+			fmt.Printf("  %s: %s\n", name, strings.Join(values, ", "))
+		}
+	}
+	return nil
+}
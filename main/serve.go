@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"motospec"
+	"net/http"
+)
+
+func serveCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "listen on `address`")
+	specs := fs.String("specs", "motospecs.json", "serve specs from `file`")
+	brands := fs.String("brands", "brands.json", "serve brand records from `file`")
+	models := fs.String("models", "models.json", "serve model records from `file`")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	api, err := motospec.NewAPI(*specs, *brands, *models)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/brands", api)
+	mux.Handle("/brands/", api)
+	mux.Handle("/specs", api)
+	mux.Handle("/specs/", api)
+	mux.Handle("/search", api)
+	mux.Handle("/compare", api)
+	mux.HandleFunc("/graphql", api.ServeGraphQL)
+	// the raw files, as served before the API
+	for path, file := range map[string]string{"/specs.json": *specs, "/brands.json": *brands, "/models.json": *models} {
+		file := file
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			http.ServeFile(w, r, file)
+		})
+	}
+	fmt.Printf("serving on %s\n", *addr)
+	return http.ListenAndServe(*addr, mux)
+}
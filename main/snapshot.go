@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"motospec"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func printSnapshot(s motospec.Snapshot) {
+	partial := ""
+	if s.Partial {
+		partial = " (partial)"
+	}
+	fmt.Printf("snapshot %s%s: %d specs, %d added, %d changed, %d removed\n", s.ID, partial, s.Specs, s.Added, s.Changed, s.Removed)
+}
+
+func snapshotCommand(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	dir := fs.String("dir", "snapshots", "keep snapshots in `dir`")
+	partial := fs.Bool("partial", false, "the file covers part of the site only, remove no variant")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	store, err := motospec.OpenSnapshotStore(*dir)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "id\ttime\tspecs\tadded\tchanged\tremoved\t")
+		for _, s := range store.Snapshots() {
+			id := s.ID
+			if s.Partial {
+				id += " (partial)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t\n", id, s.Time.Format(time.RFC3339), s.Specs, s.Added, s.Changed, s.Removed)
+		}
+		return w.Flush()
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: motospec snapshot [flags] [motospecs.json]")
+	}
+	specs, err := motospec.LoadSpecs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	snapshot, err := store.Commit(specs, time.Now(), *partial)
+	if err != nil {
+		return err
+	}
+	printSnapshot(snapshot)
+	return nil
+}
+
+func historyCommand(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	dir := fs.String("dir", "snapshots", "read snapshots from `dir`")
+	field := fs.String("field", "", "the `field` to follow: brand, model, type, year, description or a spec name")
+	format := fs.String("format", "text", "output `format`: text or json")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *field == "" {
+		return fmt.Errorf("usage: motospec history -field <field> [flags] <variant url or id>")
+	}
+	store, err := motospec.OpenSnapshotStore(*dir)
+	if err != nil {
+		return err
+	}
+	versions, err := store.History(fs.Arg(0), *field)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("no variant %s in %s", fs.Arg(0), *dir)
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, version := range versions {
+			if err := encoder.Encode(version); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, version := range versions {
+		value := fmt.Sprintf("%q", version.Value)
+		if version.Removed {
+			value = "removed"
+		}
+		fmt.Printf("%s %s %s\n", version.Snapshot, version.Time.Format(time.RFC3339), value)
+	}
+	return nil
+}
+
+func emitSnapshot(emitter *motospec.EventEmitter, store *motospec.SnapshotStore, snapshot motospec.Snapshot) error {
+	changes, err := store.Changes(snapshot.ID)
+	if err != nil {
+		return err
+	}
+	sent, err := emitter.Emit(motospec.SnapshotEvents(snapshot, changes))
+	fmt.Printf("%d events sent\n", sent)
+	return err
+}
+
+func notifyCommand(args []string) error {
+	fs := flag.NewFlagSet("notify", flag.ExitOnError)
+	configFile := fs.String("config", "", "read the events section from `file`")
+	profile := fs.String("profile", "", "apply the named `profile` of the config file")
+	dir := fs.String("dir", "snapshots", "read snapshots from `dir`")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *configFile == "" || fs.NArg() > 1 {
+		return fmt.Errorf("usage: motospec notify -config <file> [flags] [snapshot id]")
+	}
+	config, err := motospec.LoadConfig(*configFile, *profile)
+	if err != nil {
+		return err
+	}
+	emitter, err := config.Events.Open()
+	if err != nil {
+		return err
+	}
+	if emitter == nil {
+		return fmt.Errorf("%s: no events sinks configured", *configFile)
+	}
+	defer emitter.Close()
+	store, err := motospec.OpenSnapshotStore(*dir)
+	if err != nil {
+		return err
+	}
+	id := "latest"
+	if fs.NArg() == 1 {
+		id = fs.Arg(0)
+	}
+	snapshot, _, err := store.Snapshot(id)
+	if err != nil {
+		return err
+	}
+	return emitSnapshot(emitter, store, snapshot)
+}
@@ -0,0 +1,243 @@
+package motospec
+
+import (
+	"fmt"
+	"net/url"
+	"notbearparser"
+	"strings"
+)
+
+// motorcycle.com has no page per model. Its spec search is a form of
+// chained selects, make -> type -> year -> trim, each filled from the
+// options endpoint, and the specs are the answer to POSTing the form.
+const (
+	DefaultMotorcycleOptions = "http://www.motorcycle.com/specs/?step={step}&MakeId={make}&ModelType={type}&year={year}"
+	DefaultMotorcycleSpecs   = "http://www.motorcycle.com/specs/"
+)
+
+type MakeOption struct {
+	Name  string
+	Value string
+}
+
+func (o MakeOption) String() string {
+	return "motorcycle.com make " + o.Name
+}
+
+type CategoryOption struct {
+	Make  MakeOption
+	Name  string
+	Value string
+}
+
+func (o CategoryOption) String() string {
+	return fmt.Sprintf("motorcycle.com %s %s", o.Make.Name, o.Name)
+}
+
+type YearOption struct {
+	Category CategoryOption
+	Name     string
+	Value    string
+}
+
+func (o YearOption) String() string {
+	return fmt.Sprintf("motorcycle.com %s %s %s", o.Category.Make.Name, o.Category.Name, o.Name)
+}
+
+type TrimOption struct {
+	Year  YearOption
+	Name  string
+	Value string
+}
+
+func (o TrimOption) String() string {
+	return fmt.Sprintf("motorcycle.com %s %s %s %s", o.Year.Category.Make.Name, o.Year.Category.Name, o.Year.Name, o.Name)
+}
+
+type motorcycleCom struct{}
+
+func (motorcycleCom) Name() string {
+	return "motorcycle.com"
+}
+
+func (motorcycleCom) Stages() []string {
+	return []string{"make", "category", "year", "trim", "spec"}
+}
+
+func (motorcycleCom) Inputs() []interface{} {
+	return []interface{}{"", MakeOption{}, CategoryOption{}, YearOption{}, TrimOption{}}
+}
+
+func (motorcycleCom) ParseSeed(rawURL string) (Seed, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Seed{}, err
+	}
+	if u.Host == "" || strings.Trim(u.Path, "/") != "specs" {
+		return Seed{}, fmt.Errorf("%s is not the motorcycle.com spec search", rawURL)
+	}
+	return Seed{Stage: 0, Item: rawURL}, nil
+}
+
+func (motorcycleCom) StageFunc(stage string, config *Config) (ProcessFunc, error) {
+	site := config.CurrentSite()
+	options := site.Endpoints["options"]
+	if options == "" {
+		options = DefaultMotorcycleOptions
+	}
+	specs := site.Endpoints["specs"]
+	if specs == "" {
+		specs = DefaultMotorcycleSpecs
+	}
+	optionURL := func(step, makeID, typeID, yearID string) string {
+		return strings.NewReplacer(
+			"{step}", step,
+			"{make}", url.QueryEscape(makeID),
+			"{type}", url.QueryEscape(typeID),
+			"{year}", url.QueryEscape(yearID),
+		).Replace(options)
+	}
+	switch stage {
+	case "make":
+		return func(p *Processor, input interface{}) {
+			if _, ok := input.(string); !ok {
+				p.Error <- fmt.Errorf("%v is not valid url string", input)
+				return
+			}
+			u := optionURL("0", "", "", "")
+			p.forOptions(input, u, func(name, value string) {
+				if p.Filter.AllowBrand(name) {
+					p.Output <- MakeOption{Name: name, Value: value}
+				}
+			})
+		}, nil
+	case "category":
+		return func(p *Processor, input interface{}) {
+			maker, ok := input.(MakeOption)
+			if !ok {
+				p.Error <- fmt.Errorf("%v is not a valid MakeOption", input)
+				return
+			}
+			u := optionURL("1", maker.Value, "", "")
+			p.forOptions(maker, u, func(name, value string) {
+				p.Output <- CategoryOption{Make: maker, Name: name, Value: value}
+			})
+		}, nil
+	case "year":
+		return func(p *Processor, input interface{}) {
+			category, ok := input.(CategoryOption)
+			if !ok {
+				p.Error <- fmt.Errorf("%v is not a valid CategoryOption", input)
+				return
+			}
+			u := optionURL("2", category.Make.Value, category.Value, "")
+			p.forOptions(category, u, func(name, value string) {
+				if p.Filter.AllowYears(name) {
+					p.Output <- YearOption{Category: category, Name: name, Value: value}
+				}
+			})
+		}, nil
+	case "trim":
+		return func(p *Processor, input interface{}) {
+			year, ok := input.(YearOption)
+			if !ok {
+				p.Error <- fmt.Errorf("%v is not a valid YearOption", input)
+				return
+			}
+			u := optionURL("3", year.Category.Make.Value, year.Category.Value, year.Value)
+			p.forOptions(year, u, func(name, value string) {
+				if p.Filter.AllowModel(name) {
+					p.Output <- TrimOption{Year: year, Name: name, Value: value}
+				}
+			})
+		}, nil
+	case "spec":
+		return func(p *Processor, input interface{}) {
+			trim, ok := input.(TrimOption)
+			if !ok {
+				p.Error <- fmt.Errorf("%v is not a valid TrimOption", input)
+				return
+			}
+			ProcessorLogger.Printf("Spec Processor: IN %s", trim)
+			req, err := p.NewFormRequest(specs, url.Values{
+				"MakeId":      {trim.Year.Category.Make.Value},
+				"ModelType":   {trim.Year.Category.Value},
+				"year":        {trim.Year.Value},
+				"TrimId":      {trim.Value},
+				"get_specs.x": {"94"},
+				"get_specs.y": {"14"},
+			})
+			if err != nil {
+				p.Fail(trim, err)
+				return
+			}
+			cells, err := p.Search(req, p.Selectors.SpecCell)
+			if err != nil {
+				p.Fail(trim, err)
+				return
+			}
+			if len(cells) < 2 {
+				p.Fail(trim, fmt.Errorf("%s has no spec table", trim))
+				return
+			}
+			spec := Spec{
+				Source: "motorcycle.com",
+				Brand:  trim.Year.Category.Make.Name,
+				Model:  trim.Name,
+				Moto:   trim.Name,
+				Year:   trim.Year.Name,
+				Specs:  make(map[string]string),
+			}
+			spec.Segment = trim.Year.Category.Name
+			for i := 0; i+1 < len(cells); i += 2 {
+				key := strings.Trim(cells[i].Content, ": \n\r\t")
+				if key != "" {
+					spec.Specs[key] = strings.TrimSpace(cells[i+1].Content)
+				}
+			}
+			p.Output <- spec
+			ProcessorLogger.Printf("Spec Processor: OUT %s", trim)
+		}, nil
+	}
+	return nil, fmt.Errorf("motorcycle.com has no stage %q", stage)
+}
+
+// forOptions fetches a select of the spec search form and calls fn with
+// every option carrying a value.
+func (p *Processor) forOptions(input interface{}, u string, fn func(name, value string)) {
+	ProcessorLogger.Printf("Option Processor: IN %s", u)
+	req, err := p.NewRequest("GET", u)
+	if err != nil {
+		p.Fail(input, err)
+		return
+	}
+	options, err := p.Search(req, p.Selectors.Option)
+	if err != nil {
+		p.Fail(input, err)
+		return
+	}
+	found := 0
+	for _, option := range options {
+		select {
+		case <-p.Ctx.Done():
+			return
+		default:
+		}
+		values, ok := option.Attrs.Get("value")
+		if !ok || values[0] == "" {
+			continue
+		}
+		found++
+		fn(strings.Trim(optionText(option), "\n\r "), values[0])
+	}
+	if found == 0 {
+		p.Fail(input, fmt.Errorf("%s has no options", u))
+	}
+}
+
+func optionText(node *notbearparser.Node) string {
+	if node.Content != "" || len(node.Children) == 0 {
+		return node.Content
+	}
+	return node.Children[0].Content
+}
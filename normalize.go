@@ -0,0 +1,179 @@
+package motospec
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Normalized holds the fields of a Spec that compare across sources: brand
+// and model folded to lower case words, the production years and the key
+// numbers in fixed units.
+type Normalized struct {
+	Brand        string  `json:"brand"`
+	Model        string  `json:"model"`
+	YearFrom     int     `json:"year_from,omitempty"`
+	YearTo       int     `json:"year_to,omitempty"`
+	Displacement float64 `json:"displacement_cc,omitempty"`
+	Power        float64 `json:"power_hp,omitempty"`
+	Weight       float64 `json:"weight_kg,omitempty"`
+}
+
+// BrandAliases maps the normalized spellings of a brand onto one name.
+var BrandAliases = map[string]string{
+	"harley":         "harleydavidson",
+	"hd":             "harleydavidson",
+	"mv":             "mvagusta",
+	"bmwmotorrad":    "bmw",
+	"enfield":        "royalenfield",
+	"guzzi":          "motoguzzi",
+	"kawasakimotors": "kawasaki",
+}
+
+var brandNoise = map[string]bool{
+	"motorcycles": true,
+	"motorcycle":  true,
+	"motors":      true,
+	"motor":       true,
+	"company":     true,
+	"co":          true,
+	"inc":         true,
+	"ltd":         true,
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	})
+}
+
+func NormalizeBrand(brand string) string {
+	kept := []string{}
+	for _, word := range words(brand) {
+		if !brandNoise[word] {
+			kept = append(kept, word)
+		}
+	}
+	name := strings.Join(kept, "")
+	if alias, ok := BrandAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// NormalizeModel drops the brand from the model name, which some sources
+// repeat, and keeps the remaining words in lower case.
+func NormalizeModel(brand, model string) string {
+	brandWords := make(map[string]bool)
+	for _, word := range words(brand) {
+		brandWords[word] = true
+	}
+	kept := []string{}
+	for _, word := range words(model) {
+		if !brandWords[word] {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+type unit struct {
+	name   string
+	factor float64
+}
+
+var (
+	quantityPattern = regexp.MustCompile(`(\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:[.,]\d+)?)\s*([a-z][a-z0-9]*)`)
+	groupedPattern  = regexp.MustCompile(`^\d{1,3}(?:,\d{3})+(?:\.\d+)?$`)
+	tagPattern      = regexp.MustCompile(`<[^>]*>`)
+
+	displacementUnits = []unit{{"cc", 1}, {"cm3", 1}, {"ccm", 1}, {"cu", 16.387}, {"ci", 16.387}}
+	powerUnits        = []unit{{"hp", 1}, {"bhp", 1}, {"ps", 0.9863}, {"cv", 0.9863}, {"kw", 1.341}}
+	weightUnits       = []unit{{"kg", 1}, {"kgs", 1}, {"lbs", 0.4536}, {"lb", 0.4536}}
+)
+
+// parseQuantity finds a number followed by one of units in value and
+// converts it, the units earlier in the list being preferred when value
+// gives several, e.g. "364 lbs OR 165 kg".
+func parseQuantity(value string, units []unit) (float64, bool) {
+	value = strings.ToLower(tagPattern.ReplaceAllString(value, " "))
+	found := make(map[string]float64)
+	for _, m := range quantityPattern.FindAllStringSubmatch(value, -1) {
+		n, err := parseNumber(m[1])
+		if err != nil {
+			continue
+		}
+		if _, ok := found[m[2]]; !ok {
+			found[m[2]] = n
+		}
+	}
+	for _, u := range units {
+		if n, ok := found[u.name]; ok && n > 0 {
+			return n * u.factor, true
+		}
+	}
+	return 0, false
+}
+
+// parseNumber reads a comma followed by three digits as a thousands
+// separator, "1,200", and any other comma as a decimal one, "165,5".
+func parseNumber(s string) (float64, error) {
+	if groupedPattern.MatchString(s) {
+		return strconv.ParseFloat(strings.Replace(s, ",", "", -1), 64)
+	}
+	return strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+}
+
+// parsePower also reads the "16/5600 KW(hp)/RPM" layout of autoevolution,
+// power and rpm ahead of the units.
+func parsePower(value string) (float64, bool) {
+	lower := strings.ToLower(value)
+	if i := strings.Index(lower, "/"); i > 0 && strings.Contains(lower, "(hp)") {
+		if n, err := strconv.ParseFloat(strings.TrimSpace(lower[:i]), 64); err == nil && n > 0 {
+			return n, true
+		}
+	}
+	return parseQuantity(value, powerUnits)
+}
+
+// SpecValue returns the spec named like one of names, or else the first one,
+// in key order, whose name contains it. The names are tried in order.
+func SpecValue(spec Spec, names ...string) string {
+	keys := make([]string, 0, len(spec.Specs))
+	for key := range spec.Specs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, name := range names {
+		for _, key := range keys {
+			if strings.EqualFold(strings.Trim(key, ": "), name) {
+				return spec.Specs[key]
+			}
+		}
+		for _, key := range keys {
+			if strings.Contains(strings.ToLower(key), name) {
+				return spec.Specs[key]
+			}
+		}
+	}
+	return ""
+}
+
+func Normalize(spec Spec) Normalized {
+	model := spec.Moto
+	if model == "" {
+		model = spec.Model
+	}
+	n := Normalized{
+		Brand: NormalizeBrand(spec.Brand),
+		Model: NormalizeModel(spec.Brand, model),
+	}
+	if from, to, ok := ParseYears(spec.Year); ok {
+		n.YearFrom, n.YearTo = from, to
+	}
+	n.Displacement, _ = parseQuantity(SpecValue(spec, "displacement", "engine size"), displacementUnits)
+	n.Power, _ = parsePower(SpecValue(spec, "horsepower", "power"))
+	n.Weight, _ = parseQuantity(SpecValue(spec, "wet weight", "weight"), weightUnits)
+	return n
+}
@@ -0,0 +1,88 @@
+package motospec
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeBrand(t *testing.T) {
+	tests := []struct {
+		brand, want string
+	}{
+		{"Ducati", "ducati"},
+		{"Harley-Davidson", "harleydavidson"},
+		{"Harley", "harleydavidson"},
+		{"BMW Motorrad", "bmw"},
+		{"MV Agusta", "mvagusta"},
+		{"Indian Motorcycle Company", "indian"},
+		{"Royal Enfield", "royalenfield"},
+	}
+	for _, test := range tests {
+		if got := NormalizeBrand(test.brand); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.brand, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeModel(t *testing.T) {
+	tests := []struct {
+		brand, model, want string
+	}{
+		{"Ducati", "Ducati Monster 821", "monster 821"},
+		{"Ducati", "Monster 821", "monster 821"},
+		{"Harley-Davidson", "Harley-Davidson Fat Boy", "fat boy"},
+		{"BMW", "R1250GS Adventure", "r1250gs adventure"},
+	}
+	for _, test := range tests {
+		if got := NormalizeModel(test.brand, test.model); got != test.want {
+			t.Errorf("%q %q: got %q, want %q", test.brand, test.model, got, test.want)
+		}
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		value string
+		units []unit
+		want  float64
+		ok    bool
+	}{
+		{"803 cc", displacementUnits, 803, true},
+		{"48.9 cu in OR 803 cm3", displacementUnits, 803, true},
+		{"364 lbs OR 165 kg", weightUnits, 165, true},
+		{"364 lbs", weightUnits, 165.1, true},
+		{"165,5 kg", weightUnits, 165.5, true},
+		{"1,200 cc", displacementUnits, 1200, true},
+		{"1,000 kg", weightUnits, 1000, true},
+		{"1,234.5 cc", displacementUnits, 1234.5, true},
+		{"1,2345 cc", displacementUnits, 1.2345, true},
+		{"<b>73</b> hp", powerUnits, 73, true},
+		{"54 kW", powerUnits, 72.4, true},
+		{"0 kg", weightUnits, 0, false},
+		{"-", weightUnits, 0, false},
+	}
+	for _, test := range tests {
+		got, ok := parseQuantity(test.value, test.units)
+		if ok != test.ok || math.Abs(got-test.want) > 0.05 {
+			t.Errorf("%q: got %g %v, want %g %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	spec := Spec{
+		Brand: "Ducati",
+		Model: "Ducati Monster",
+		Moto:  "Ducati Monster 821",
+		Year:  "2014 - 2017",
+		Specs: map[string]string{
+			"Displacement": "821 cc",
+			"Power":        "82/9250 KW(hp)/RPM",
+			"Weight":       "393 lbs OR 179 kg",
+		},
+	}
+	want := Normalized{Brand: "ducati", Model: "monster 821", YearFrom: 2014, YearTo: 2017, Displacement: 821, Power: 82, Weight: 179}
+	if got := Normalize(spec); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package motospec
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"notbearparser"
+	"strconv"
+	"strings"
+)
+
+const DefaultMaxPages = 100
+
+// Pagination tells a listing stage how to reach the pages after the first:
+// either through the link matched by Next, or by filling Template, e.g.
+// "{url}?page={page}", with page numbers counting up from Start.
+type Pagination struct {
+	Next     string `yaml:"next"`
+	Template string `yaml:"template"`
+	Start    int    `yaml:"start"`
+	MaxPages int    `yaml:"max_pages"`
+}
+
+func (pg *Pagination) Validate() []string {
+	var errs []string
+	switch {
+	case pg.Next == "" && pg.Template == "":
+		errs = append(errs, "one of next and template is required")
+	case pg.Next != "" && pg.Template != "":
+		errs = append(errs, "next and template exclude each other")
+	case pg.Template != "" && !strings.Contains(pg.Template, "{page}"):
+		errs = append(errs, "template: has no {page} placeholder")
+	}
+	if pg.Start < 0 {
+		errs = append(errs, "start: must not be negative")
+	}
+	if pg.MaxPages < 0 {
+		errs = append(errs, "max_pages: must not be negative")
+	}
+	return errs
+}
+
+func (pg *Pagination) maxPages() int {
+	if pg.MaxPages == 0 {
+		return DefaultMaxPages
+	}
+	return pg.MaxPages
+}
+
+// nextURL returns the url of page n (n >= 2) of the listing at first, page
+// n-1 being current, or "" when there is none.
+func (pg *Pagination) nextURL(first, current string, page *notbearparser.Node, n int) string {
+	if pg.Template != "" {
+		start := pg.Start
+		if start == 0 {
+			start = 2
+		}
+		return strings.NewReplacer("{url}", first, "{page}", strconv.Itoa(start+n-2)).Replace(pg.Template)
+	}
+	href := firstAttr(page, pg.Next, "href")
+	if href == "" {
+		return ""
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func pageKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
+
+// fingerprint identifies a page by the items it lists, so a site serving
+// its last page for any page number past the end is noticed.
+func fingerprint(nodes []*notbearparser.Node) string {
+	var b bytes.Buffer
+	var walk func(*notbearparser.Node)
+	walk = func(node *notbearparser.Node) {
+		b.WriteString(node.Content)
+		if hrefs, ok := node.Attrs.Get("href"); ok {
+			b.WriteString(hrefs[0])
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	for _, node := range nodes {
+		walk(node)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func (p *Processor) fetchURL(rawURL string) (*notbearparser.Node, error) {
+	req, err := p.NewRequest("GET", rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return p.Fetch(req)
+}
+
+// SearchPages fetches the listing at rawURL and, when the processor has
+// Paging, every page after it. It returns the first page and the nodes
+// matching query on all pages. Only a failing first page is an error; a
+// later page failing ends the listing there and is reported on p.Error.
+func (p *Processor) SearchPages(rawURL, query string) (*notbearparser.Node, []*notbearparser.Node, error) {
+	root, err := p.fetchURL(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes, err := notbearparser.Search(root, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	pg := p.Paging
+	if pg == nil {
+		return root, nodes, nil
+	}
+	seen := map[string]bool{pageKey(rawURL): true}
+	prints := map[string]bool{fingerprint(nodes): true}
+	current, page := rawURL, root
+	for n := 2; ; n++ {
+		next := pg.nextURL(rawURL, current, page, n)
+		if next == "" {
+			break
+		}
+		if n > pg.maxPages() {
+			ProcessorLogger.Printf("Pagination: %s stopped at max_pages %d", rawURL, pg.maxPages())
+			break
+		}
+		if seen[pageKey(next)] {
+			ProcessorLogger.Printf("Pagination: %s page %d loops back to %s", rawURL, n, next)
+			break
+		}
+		seen[pageKey(next)] = true
+		if page, err = p.fetchURL(next); err != nil {
+			p.Error <- fmt.Errorf("%s page %d: %v", rawURL, n, err)
+			break
+		}
+		found, err := notbearparser.Search(page, query)
+		if err != nil || len(found) == 0 {
+			break
+		}
+		fp := fingerprint(found)
+		if prints[fp] {
+			ProcessorLogger.Printf("Pagination: %s page %d repeats an earlier page", rawURL, n)
+			break
+		}
+		prints[fp] = true
+		ProcessorLogger.Printf("Pagination: %s page %d has %d items", rawURL, n, len(found))
+		nodes = append(nodes, found...)
+		current = next
+	}
+	return root, nodes, nil
+}
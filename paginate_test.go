@@ -0,0 +1,62 @@
+package motospec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPaginationValidate(t *testing.T) {
+	tests := []struct {
+		paging Pagination
+		want   string
+	}{
+		{Pagination{Next: "a.next"}, ""},
+		{Pagination{Template: "{url}?page={page}", Start: 1, MaxPages: 20}, ""},
+		{Pagination{}, "one of next and template is required"},
+		{Pagination{Next: "a.next", Template: "{url}?page={page}"}, "next and template exclude each other"},
+		{Pagination{Template: "{url}?page=2"}, "template: has no {page} placeholder"},
+		{Pagination{Next: "a.next", Start: -1, MaxPages: -1}, "start: must not be negative; max_pages: must not be negative"},
+	}
+	for _, test := range tests {
+		if got := strings.Join(test.paging.Validate(), "; "); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.paging, got, test.want)
+		}
+	}
+}
+
+func TestPaginationTemplate(t *testing.T) {
+	const first = "https://www.motorcycle.com/specs/ducati"
+	tests := []struct {
+		paging Pagination
+		n      int
+		want   string
+	}{
+		{Pagination{Template: "{url}?page={page}"}, 2, first + "?page=2"},
+		{Pagination{Template: "{url}?page={page}"}, 3, first + "?page=3"},
+		{Pagination{Template: "{url}/p/{page}", Start: 1}, 2, first + "/p/1"},
+		{Pagination{Template: "{url}?offset={page}0", Start: 1}, 4, first + "?offset=30"},
+	}
+	for _, test := range tests {
+		if got := test.paging.nextURL(first, "", nil, test.n); got != test.want {
+			t.Errorf("%s page %d: got %s, want %s", test.paging.Template, test.n, got, test.want)
+		}
+	}
+	if got := (&Pagination{}).maxPages(); got != DefaultMaxPages {
+		t.Errorf("default max pages %d", got)
+	}
+}
+
+func TestPageKey(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"https://example.com/moto/ducati/", "https://example.com/moto/ducati"},
+		{"https://example.com/moto/ducati#models", "https://example.com/moto/ducati"},
+		{"https://example.com/moto/ducati/?page=2", "https://example.com/moto/ducati?page=2"},
+	}
+	for _, test := range tests {
+		if got := pageKey(test.url); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.url, got, test.want)
+		}
+	}
+}
@@ -2,17 +2,61 @@ package motospec
 
 import (
 	"context"
+	"fmt"
 	"sync"
 )
 
+type stage struct {
+	input   chan interface{}
+	mutex   sync.Mutex
+	feeders int
+	closed  bool
+}
+
+func (s *stage) addFeeder() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return false
+	}
+	s.feeders++
+	return true
+}
+
+func (s *stage) feederDone() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.feeders--
+	if s.feeders == 0 {
+		s.closed = true
+		close(s.input)
+	}
+}
+
 type Pipeline struct {
 	ProcessorList []*Processor
 	Ctx           context.Context
 	Done          chan struct{}
 	Input         chan interface{}
 	Output        chan interface{}
+	Entities      chan interface{}
 	Error         chan error
 	WG            sync.WaitGroup
+
+	// FailureSink and QuarantineSink, when set before Run, receive the
+	// failed inputs and the quarantined specs of the run.
+	FailureSink    Sink
+	QuarantineSink Sink
+	// Failed and Quarantined count the errors, robots skips aside, and the
+	// quarantined specs of the run, once Done is closed.
+	Failed      int
+	Quarantined int
+	// Missed holds the failed inputs and the quarantined specs of the run,
+	// once Done is closed.
+	Missed Missed
+
+	stages     []*stage
+	errHandled chan struct{}
 }
 
 func NewPipeline(ctx context.Context, pfList []ProcessFunc, interval int) *Pipeline {
@@ -21,18 +65,108 @@ func NewPipeline(ctx context.Context, pfList []ProcessFunc, interval int) *Pipel
 		Ctx:           ctx,
 		Done:          make(chan struct{}),
 		Input:         make(chan interface{}),
+		Entities:      make(chan interface{}),
 		Error:         make(chan error),
 	}
-	firstProcessor := NewProcessor(pipeLine.Input, pipeLine.Error, ctx, pfList[0], interval)
-	pipeLine.ProcessorList = append(pipeLine.ProcessorList, firstProcessor)
-	for _, pf := range pfList[1:] {
-		processor := NewProcessor(pipeLine.ProcessorList[len(pipeLine.ProcessorList)-1].Output, pipeLine.Error, ctx, pf, interval)
+	upstream := pipeLine.Input
+	for _, pf := range pfList {
+		st := &stage{input: make(chan interface{})}
+		st.addFeeder()
+		go pipeLine.feed(st, upstream)
+		processor := NewProcessor(st.input, pipeLine.Error, ctx, pf, interval)
+		processor.Entity = pipeLine.Entities
 		pipeLine.ProcessorList = append(pipeLine.ProcessorList, processor)
+		pipeLine.stages = append(pipeLine.stages, st)
+		upstream = processor.Output
 	}
-	pipeLine.Output = pipeLine.ProcessorList[len(pipeLine.ProcessorList)-1].Output
+	pipeLine.Output = upstream
 	return pipeLine
 }
 
+// Append adds a processor running pf on the output of the last stage, such
+// as the post-processing of specs. It must be called before Run.
+func (pl *Pipeline) Append(pf ProcessFunc) *Processor {
+	st := &stage{input: make(chan interface{})}
+	st.addFeeder()
+	go pl.feed(st, pl.Output)
+	processor := NewProcessor(st.input, pl.Error, pl.Ctx, pf, 0)
+	processor.Entity = pl.Entities
+	pl.ProcessorList = append(pl.ProcessorList, processor)
+	pl.stages = append(pl.stages, st)
+	pl.Output = processor.Output
+	return processor
+}
+
+// feed forwards src into the stage until src is closed. Once the pipeline
+// is cancelled the stage stops waiting for src, but src is still drained so
+// whoever writes to it is not left blocked.
+func (pl *Pipeline) feed(st *stage, src <-chan interface{}) {
+	for {
+		select {
+		case <-pl.Ctx.Done():
+			st.feederDone()
+			for range src {
+			}
+			return
+		case item, ok := <-src:
+			if !ok {
+				st.feederDone()
+				return
+			}
+			select {
+			case st.input <- item:
+			case <-pl.Ctx.Done():
+			}
+		}
+	}
+}
+
+// Inject returns a channel feeding the processor at index stage directly.
+// The stage is closed only after its upstream and every injector channel
+// have been closed, so callers must close the returned channel when done.
+func (pl *Pipeline) Inject(stage int) (chan<- interface{}, error) {
+	if stage < 0 || stage >= len(pl.stages) {
+		return nil, fmt.Errorf("pipeline has no stage %d", stage)
+	}
+	st := pl.stages[stage]
+	if !st.addFeeder() {
+		return nil, fmt.Errorf("stage %d is already closed", stage)
+	}
+	injector := make(chan interface{})
+	go pl.feed(st, injector)
+	return injector, nil
+}
+
+func (pl *Pipeline) SetFilter(filter *Filter) {
+	for _, processor := range pl.ProcessorList {
+		processor.Filter = filter
+	}
+}
+
+func (pl *Pipeline) SetSession(session *Session) {
+	for _, processor := range pl.ProcessorList {
+		processor.Session = session
+	}
+}
+
+func (pl *Pipeline) SetRobots(robots *Robots) {
+	for _, processor := range pl.ProcessorList {
+		processor.Robots = robots
+	}
+}
+
+func (pl *Pipeline) SetLimiter(limiter *RateLimiter) {
+	for _, processor := range pl.ProcessorList {
+		processor.Limiter = limiter
+	}
+}
+
+func (pl *Pipeline) SetWorkers(workers int) {
+	for _, processor := range pl.ProcessorList {
+		processor.Workers = workers
+	}
+}
+
 func (pl *Pipeline) Close() {
 	for _, processor := range pl.ProcessorList {
 		go func(p *Processor) {
@@ -41,7 +175,9 @@ func (pl *Pipeline) Close() {
 		}(processor)
 	}
 	pl.WG.Wait()
+	close(pl.Entities)
 	close(pl.Error)
+	<-pl.errHandled
 	close(pl.Done)
 }
 
@@ -50,6 +186,10 @@ func (pl *Pipeline) Run() {
 		go processor.Run()
 		pl.WG.Add(1)
 	}
-	go HandleError(pl.Error)
+	pl.errHandled = make(chan struct{})
+	go func() {
+		pl.Failed, pl.Quarantined = HandleError(pl.Error, pl.FailureSink, pl.QuarantineSink, &pl.Missed)
+		close(pl.errHandled)
+	}()
 	pl.Close()
 }
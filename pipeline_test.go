@@ -0,0 +1,51 @@
+package motospec
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// prefixFunc passes its input on with prefix in front.
+func prefixFunc(prefix string) ProcessFunc {
+	return func(p *Processor, input interface{}) {
+		p.Output <- prefix + input.(string)
+	}
+}
+
+func TestPipelineInject(t *testing.T) {
+	pipeline := NewPipeline(context.Background(), []ProcessFunc{prefixFunc("a:"), prefixFunc("b:")}, 0)
+	session, err := NewSession("test", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline.SetSession(session)
+	if _, err := pipeline.Inject(2); err == nil {
+		t.Error("injected into a stage the pipeline does not have")
+	}
+	injector, err := pipeline.Inject(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		pipeline.Input <- "x"
+		close(pipeline.Input)
+		injector <- "y"
+		injector <- "z"
+		close(injector)
+	}()
+	go pipeline.Run()
+	got := []string{}
+	for output := range pipeline.Output {
+		got = append(got, output.(string))
+	}
+	<-pipeline.Done
+	sort.Strings(got)
+	if strings.Join(got, ",") != "b:a:x,b:y,b:z" {
+		t.Errorf("got %q", got)
+	}
+	if _, err := pipeline.Inject(1); err == nil {
+		t.Error("injected into a closed stage")
+	}
+}
@@ -5,49 +5,123 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	neturl "net/url"
 	"notbearclient"
 	"notbearparser"
+	"strings"
+	"sync"
 	"time"
 )
 
 type ProcessFunc func(*Processor, interface{})
 
 type Processor struct {
-	Client  *notbearclient.Client
-	Input   chan interface{}
-	Output  chan interface{}
-	Error   chan error
-	Done    chan struct{}
-	Ctx     context.Context
-	Process ProcessFunc
+	Client    *notbearclient.Client
+	Input     chan interface{}
+	Output    chan interface{}
+	Error     chan error
+	Entity    chan interface{}
+	Done      chan struct{}
+	Ctx       context.Context
+	Process   ProcessFunc
+	Filter    *Filter
+	Selectors *Selectors
+	Header    string
+	Headers   *HeaderProfile
+	Session   *Session
+	Robots    *Robots
+	Limiter   *RateLimiter
+	Paging    *Pagination
 
 	Interval int
+	Workers  int
+	Retries  int
+	Timeout  int
+
+	clients []*notbearclient.Client
 }
 
 func NewProcessor(input chan interface{}, errChan chan error, ctx context.Context, processFunc ProcessFunc, interval int) *Processor {
-	client := notbearclient.NewClient(3, 10, ctx, errChan)
 	return &Processor{
-		Client:  client,
-		Input:   input,
-		Output:  make(chan interface{}),
-		Error:   errChan,
-		Done:    make(chan struct{}),
-		Ctx:     ctx,
-		Process: processFunc,
+		Input:     input,
+		Output:    make(chan interface{}),
+		Error:     errChan,
+		Done:      make(chan struct{}),
+		Ctx:       ctx,
+		Process:   processFunc,
+		Selectors: &DefaultSelectors,
+		Header:    "motoSpecHeader",
 
 		Interval: interval,
+		Workers:  1,
+		Retries:  3,
+		Timeout:  10,
 	}
 }
 
-func (p *Processor) Search(req *http.Request, query string) ([]*notbearparser.Node, error) {
-	p.Client.Input <- req
-	html := <-p.Client.Output
-	parser := notbearparser.NewCursor(html)
+// NewRequest builds a request with the processor's header profile. Without
+// a profile the named header set of notbearclient's settings file is used.
+func (p *Processor) NewRequest(method, url string) (*http.Request, error) {
+	if p.Headers == nil {
+		return notbearclient.NewRequest(method, url, "", p.Header, map[string][]string{})
+	}
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	p.Headers.Apply(req)
+	ProcessorLogger.Printf("Request: %s %s with header profile %s (%s)", method, url, p.Headers.Name, req.Header.Get("User-Agent"))
+	return req, nil
+}
+
+// NewFormRequest builds a POST of form with the processor's header profile.
+func (p *Processor) NewFormRequest(url string, form neturl.Values) (*http.Request, error) {
+	if p.Headers == nil {
+		return notbearclient.NewRequest("POST", url, "application/x-www-form-urlencoded", p.Header, form)
+	}
+	req, err := http.NewRequest("POST", url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	p.Headers.Apply(req)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ProcessorLogger.Printf("Request: POST %s with header profile %s (%s)", url, p.Headers.Name, req.Header.Get("User-Agent"))
+	return req, nil
+}
+
+func (p *Processor) Fetch(req *http.Request) (*notbearparser.Node, error) {
+	if p.Robots != nil && !p.Robots.Allowed(p.Ctx, req.URL) {
+		return nil, &ErrDisallowed{URL: req.URL.String()}
+	}
+	var parser *notbearparser.Cursor
+	if p.Session != nil {
+		body, err := p.Session.Do(p.Ctx, req, p.Retries)
+		if err != nil {
+			return nil, err
+		}
+		parser = notbearparser.NewCursor(string(body))
+	} else {
+		if p.Limiter != nil {
+			if err := p.Limiter.Wait(p.Ctx, req.URL.Host); err != nil {
+				return nil, err
+			}
+		}
+		p.Client.Input <- req
+		parser = notbearparser.NewCursor(<-p.Client.Output)
+	}
 	err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	return parser.Root, nil
+}
+
+func (p *Processor) Search(req *http.Request, query string) ([]*notbearparser.Node, error) {
+	root, err := p.Fetch(req)
 	if err != nil {
 		return []*notbearparser.Node{}, err
 	}
-	nodes, err := notbearparser.Search(parser.Root, query)
+	nodes, err := notbearparser.Search(root, query)
 	if err != nil {
 		return []*notbearparser.Node{}, err
 	}
@@ -55,32 +129,135 @@ func (p *Processor) Search(req *http.Request, query string) ([]*notbearparser.No
 }
 
 func (p *Processor) Close() {
-	<-p.Client.Done
-	fmt.Println("closing processor")
+	for _, client := range p.clients {
+		<-client.Done
+	}
+	ProcessorLogger.Println("closing processor")
 	close(p.Output)
 	<-p.Input
 	close(p.Done)
-	fmt.Println("processor closed")
+	ProcessorLogger.Println("processor closed")
 }
 
+// Run starts Workers copies of the processor, each with its own client so
+// that responses cannot be handed to the wrong worker.
 func (p *Processor) Run() {
 	defer p.Close()
-	go p.Client.Run()
+	workers := p.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		worker := *p
+		if p.Session == nil {
+			worker.Client = notbearclient.NewClient(p.Retries, p.Timeout, p.Ctx, p.Error)
+			p.clients = append(p.clients, worker.Client)
+		}
+		wg.Add(1)
+		go func(w *Processor) {
+			defer wg.Done()
+			w.work()
+		}(&worker)
+	}
+	wg.Wait()
+}
+
+func (p *Processor) work() {
+	if p.Client != nil {
+		go p.Client.Run()
+	}
 	for {
 		select {
 		case <-p.Ctx.Done():
 			return
 		case input, ok := <-p.Input:
 			if !ok {
-				close(p.Client.Input)
+				if p.Client != nil {
+					close(p.Client.Input)
+				}
 				return
 			}
-			time.Sleep(time.Duration(p.Interval) * time.Second)
+			if p.Limiter == nil {
+				time.Sleep(time.Duration(p.Interval) * time.Second)
+			}
 			p.Process(p, input)
 		}
 	}
 }
 
+func (p *Processor) Fail(input interface{}, err error) {
+	p.Error <- &StageError{Input: input, Err: err}
+}
+
+func (p *Processor) Emit(entity interface{}) {
+	if p.Entity == nil {
+		return
+	}
+	select {
+	case <-p.Ctx.Done():
+	case p.Entity <- entity:
+	}
+}
+
+func firstContent(node *notbearparser.Node, query string) string {
+	nodes, err := notbearparser.Search(node, query)
+	if err != nil || len(nodes) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(nodes[0].Content)
+}
+
+func firstAttr(node *notbearparser.Node, query, attr string) string {
+	nodes, err := notbearparser.Search(node, query)
+	if err != nil || len(nodes) == 0 {
+		return ""
+	}
+	values, ok := nodes[0].Attrs.Get(attr)
+	if !ok {
+		return ""
+	}
+	return values[0]
+}
+
+func definitionList(node *notbearparser.Node, query string) map[string]string {
+	m := make(map[string]string)
+	dls, err := notbearparser.Search(node, query)
+	if err != nil {
+		return m
+	}
+	for _, dl := range dls {
+		dts, err := notbearparser.Search(dl, `dt`)
+		if err != nil {
+			continue
+		}
+		dds, err := notbearparser.Search(dl, `dd`)
+		if err != nil {
+			continue
+		}
+		for i := 0; i < len(dts) && i < len(dds); i++ {
+			key := strings.ToLower(strings.Trim(dts[i].Content, ": \n\t"))
+			m[key] = strings.TrimSpace(dds[i].Content)
+		}
+	}
+	return m
+}
+
+func leadingInt(s string) int {
+	n, digits := 0, 0
+	for _, r := range strings.TrimSpace(s) {
+		if r < '0' || r > '9' {
+			if digits > 0 {
+				break
+			}
+			continue
+		}
+		n = n*10 + int(r-'0')
+		digits++
+	}
+	return n
+}
+
 var BrandFunc ProcessFunc = func(p *Processor, input interface{}) {
 	s, ok := input.(string)
 	if !ok {
@@ -88,14 +265,9 @@ var BrandFunc ProcessFunc = func(p *Processor, input interface{}) {
 		return
 	}
 	ProcessorLogger.Printf("Brand Processor: IN %s\n", s)
-	req, err := notbearclient.NewRequest("GET", s, "", "motoSpecHeader", map[string][]string{})
+	_, nodes, err := p.SearchPages(s, p.Selectors.BrandList)
 	if err != nil {
-		p.Error <- err
-		return
-	}
-	nodes, err := p.Search(req, `.carman h5 a`)
-	if err != nil {
-		p.Error <- err
+		p.Fail(s, err)
 		return
 	}
 OUTER:
@@ -104,13 +276,26 @@ OUTER:
 		case <-p.Ctx.Done():
 			return
 		default:
-			brand := node.Children[0].Content
-			hrefs, ok := node.Attrs.Get("href")
+			as, err := notbearparser.Search(node, p.Selectors.BrandLink)
+			if err != nil || len(as) == 0 {
+				p.Error <- fmt.Errorf("%s has a brand without link", s)
+				continue OUTER
+			}
+			brand := as[0].Children[0].Content
+			if !p.Filter.AllowBrand(brand) {
+				continue OUTER
+			}
+			hrefs, ok := as[0].Attrs.Get("href")
 			if !ok {
 				p.Error <- fmt.Errorf("%s has no link", brand)
 				continue OUTER
 			}
-			p.Output <- BrandURL{Brand: brand, URL: hrefs[0]}
+			p.Output <- BrandURL{
+				Brand:      brand,
+				URL:        hrefs[0],
+				Logo:       firstAttr(node, p.Selectors.BrandLogo, "src"),
+				ModelCount: leadingInt(firstContent(node, p.Selectors.BrandModelCount)),
+			}
 			ProcessorLogger.Printf("Brand Processor: OUT %s\n", s)
 		}
 	}
@@ -123,34 +308,53 @@ var ModelFunc ProcessFunc = func(p *Processor, input interface{}) {
 		return
 	}
 	ProcessorLogger.Printf("Model Processor: IN %s\n", brand.URL)
-	req, err := notbearclient.NewRequest("GET", brand.URL, "", "motoSpecHeader", map[string][]string{})
+	root, nodes, err := p.SearchPages(brand.URL, p.Selectors.ModelList)
 	if err != nil {
-		p.Error <- err
+		p.Fail(brand, err)
 		return
 	}
-	nodes, err := p.Search(req, `.carmod a`)
-	if err != nil {
-		p.Error <- err
-		return
+	info := definitionList(root, p.Selectors.BrandInfo)
+	entity := Brand{
+		Name:       brand.Brand,
+		URL:        brand.URL,
+		Logo:       brand.Logo,
+		Country:    info["country"],
+		Founded:    info["founded"],
+		ModelCount: brand.ModelCount,
+		Status:     info["status"],
 	}
+	if entity.ModelCount == 0 {
+		entity.ModelCount = len(nodes)
+	}
+	p.Emit(entity)
 OUTER:
 	for _, node := range nodes {
 		select {
 		case <-p.Ctx.Done():
 			return
 		default:
-			models, err := notbearparser.Search(node, `h4`)
+			models, err := notbearparser.Search(node, p.Selectors.ModelName)
 			if err != nil {
 				p.Error <- err
 				return
 			}
 			model := models[0].Content
+			if !p.Filter.AllowModel(model) {
+				continue OUTER
+			}
 			hrefs, ok := node.Attrs.Get("href")
 			if !ok {
 				p.Error <- fmt.Errorf("%s has no href", model)
 				continue OUTER
 			}
-			p.Output <- ModelURL{Brand: brand.Brand, Model: model, URL: hrefs[0]}
+			p.Output <- ModelURL{
+				Brand:  brand.Brand,
+				Model:  model,
+				URL:    hrefs[0],
+				Image:  firstAttr(node, p.Selectors.ModelImage, "src"),
+				Years:  firstContent(node, p.Selectors.ModelYears),
+				Status: firstContent(node, p.Selectors.ModelStatus),
+			}
 			ProcessorLogger.Printf("Model Processor: OUT %s\n", brand.URL)
 		}
 	}
@@ -163,35 +367,42 @@ var MotoFunc ProcessFunc = func(p *Processor, input interface{}) {
 		return
 	}
 	ProcessorLogger.Printf("Moto Processor: IN %s\n", model.URL)
-	req, err := notbearclient.NewRequest("GET", model.URL, "", "motoSpecHeader", map[string][]string{})
-	if err != nil {
-		p.Error <- err
-		return
-	}
-	nodes, err := p.Search(req, `.carmodel`)
+	_, nodes, err := p.SearchPages(model.URL, p.Selectors.MotoList)
 	if err != nil {
-		p.Error <- err
+		p.Fail(model, err)
 		return
 	}
+	p.Emit(Model{
+		Brand:        model.Brand,
+		Name:         model.Model,
+		URL:          model.URL,
+		Image:        model.Image,
+		Years:        model.Years,
+		Status:       model.Status,
+		VariantCount: len(nodes),
+	})
 OUTER:
 	for _, node := range nodes {
 		select {
 		case <-p.Ctx.Done():
 			return
 		default:
-			motoNames, err := notbearparser.Search(node, `span[itemprop="name"]`)
+			motoNames, err := notbearparser.Search(node, p.Selectors.MotoName)
 			if err != nil {
 				p.Error <- err
 				continue OUTER
 			}
 			moto := motoNames[0].Content
-			years, err := notbearparser.Search(node, `p[class="years"]`)
+			years, err := notbearparser.Search(node, p.Selectors.MotoYears)
 			if err != nil {
 				p.Error <- err
 				continue OUTER
 			}
 			year := years[0].Content
-			as, err := notbearparser.Search(node, `a[itemprop="url"]`)
+			if !p.Filter.AllowYears(year) {
+				continue OUTER
+			}
+			as, err := notbearparser.Search(node, p.Selectors.MotoLink)
 			if err != nil {
 				p.Error <- err
 				continue OUTER
@@ -213,467 +424,120 @@ OUTER:
 	}
 }
 
-var SpecFunc ProcessFunc = func(p *Processor, input interface{}) {
-	moto, ok := input.(MotoURL)
-	if !ok {
-		p.Error <- fmt.Errorf("%v is not a valid MotoURL", input)
-	}
-	ProcessorLogger.Printf("Spec Processor: IN %s", moto.URL)
-	req, err := notbearclient.NewRequest("GET", moto.URL, "", "motoSpecHeader", map[string][]string{})
-	if err != nil {
-		p.Error <- err
-		return
+var SpecFunc = NewSpecFunc(nil)
+
+func NewSpecFunc(store *ImageStore) ProcessFunc {
+	return func(p *Processor, input interface{}) {
+		moto, ok := input.(MotoURL)
+		if !ok {
+			p.Error <- fmt.Errorf("%v is not a valid MotoURL", input)
+			return
+		}
+		ProcessorLogger.Printf("Spec Processor: IN %s", moto.URL)
+		req, err := p.NewRequest("GET", moto.URL)
+		if err != nil {
+			p.Fail(moto, err)
+			return
+		}
+		root, err := p.Fetch(req)
+		if err != nil {
+			p.Fail(moto, err)
+			return
+		}
+		specTabs, err := notbearparser.Search(root, p.Selectors.SpecTable)
+		if err != nil {
+			p.Fail(moto, err)
+			return
+		}
+		if len(specTabs) == 0 {
+			p.Fail(moto, fmt.Errorf("%s %s %s(%s) has no spec table", moto.Brand, moto.Model, moto.Moto, moto.Year))
+			return
+		}
+		dts, err := notbearparser.Search(specTabs[0], p.Selectors.SpecKey)
+		if err != nil {
+			p.Fail(moto, err)
+			return
+		}
+		dds, err := notbearparser.Search(specTabs[0], p.Selectors.SpecValue)
+		if err != nil {
+			p.Fail(moto, err)
+			return
+		}
+		if len(dts) != len(dds) {
+			p.Fail(moto, errors.New("spec table dt is not equal to dd"))
+			return
+		}
+		spec := Spec{
+			Source: "autoevolution",
+			Brand:  moto.Brand,
+			Model:  moto.Model,
+			Moto:   moto.Moto,
+			Year:   moto.Year,
+			URL:    moto.URL,
+			Specs:  make(map[string]string),
+		}
+		for i := 0; i < len(dts); i++ {
+			spec.Specs[dts[i].Content] = dds[i].Content
+		}
+		page, err := ParseSpecPage(root, p.Selectors)
+		if err != nil {
+			p.Error <- fmt.Errorf("%s: %v", moto.URL, err)
+		}
+		if store != nil {
+			for i, img := range page.Images {
+				saved, err := store.Save(img.URL)
+				if err != nil {
+					p.Error <- err
+					continue
+				}
+				page.Images[i] = saved
+			}
+		}
+		spec.SpecPage = page
+		p.Output <- spec
+		ProcessorLogger.Printf("Spec Processor: OUT %s", moto.URL)
 	}
-	specTabs, err := p.Search(req, `.enginedata`)
+}
+
+func ParseSpecPage(root *notbearparser.Node, sel *Selectors) (SpecPage, error) {
+	page := SpecPage{}
+	descs, err := notbearparser.Search(root, sel.Description)
 	if err != nil {
-		p.Error <- err
-		return
+		return page, err
 	}
-	if len(specTabs) == 0 {
-		p.Error <- fmt.Errorf("%s %s %s(%s) has no spec table", moto.Brand, moto.Model, moto.Moto, moto.Year)
-		return
-	}
-	dts, err := notbearparser.Search(specTabs[0], `dt em`)
-	if err != nil {
-		p.Error <- err
-		return
+	paragraphs := make([]string, 0, len(descs))
+	for _, desc := range descs {
+		if text := strings.TrimSpace(desc.Content); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
 	}
-	dds, err := notbearparser.Search(specTabs[0], `dd`)
+	page.Description = strings.Join(paragraphs, "\n\n")
+	info := definitionList(root, sel.ModelInfo)
+	page.Segment = info["segment"]
+	page.BodyStyle = info["body style"]
+	photos, err := notbearparser.Search(root, sel.Gallery)
 	if err != nil {
-		p.Error <- err
-		return
+		return page, err
 	}
-	if len(dts) != len(dds) {
-		p.Error <- errors.New("spec table dt is not equal to dd")
-		return
+	seen := make(map[string]bool)
+	for _, photo := range photos {
+		hrefs, ok := photo.Attrs.Get("href")
+		if !ok || hrefs[0] == "" || seen[hrefs[0]] {
+			continue
+		}
+		seen[hrefs[0]] = true
+		page.Images = append(page.Images, Image{URL: hrefs[0]})
 	}
-	spec := Spec{
-		Brand: moto.Brand,
-		Model: moto.Model,
-		Moto:  moto.Moto,
-		Year:  moto.Year,
-		Specs: make(map[string]string),
+	relateds, err := notbearparser.Search(root, sel.Related)
+	if err != nil {
+		return page, err
 	}
-	for i := 0; i < len(dts); i++ {
-		spec.Specs[dts[i].Content] = dds[i].Content
+	for _, related := range relateds {
+		hrefs, ok := related.Attrs.Get("href")
+		if !ok {
+			continue
+		}
+		page.Related = append(page.Related, Link{Name: strings.TrimSpace(related.Content), URL: hrefs[0]})
 	}
-	p.Output <- spec
-	ProcessorLogger.Printf("Spec Processor: OUT %s", moto.URL)
+	return page, nil
 }
-
-// type ManufacturerProcessor struct {
-// 	Client       *notbearclient.Client
-// 	ClientCancel context.CancelFunc
-// 	Output       chan Manufacturer
-// 	Error        chan error
-// 	Done         chan struct{}
-// 	Ctx          context.Context
-// }
-//
-// func NewManufacturerProcessor(err chan error, ctx context.Context) *ManufacturerProcessor {
-// 	newCtx, cancel := context.WithCancel(context.Background())
-// 	client := notbearclient.NewClient(3, 10, newCtx, err)
-// 	return &ManufacturerProcessor{
-// 		Client:       client,
-// 		ClientCancel: cancel,
-// 		Output:       make(chan Manufacturer, 128),
-// 		Error:        err,
-// 		Done:         make(chan struct{}, 1),
-// 		Ctx:          ctx,
-// 	}
-// }
-//
-// func (mfp *ManufacturerProcessor) Run() {
-// 	defer mfp.Close()
-// 	go mfp.Client.Run()
-// OUTER:
-// 	for {
-// 		select {
-// 		case <-mfp.Ctx.Done():
-// 			return
-// 		default:
-// 			url := fmt.Sprintf(BaseURL, "0", "", "", "")
-// 			req, err := notbearclient.NewRequest("GET", url, "", "motoSpecHeader", map[string][]string{})
-// 			if err != nil {
-// 				mfp.Error <- fmt.Errorf("failed to get %s", url)
-// 				return
-// 			}
-// 			mfp.Client.Input <- req
-// 			if content, ok := <-mfp.Client.Output; ok {
-// 				cursor := notbearparser.NewCursor(content)
-// 				err = cursor.Parse()
-// 				if err != nil {
-// 					mfp.Error <- err
-// 					return
-// 				}
-// 				options, err := notbearparser.Search(cursor.Root, "option")
-// 				if err != nil {
-// 					mfp.Error <- err
-// 					return
-// 				}
-// 				if len(options) == 0 {
-// 					mfp.Error <- fmt.Errorf("Empty manufacturer option(%s)", url)
-// 					return
-// 				}
-// 				for _, option := range options {
-// 					if value, ok := option.Attrs.Get("value"); ok && value[0] != "" {
-// 						name := strings.Trim(option.Content, "\n\r ")
-// 						manufacturer := Manufacturer{Name: name, Value: value[0]}
-// 						mfp.Output <- manufacturer
-// 					}
-// 				}
-// 				break OUTER
-// 			}
-// 		}
-// 	}
-// }
-//
-// func (mfp *ManufacturerProcessor) Close() {
-// 	fmt.Println("Closing manufacturer processor......")
-// 	mfp.ClientCancel()
-// 	<-mfp.Client.Done
-// 	close(mfp.Output)
-// 	mfp.Done <- struct{}{}
-// 	close(mfp.Done)
-// 	fmt.Println("Manufacturer processor has closed")
-// }
-//
-// func (mfp *ManufacturerProcessor) DoneChan() chan struct{} {
-// 	return mfp.Done
-// }
-//
-// type CategoryProcessor struct {
-// 	Client       notbearclient.Client
-// 	ClientCancel context.CancelFunc
-// 	Input        chan Manufacturer
-// 	Output       chan Category
-// 	Error        chan error
-// 	Done         chan struct{}
-// 	Ctx          context.Context
-// }
-//
-// func NewCategoryProcessor(input chan Manufacturer, errChan chan error, ctx context.Context) *CategoryProcessor {
-// 	newCtx, cancel := context.WithCancel(context.Background())
-// 	client := notbearclient.NewClient(3, 10, newCtx, errChan)
-// 	return &CategoryProcessor{
-// 		Client:       *client,
-// 		ClientCancel: cancel,
-// 		Input:        input,
-// 		Output:       make(chan Category),
-// 		Error:        errChan,
-// 		Done:         make(chan struct{}),
-// 		Ctx:          ctx,
-// 	}
-// }
-//
-// func (ctp *CategoryProcessor) Close() {
-// 	fmt.Println("Closing category processor......")
-// 	ctp.ClientCancel()
-// 	<-ctp.Client.Done
-// 	close(ctp.Output)
-// 	ctp.Done <- struct{}{}
-// 	close(ctp.Done)
-// 	fmt.Println("Category processor has closed")
-// }
-//
-// func (ctp *CategoryProcessor) DoneChan() chan struct{} {
-// 	return ctp.Done
-// }
-//
-// func (ctp *CategoryProcessor) Run() {
-// 	defer ctp.Close()
-// 	go ctp.Client.Run()
-// OUTER:
-// 	for {
-// 		select {
-// 		case <-ctp.Ctx.Done():
-// 			return
-// 		case mf, ok := <-ctp.Input:
-// 			if ok {
-// 				url := fmt.Sprintf(BaseURL, "1", mf.Value, "", "")
-// 				req, err := notbearclient.NewRequest("GET", url, "", "motoSpecHeader", map[string][]string{})
-// 				if err != nil {
-// 					ctp.Error <- err
-// 					continue OUTER
-// 				}
-// 				ctp.Client.Input <- req
-// 				result, ok := <-ctp.Client.Output
-// 				if ok {
-// 					parser := notbearparser.NewCursor(result)
-// 					err = parser.Parse()
-// 					if err != nil {
-// 						ctp.Error <- err
-// 						continue OUTER
-// 					}
-// 					l, err := notbearparser.Search(parser.Root, `option`)
-// 					if err != nil {
-// 						ctp.Error <- err
-// 						continue OUTER
-// 					}
-// 					for _, node := range l {
-// 						if value, ok := node.Attrs.Get("value"); ok && value[0] != "" {
-// 							category := Category{Manufacturer: mf, Name: node.Content, Value: value[0]}
-// 							ctp.Output <- category
-// 						}
-// 					}
-// 				}
-// 			} else {
-// 				break OUTER
-// 			}
-// 		}
-// 	}
-// }
-//
-// type YearProcessor struct {
-// 	Client       *notbearclient.Client
-// 	ClientCancel context.CancelFunc
-// 	Input        <-chan Category
-// 	Output       chan Year
-// 	Error        chan<- error
-// 	Done         chan struct{}
-// 	Ctx          context.Context
-// }
-//
-// func NewYearProcessor(input <-chan Category, errChan chan error, ctx context.Context) *YearProcessor {
-// 	clientCtx, cancel := context.WithCancel(context.Background())
-// 	client := notbearclient.NewClient(3, 10, clientCtx, errChan)
-// 	return &YearProcessor{
-// 		Client:       client,
-// 		ClientCancel: cancel,
-// 		Input:        input,
-// 		Output:       make(chan Year),
-// 		Error:        errChan,
-// 		Done:         make(chan struct{}),
-// 		Ctx:          ctx,
-// 	}
-// }
-//
-// func (yp *YearProcessor) Close() {
-// 	fmt.Println("Closing year processor......")
-// 	yp.ClientCancel()
-// 	<-yp.Client.Done
-// 	close(yp.Output)
-// 	yp.Done <- struct{}{}
-// 	close(yp.Done)
-// 	fmt.Println("Year processor has closed")
-// }
-//
-// func (yp *YearProcessor) DoneChan() chan struct{} {
-// 	return yp.Done
-// }
-//
-// func (yp *YearProcessor) Run() {
-// 	defer yp.Close()
-// 	go yp.Client.Run()
-// OUTER:
-// 	for {
-// 		select {
-// 		case <-yp.Ctx.Done():
-// 			return
-// 		case category, ok := <-yp.Input:
-// 			if ok {
-// 				url := fmt.Sprintf(BaseURL, "2", category.Manufacturer.Value, category.Value, "")
-// 				req, err := notbearclient.NewRequest("GET", url, "", "motoSpecHeader", map[string][]string{})
-// 				if err != nil {
-// 					yp.Error <- err
-// 					continue OUTER
-// 				}
-// 				yp.Client.Input <- req
-// 				if result, ok := <-yp.Client.Output; ok {
-// 					parser := notbearparser.NewCursor(result)
-// 					err = parser.Parse()
-// 					if err != nil {
-// 						yp.Error <- err
-// 						continue OUTER
-// 					}
-// 					l, err := notbearparser.Search(parser.Root, "option")
-// 					if err != nil {
-// 						yp.Error <- err
-// 						continue OUTER
-// 					}
-// 					for _, node := range l {
-// 						if values, ok := node.Attrs.Get("value"); ok && values[0] != "" {
-// 							year := Year{Category: category, Name: node.Content, Value: values[0]}
-// 							yp.Output <- year
-// 						}
-// 					}
-// 				}
-// 			} else {
-// 				return
-// 			}
-// 		}
-// 	}
-// }
-//
-// type ModelProcessor struct {
-// 	Client       notbearclient.Client
-// 	ClientCancel context.CancelFunc
-// 	Input        <-chan Year
-// 	Output       chan Model
-// 	Error        chan error
-// 	Done         chan struct{}
-// 	Ctx          context.Context
-// }
-//
-// func NewModelProcessor(input <-chan Year, errChan chan error, ctx context.Context) *ModelProcessor {
-// 	clientCtx, cancel := context.WithCancel(context.Background())
-// 	client := notbearclient.NewClient(3, 10, clientCtx, errChan)
-// 	return &ModelProcessor{
-// 		Client:       *client,
-// 		ClientCancel: cancel,
-// 		Input:        input,
-// 		Output:       make(chan Model),
-// 		Error:        errChan,
-// 		Done:         make(chan struct{}),
-// 		Ctx:          ctx,
-// 	}
-// }
-//
-// func (mp *ModelProcessor) Close() {
-// 	fmt.Println("Closing model processor......")
-// 	mp.ClientCancel()
-// 	<-mp.Client.Done
-// 	close(mp.Output)
-// 	mp.Done <- struct{}{}
-// 	close(mp.Done)
-// 	fmt.Println("Model processor has closed")
-// }
-//
-// func (mp *ModelProcessor) DoneChan() chan struct{} {
-// 	return mp.Done
-// }
-//
-// func (mp *ModelProcessor) Run() {
-// 	defer mp.Close()
-// 	go mp.Client.Run()
-// OUTER:
-// 	for {
-// 		select {
-// 		case <-mp.Ctx.Done():
-// 			return
-// 		case year, ok := <-mp.Input:
-// 			if ok {
-// 				url := fmt.Sprintf(BaseURL, "3", year.Category.Manufacturer.Value, year.Category.Value, year.Value)
-// 				req, err := notbearclient.NewRequest("GET", url, "", "motoSpecHeader", map[string][]string{})
-// 				if err != nil {
-// 					mp.Error <- err
-// 					continue OUTER
-// 				}
-// 				mp.Client.Input <- req
-// 				if result, ok := <-mp.Client.Output; ok {
-// 					parser := notbearparser.NewCursor(result)
-// 					err = parser.Parse()
-// 					if err != nil {
-// 						mp.Error <- err
-// 						continue OUTER
-// 					}
-// 					l, err := notbearparser.Search(parser.Root, "option")
-// 					if err != nil {
-// 						mp.Error <- err
-// 						continue OUTER
-// 					}
-// 					for _, node := range l {
-// 						if values, ok := node.Attrs.Get("value"); ok && values[0] != "" {
-// 							model := Model{Year: year, Name: node.Content, Value: values[0]}
-// 							mp.Output <- model
-// 						}
-// 					}
-// 				}
-// 			} else {
-// 				return
-// 			}
-// 		}
-// 	}
-// }
-//
-// type SpecProcessor struct {
-// 	Client       notbearclient.Client
-// 	ClientCancel context.CancelFunc
-// 	Input        <-chan Model
-// 	Output       chan Spec
-// 	Error        chan error
-// 	Done         chan struct{}
-// 	Ctx          context.Context
-// }
-//
-// func NewSpecProcessor(input <-chan Model, errChan chan error, ctx context.Context) *SpecProcessor {
-// 	clientCtx, cancel := context.WithCancel(context.Background())
-// 	client := notbearclient.NewClient(3, 10, clientCtx, errChan)
-// 	return &SpecProcessor{
-// 		Client:       *client,
-// 		ClientCancel: cancel,
-// 		Input:        input,
-// 		Output:       make(chan Spec),
-// 		Error:        errChan,
-// 		Done:         make(chan struct{}),
-// 		Ctx:          ctx,
-// 	}
-// }
-//
-// func (sp *SpecProcessor) Close() {
-// 	fmt.Println("Closing spec processor......")
-// 	sp.ClientCancel()
-// 	<-sp.Client.Done
-// 	close(sp.Output)
-// 	sp.Done <- struct{}{}
-// 	close(sp.Done)
-// 	fmt.Println("Spec processor has closed")
-// }
-//
-// func (sp *SpecProcessor) DoneChan() chan struct{} {
-// 	return sp.Done
-// }
-//
-// func (sp *SpecProcessor) Run() {
-// 	defer sp.Close()
-// 	go sp.Client.Run()
-// OUTER:
-// 	for {
-// 		select {
-// 		case <-sp.Ctx.Done():
-// 			return
-// 		case model, ok := <-sp.Input:
-// 			if ok {
-// 				req, err := notbearclient.NewRequest("POST", "http://www.motorcycle.com/specs/", "application/x-www-form-urlencoded", "motoSpecHeader", map[string][]string{
-// 					"MakeId":      []string{model.Year.Category.Manufacturer.Value},
-// 					"ModelType":   []string{model.Year.Category.Value},
-// 					"year":        []string{model.Year.Value},
-// 					"TrimId":      []string{model.Value},
-// 					"get_specs.x": []string{"94"},
-// 					"get_specs.y": []string{"14"},
-// 				})
-// 				if err != nil {
-// 					sp.Error <- err
-// 					continue OUTER
-// 				}
-// 				sp.Client.Input <- req
-// 				if result, ok := <-sp.Client.Output; ok {
-// 					parser := notbearparser.NewCursor(result)
-// 					err = parser.Parse()
-// 					if err != nil {
-// 						sp.Error <- err
-// 						continue OUTER
-// 					}
-// 					l, err := notbearparser.Search(parser.Root, ".table_info td")
-// 					if err != nil {
-// 						sp.Error <- err
-// 						continue OUTER
-// 					}
-// 					spec := Spec{}
-// 					spec["Manufacturer"] = model.Year.Category.Manufacturer.Name
-// 					spec["Category"] = model.Year.Category.Name
-// 					spec["Year"] = model.Year.Name
-// 					spec["Model"] = model.Name
-// 					for i := 0; i < len(l)-1; i += 2 {
-// 						key := l[i].Content
-// 						value := l[i+1].Content
-// 						spec[key] = value
-// 					}
-// 					sp.Output <- spec
-// 				}
-// 			} else {
-// 				return
-// 			}
-// 		}
-// 	}
-// }
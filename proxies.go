@@ -0,0 +1,208 @@
+package motospec
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Proxy struct {
+	URL *url.URL
+
+	mutex        sync.Mutex
+	successes    int64
+	failures     int64
+	consecutive  int
+	demotions    int
+	demotedUntil time.Time
+}
+
+type ProxyStats struct {
+	Proxy     string    `json:"proxy"`
+	Successes int64     `json:"successes"`
+	Failures  int64     `json:"failures"`
+	Demotions int       `json:"demotions"`
+	Demoted   bool      `json:"demoted"`
+	Until     time.Time `json:"until,omitempty"`
+}
+
+func (p *Proxy) healthy(now time.Time) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return !now.Before(p.demotedUntil)
+}
+
+// ProxyPool hands out proxies per host. A host keeps its proxy for as long as
+// the proxy stays healthy; a proxy is demoted for Cooldown after MaxFailures
+// failures in a row.
+type ProxyPool struct {
+	Proxies     []*Proxy
+	MaxFailures int
+	Cooldown    time.Duration
+
+	mutex  sync.Mutex
+	sticky map[string]*Proxy
+	next   int
+}
+
+func NewProxyPool(urls []string, maxFailures int, cooldown time.Duration) (*ProxyPool, error) {
+	pool := &ProxyPool{
+		MaxFailures: maxFailures,
+		Cooldown:    cooldown,
+		sticky:      make(map[string]*Proxy),
+	}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("proxy %s: unsupported scheme %q", raw, u.Scheme)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("proxy %s: missing host", raw)
+		}
+		pool.Proxies = append(pool.Proxies, &Proxy{URL: u})
+	}
+	if len(pool.Proxies) == 0 {
+		return nil, fmt.Errorf("proxy pool is empty")
+	}
+	if pool.MaxFailures < 1 {
+		pool.MaxFailures = 1
+	}
+	return pool, nil
+}
+
+// Pick returns the proxy of host. When every proxy is demoted the one that
+// recovers first is used rather than stalling the crawl.
+func (pp *ProxyPool) Pick(host string) *Proxy {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	now := time.Now()
+	if proxy, ok := pp.sticky[host]; ok && proxy.healthy(now) {
+		return proxy
+	}
+	var chosen *Proxy
+	for i := 0; i < len(pp.Proxies); i++ {
+		proxy := pp.Proxies[(pp.next+i)%len(pp.Proxies)]
+		if proxy.healthy(now) {
+			chosen = proxy
+			pp.next = (pp.next + i + 1) % len(pp.Proxies)
+			break
+		}
+	}
+	if chosen == nil {
+		var chosenUntil time.Time
+		for _, proxy := range pp.Proxies {
+			proxy.mutex.Lock()
+			until := proxy.demotedUntil
+			proxy.mutex.Unlock()
+			if chosen == nil || until.Before(chosenUntil) {
+				chosen, chosenUntil = proxy, until
+			}
+		}
+	}
+	pp.sticky[host] = chosen
+	return chosen
+}
+
+// Report records the outcome of a request made through proxy. Network
+// errors, timeouts and 403/429 responses count as failures; a request
+// cancelled by its context, as when the crawl stops, does not.
+func (pp *ProxyPool) Report(proxy *Proxy, host string, err error) {
+	failed := false
+	switch e := err.(type) {
+	case nil:
+	case *ErrFetch:
+		failed = !contextError(e.Err)
+	case *ErrStatus:
+		failed = e.StatusCode == http.StatusForbidden || e.StatusCode == http.StatusTooManyRequests
+	}
+	proxy.mutex.Lock()
+	if !failed {
+		proxy.successes++
+		proxy.consecutive = 0
+		proxy.mutex.Unlock()
+		return
+	}
+	proxy.failures++
+	proxy.consecutive++
+	demoted := proxy.consecutive >= pp.MaxFailures
+	if demoted {
+		proxy.consecutive = 0
+		proxy.demotions++
+		proxy.demotedUntil = time.Now().Add(pp.Cooldown)
+	}
+	proxy.mutex.Unlock()
+	if demoted {
+		pp.mutex.Lock()
+		if pp.sticky[host] == proxy {
+			delete(pp.sticky, host)
+		}
+		pp.mutex.Unlock()
+		ProcessorLogger.Printf("Proxy %s: demoted for %s after %d failures", proxy.URL.Host, pp.Cooldown, pp.MaxFailures)
+	}
+}
+
+// contextError tells an error of a request cancelled by its context. A
+// timeout of the client is not one: the proxy may well be the slow part.
+func contextError(err error) bool {
+	if e, ok := err.(*url.Error); ok {
+		err = e.Err
+	}
+	return err == context.Canceled
+}
+
+func (pp *ProxyPool) Stats() []ProxyStats {
+	now := time.Now()
+	stats := make([]ProxyStats, 0, len(pp.Proxies))
+	for _, proxy := range pp.Proxies {
+		proxy.mutex.Lock()
+		s := ProxyStats{
+			Proxy:     redactProxy(proxy.URL),
+			Successes: proxy.successes,
+			Failures:  proxy.failures,
+			Demotions: proxy.demotions,
+			Demoted:   now.Before(proxy.demotedUntil),
+		}
+		if s.Demoted {
+			s.Until = proxy.demotedUntil
+		}
+		proxy.mutex.Unlock()
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Proxy < stats[j].Proxy })
+	return stats
+}
+
+func redactProxy(u *url.URL) string {
+	if u.User == nil {
+		return u.String()
+	}
+	redacted := *u
+	redacted.User = url.User(u.User.Username())
+	return redacted.String()
+}
+
+type proxyKey struct{}
+
+// Transport returns an http.RoundTripper sending every request through the
+// proxy stored in its context by Session.
+func (pp *ProxyPool) Transport() *http.Transport {
+	return &http.Transport{
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			if proxy, ok := req.Context().Value(proxyKey{}).(*Proxy); ok {
+				return proxy.URL, nil
+			}
+			return nil, nil
+		},
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
@@ -0,0 +1,254 @@
+package motospec
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// QualityThresholds fail a QualityReport. The shares run from 0 to 1; a
+// negative maximum count is not checked.
+type QualityThresholds struct {
+	MinCoverage     float64 `yaml:"min_coverage" json:"min_coverage"`
+	MaxPlaceholders float64 `yaml:"max_placeholders" json:"max_placeholders"`
+	MaxUnparseable  float64 `yaml:"max_unparseable" json:"max_unparseable"`
+	MaxDuplicates   int     `yaml:"max_duplicates" json:"max_duplicates"`
+	MaxOutliers     int     `yaml:"max_outliers" json:"max_outliers"`
+	MaxEmptyBrands  int     `yaml:"max_empty_brands" json:"max_empty_brands"`
+	MaxEmptyModels  int     `yaml:"max_empty_models" json:"max_empty_models"`
+}
+
+func (t QualityThresholds) Validate() []string {
+	errs := []string{}
+	for name, share := range map[string]float64{
+		"min_coverage":     t.MinCoverage,
+		"max_placeholders": t.MaxPlaceholders,
+		"max_unparseable":  t.MaxUnparseable,
+	} {
+		if share < 0 || share > 1 {
+			errs = append(errs, name+": must be between 0 and 1")
+		}
+	}
+	sort.Strings(errs)
+	return errs
+}
+
+var placeholderPattern = regexp.MustCompile(`^[-–—?]*$|^(n/?a|none|unknown)$|^-(/-)?\s|\bnan\b`)
+
+// IsPlaceholder tells the values a source fills a missing spec with, such as
+// "-", "-/- KW(hp)/RPM" or "NaN/- lb-ft/RPM", from real ones.
+func IsPlaceholder(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(tagPattern.ReplaceAllString(value, " ")))
+	return placeholderPattern.MatchString(value)
+}
+
+type KeyQuality struct {
+	Key              string  `json:"key"`
+	Present          int     `json:"present"`
+	Placeholders     int     `json:"placeholders"`
+	Unparseable      int     `json:"unparseable"`
+	Coverage         float64 `json:"coverage"`
+	PlaceholderShare float64 `json:"placeholder_share"`
+	UnparseableShare float64 `json:"unparseable_share"`
+}
+
+type Duplicate struct {
+	Key string   `json:"key"`
+	IDs []string `json:"ids"`
+}
+
+type Outlier struct {
+	VariantEntry
+	Reason string `json:"reason"`
+}
+
+type QualityReport struct {
+	Specs            int               `json:"specs"`
+	Brands           int               `json:"brands"`
+	Models           int               `json:"models"`
+	Values           int               `json:"values"`
+	Placeholders     int               `json:"placeholders"`
+	PlaceholderShare float64           `json:"placeholder_share"`
+	Keys             []KeyQuality      `json:"keys"`
+	EmptyBrands      []string          `json:"empty_brands"`
+	EmptyModels      []string          `json:"empty_models"`
+	Duplicates       []Duplicate       `json:"duplicates"`
+	Outliers         []Outlier         `json:"outliers"`
+	Thresholds       QualityThresholds `json:"thresholds"`
+	Failures         []string          `json:"failures"`
+}
+
+func (r *QualityReport) OK() bool {
+	return len(r.Failures) == 0
+}
+
+func share(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
+
+// BuildQualityReport checks a crawl: its specs and, when given, the brand
+// and model records the specs should cover.
+func BuildQualityReport(specs []Spec, brands []Brand, models []Model, thresholds QualityThresholds) *QualityReport {
+	r := &QualityReport{
+		Specs:       len(specs),
+		Brands:      len(brands),
+		Models:      len(models),
+		EmptyBrands: []string{},
+		EmptyModels: []string{},
+		Duplicates:  []Duplicate{},
+		Outliers:    []Outlier{},
+		Thresholds:  thresholds,
+		Failures:    []string{},
+	}
+	catalog := NewCatalog(specs, nil, nil)
+	keys := make([]KeyQuality, len(CompareKeys))
+	groups := make(map[string][]string)
+	var groupOrder []string
+	for i, spec := range specs {
+		n := catalog.Norms[i]
+		for _, value := range spec.Specs {
+			r.Values++
+			if IsPlaceholder(value) {
+				r.Placeholders++
+			}
+		}
+		for k, key := range CompareKeys {
+			keys[k].Key = key.Name
+			value := SpecValue(spec, key.Names...)
+			switch {
+			case strings.TrimSpace(value) == "":
+			case IsPlaceholder(value):
+				keys[k].Placeholders++
+			default:
+				keys[k].Present++
+				if _, ok := key.Read(spec, n); key.Numeric() && !ok {
+					keys[k].Unparseable++
+				}
+			}
+		}
+		group := strings.Join([]string{spec.Source, n.Brand, n.Model, spec.Year}, "|")
+		if _, ok := groups[group]; !ok {
+			groupOrder = append(groupOrder, group)
+		}
+		groups[group] = append(groups[group], catalog.IDs[i])
+		if d := Derive(spec); d != nil && len(d.Flags) > 0 {
+			r.Outliers = append(r.Outliers, Outlier{VariantEntry: catalog.Variant(i), Reason: strings.Join(d.Flags, "; ")})
+		}
+	}
+	for k := range keys {
+		keys[k].Coverage = share(keys[k].Present, len(specs))
+		keys[k].PlaceholderShare = share(keys[k].Placeholders, keys[k].Present+keys[k].Placeholders)
+		keys[k].UnparseableShare = share(keys[k].Unparseable, keys[k].Present)
+	}
+	r.Keys = keys
+	r.PlaceholderShare = share(r.Placeholders, r.Values)
+	for _, group := range groupOrder {
+		if ids := groups[group]; len(ids) > 1 {
+			r.Duplicates = append(r.Duplicates, Duplicate{Key: group, IDs: ids})
+		}
+	}
+	modelsOf := make(map[string]bool)
+	for _, model := range models {
+		modelsOf[NormalizeBrand(model.Brand)] = true
+	}
+	for _, brand := range brands {
+		if _, ok := catalog.Brand(slugID(brand.Name)); !ok && !modelsOf[NormalizeBrand(brand.Name)] {
+			r.EmptyBrands = append(r.EmptyBrands, brand.Name)
+		}
+	}
+	for _, model := range models {
+		if _, ok := catalog.ModelSpecs(slugID(model.Brand), modelSlug(model.Brand, model.Name)); !ok {
+			r.EmptyModels = append(r.EmptyModels, model.Brand+" "+model.Name)
+		}
+	}
+	r.check()
+	return r
+}
+
+func (r *QualityReport) check() {
+	t := r.Thresholds
+	fail := func(format string, args ...interface{}) {
+		r.Failures = append(r.Failures, fmt.Sprintf(format, args...))
+	}
+	for _, key := range r.Keys {
+		if key.Coverage < t.MinCoverage {
+			fail("%s: coverage %.1f%% is below %.1f%%", key.Key, 100*key.Coverage, 100*t.MinCoverage)
+		}
+		if key.UnparseableShare > t.MaxUnparseable {
+			fail("%s: %.1f%% unparseable values, more than %.1f%%", key.Key, 100*key.UnparseableShare, 100*t.MaxUnparseable)
+		}
+	}
+	if r.PlaceholderShare > t.MaxPlaceholders {
+		fail("%.1f%% placeholder values, more than %.1f%%", 100*r.PlaceholderShare, 100*t.MaxPlaceholders)
+	}
+	for _, count := range []struct {
+		name  string
+		found int
+		max   int
+	}{
+		{"duplicate variants", len(r.Duplicates), t.MaxDuplicates},
+		{"outliers", len(r.Outliers), t.MaxOutliers},
+		{"brands without models", len(r.EmptyBrands), t.MaxEmptyBrands},
+		{"models without variants", len(r.EmptyModels), t.MaxEmptyModels},
+	} {
+		if count.max >= 0 && count.found > count.max {
+			fail("%d %s, more than %d", count.found, count.name, count.max)
+		}
+	}
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(share float64) string { return fmt.Sprintf("%.1f%%", 100*share) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>motospec data quality</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+td, th { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+td.number { text-align: right; }
+.fail { color: #b00; }
+.ok { color: #070; }
+</style>
+</head>
+<body>
+<h1>Data quality</h1>
+<p>{{.Specs}} specs, {{.Brands}} brand records, {{.Models}} model records.
+{{.Placeholders}} of {{.Values}} values ({{percent .PlaceholderShare}}) are placeholders.</p>
+{{if .Failures}}<h2 class="fail">Failed</h2>
+<ul class="fail">{{range .Failures}}<li>{{.}}</li>{{end}}</ul>
+{{else}}<h2 class="ok">All thresholds met</h2>{{end}}
+<h2>Coverage</h2>
+<table>
+<tr><th>key</th><th>present</th><th>coverage</th><th>placeholders</th><th>unparseable</th></tr>
+{{range .Keys}}<tr><td>{{.Key}}</td><td class="number">{{.Present}}</td><td class="number">{{percent .Coverage}}</td><td class="number">{{.Placeholders}} ({{percent .PlaceholderShare}})</td><td class="number">{{.Unparseable}} ({{percent .UnparseableShare}})</td></tr>
+{{end}}</table>
+<h2>Outliers ({{len .Outliers}})</h2>
+{{if .Outliers}}<table>
+<tr><th>id</th><th>brand</th><th>type</th><th>year</th><th>reason</th></tr>
+{{range .Outliers}}<tr><td>{{.ID}}</td><td>{{.Brand}}</td><td>{{.Moto}}</td><td>{{.Year}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>{{end}}
+<h2>Duplicate variants ({{len .Duplicates}})</h2>
+{{if .Duplicates}}<table>
+<tr><th>source | brand | model | year</th><th>ids</th></tr>
+{{range .Duplicates}}<tr><td>{{.Key}}</td><td>{{range .IDs}}{{.}} {{end}}</td></tr>
+{{end}}</table>{{end}}
+<h2>Brands without models ({{len .EmptyBrands}})</h2>
+{{if .EmptyBrands}}<ul>{{range .EmptyBrands}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<h2>Models without variants ({{len .EmptyModels}})</h2>
+{{if .EmptyModels}}<ul>{{range .EmptyModels}}<li>{{.}}</li>{{end}}</ul>{{end}}
+</body>
+</html>
+`))
+
+func (r *QualityReport) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
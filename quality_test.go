@@ -0,0 +1,81 @@
+package motospec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsPlaceholder(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"-", true},
+		{" ? ", true},
+		{"N/A", true},
+		{"unknown", true},
+		{"-/- KW(hp)/RPM", true},
+		{"NaN/- lb-ft/RPM", true},
+		{"<br>-", true},
+		{"73 hp", false},
+		{"-10 C", false},
+		{"Unknown origin frame", false},
+	}
+	for _, test := range tests {
+		if got := IsPlaceholder(test.value); got != test.want {
+			t.Errorf("%q: got %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestQualityThresholdsValidate(t *testing.T) {
+	if errs := (QualityThresholds{MinCoverage: 0.5}).Validate(); len(errs) != 0 {
+		t.Errorf("valid thresholds: %v", errs)
+	}
+	errs := (QualityThresholds{MinCoverage: 2, MaxUnparseable: -1}).Validate()
+	if strings.Join(errs, "; ") != "max_unparseable: must be between 0 and 1; min_coverage: must be between 0 and 1" {
+		t.Errorf("invalid thresholds: %v", errs)
+	}
+}
+
+func TestBuildQualityReport(t *testing.T) {
+	spec := func(page, moto, power string) Spec {
+		return Spec{Source: "autoevolution", Brand: "Ducati", Model: "Monster", Moto: moto, Year: "2020",
+			URL: "https://www.autoevolution.com/moto/" + page + ".html", Specs: map[string]string{"Power": power, "Weight": "188 kg"}}
+	}
+	specs := []Spec{
+		spec("ducati-monster", "Ducati Monster", "111 hp"),
+		spec("ducati-monster-2020", "Ducati Monster", "111 hp"),
+		spec("ducati-monster-plus", "Ducati Monster Plus", "-"),
+		spec("ducati-monster-rocket", "Ducati Monster Rocket", "600 hp"),
+	}
+	brands := []Brand{{Name: "Ducati"}, {Name: "Bimota"}}
+	models := []Model{{Brand: "Ducati", Name: "Monster"}, {Brand: "Ducati", Name: "Panigale"}}
+	tests := []struct {
+		name       string
+		thresholds QualityThresholds
+		failures   int
+	}{
+		{"lenient", QualityThresholds{MaxPlaceholders: 1, MaxUnparseable: 1, MaxDuplicates: -1, MaxOutliers: -1, MaxEmptyBrands: -1, MaxEmptyModels: -1}, 0},
+		{"no outliers", QualityThresholds{MaxPlaceholders: 1, MaxUnparseable: 1, MaxDuplicates: -1, MaxOutliers: 0, MaxEmptyBrands: -1, MaxEmptyModels: -1}, 1},
+		{"strict", QualityThresholds{MinCoverage: 1}, 0},
+	}
+	for _, test := range tests {
+		r := BuildQualityReport(specs, brands, models, test.thresholds)
+		if r.Specs != 4 || r.Placeholders != 1 {
+			t.Errorf("%s: %d specs, %d placeholders", test.name, r.Specs, r.Placeholders)
+		}
+		if len(r.Outliers) != 1 || r.Outliers[0].Reason != "over 1.5 hp per kg; over 400 hp" {
+			t.Errorf("%s: outliers %+v", test.name, r.Outliers)
+		}
+		if len(r.Duplicates) != 1 || len(r.Duplicates[0].IDs) != 2 {
+			t.Errorf("%s: duplicates %+v", test.name, r.Duplicates)
+		}
+		if strings.Join(r.EmptyBrands, ",") != "Bimota" || strings.Join(r.EmptyModels, ",") != "Ducati Panigale" {
+			t.Errorf("%s: empty brands %v, models %v", test.name, r.EmptyBrands, r.EmptyModels)
+		}
+		if test.failures > 0 && len(r.Failures) != test.failures || test.name == "strict" && r.OK() || test.name == "lenient" && !r.OK() {
+			t.Errorf("%s: failures %q", test.name, r.Failures)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package motospec
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	RulePrefer   = "prefer"
+	RuleLongest  = "longest"
+	RuleMajority = "majority"
+)
+
+var ConflictRules = []string{RulePrefer, RuleLongest, RuleMajority}
+
+// Resolver groups the specs of different sources describing the same bike
+// and merges every group into a GoldenRecord.
+//
+// Two specs match when their brands normalize alike, their years overlap
+// within YearSlack and their models are similar enough; displacement, power
+// and weight agreeing within Tolerance raise the score, disagreeing ones
+// lower it. A field present in several specs of a group is settled by the
+// rule Rules names for it, "*" naming the default rule.
+type Resolver struct {
+	Threshold float64
+	Tolerance float64
+	YearSlack int
+	Sources   []string
+	Rules     map[string]string
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{
+		Threshold: 0.75,
+		Tolerance: 0.08,
+		YearSlack: 1,
+		Rules:     map[string]string{"*": RulePrefer},
+	}
+}
+
+type FieldValue struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+type GoldenRecord struct {
+	ID         string                  `json:"id"`
+	Normalized Normalized              `json:"normalized"`
+	Fields     map[string]FieldValue   `json:"fields"`
+	Specs      map[string]FieldValue   `json:"specs"`
+	Conflicts  map[string][]FieldValue `json:"conflicts,omitempty"`
+	Sources    []string                `json:"sources"`
+	Members    []string                `json:"members"`
+}
+
+func modelSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	if strings.Replace(a, " ", "", -1) == strings.Replace(b, " ", "", -1) {
+		return 1
+	}
+	as, bs := strings.Fields(a), strings.Fields(b)
+	if len(as) == 0 || len(bs) == 0 {
+		return 0
+	}
+	count := make(map[string]int)
+	for _, word := range as {
+		count[word]++
+	}
+	common := 0
+	for _, word := range bs {
+		if count[word] > 0 {
+			count[word]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(as)+len(bs))
+}
+
+func (r *Resolver) yearsOverlap(a, b Normalized) bool {
+	if a.YearFrom == 0 || b.YearFrom == 0 {
+		return true
+	}
+	return a.YearFrom <= b.YearTo+r.YearSlack && b.YearFrom <= a.YearTo+r.YearSlack
+}
+
+// Score rates how likely a and b describe the same bike, from 0 to 1.
+func (r *Resolver) Score(a, b Normalized) float64 {
+	if a.Brand == "" || a.Brand != b.Brand || !r.yearsOverlap(a, b) {
+		return 0
+	}
+	score := modelSimilarity(a.Model, b.Model)
+	for _, pair := range [][2]float64{
+		{a.Displacement, b.Displacement},
+		{a.Power, b.Power},
+		{a.Weight, b.Weight},
+	} {
+		if pair[0] == 0 || pair[1] == 0 {
+			continue
+		}
+		if math.Abs(pair[0]-pair[1]) <= r.Tolerance*math.Max(pair[0], pair[1]) {
+			score += 0.1
+		} else {
+			score -= 0.3
+		}
+	}
+	return math.Max(0, math.Min(1, score))
+}
+
+type cluster struct {
+	specs   []Spec
+	norms   []Normalized
+	sources map[string]bool
+}
+
+func (r *Resolver) rank(source string) int {
+	for i, s := range r.Sources {
+		if s == source {
+			return i
+		}
+	}
+	return len(r.Sources)
+}
+
+// Resolve groups specs, each group holding at most one spec per source,
+// and merges the groups.
+func (r *Resolver) Resolve(specs []Spec) []GoldenRecord {
+	sorted := append([]Spec{}, specs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := r.rank(sorted[i].Source), r.rank(sorted[j].Source)
+		if ri != rj {
+			return ri < rj
+		}
+		if sorted[i].Source != sorted[j].Source {
+			return sorted[i].Source < sorted[j].Source
+		}
+		return sorted[i].Key() < sorted[j].Key()
+	})
+	var clusters []*cluster
+	byBrand := make(map[string][]*cluster)
+	for _, spec := range sorted {
+		n := Normalize(spec)
+		var best *cluster
+		bestScore := 0.0
+		for _, c := range byBrand[n.Brand] {
+			if c.sources[spec.Source] {
+				continue
+			}
+			for _, other := range c.norms {
+				if score := r.Score(n, other); score > bestScore {
+					best, bestScore = c, score
+				}
+			}
+		}
+		if best == nil || bestScore < r.Threshold {
+			best = &cluster{sources: make(map[string]bool)}
+			clusters = append(clusters, best)
+			byBrand[n.Brand] = append(byBrand[n.Brand], best)
+		}
+		best.specs = append(best.specs, spec)
+		best.norms = append(best.norms, n)
+		best.sources[spec.Source] = true
+	}
+	records := make([]GoldenRecord, 0, len(clusters))
+	ids := make(map[string]int)
+	for _, c := range clusters {
+		record := r.merge(c)
+		ids[record.ID]++
+		if n := ids[record.ID]; n > 1 {
+			record.ID = fmt.Sprintf("%s-%d", record.ID, n)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func (r *Resolver) rule(field string) string {
+	if rule, ok := r.Rules[field]; ok {
+		return rule
+	}
+	if rule, ok := r.Rules["*"]; ok {
+		return rule
+	}
+	return RulePrefer
+}
+
+// pick settles one field; candidates are ordered by source preference.
+func (r *Resolver) pick(field string, candidates []FieldValue) FieldValue {
+	best := candidates[0]
+	switch r.rule(field) {
+	case RuleLongest:
+		for _, c := range candidates[1:] {
+			if len(c.Value) > len(best.Value) {
+				best = c
+			}
+		}
+	case RuleMajority:
+		votes := make(map[string]int)
+		for _, c := range candidates {
+			votes[foldValue(c.Value)]++
+		}
+		for _, c := range candidates[1:] {
+			if votes[foldValue(c.Value)] > votes[foldValue(best.Value)] {
+				best = c
+			}
+		}
+	}
+	return best
+}
+
+func foldValue(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+func conflicting(candidates []FieldValue) bool {
+	for _, c := range candidates[1:] {
+		if foldValue(c.Value) != foldValue(candidates[0].Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func slugID(parts ...string) string {
+	kept := []string{}
+	for _, part := range parts {
+		kept = append(kept, words(part)...)
+	}
+	return strings.Join(kept, "-")
+}
+
+func (r *Resolver) merge(c *cluster) GoldenRecord {
+	record := GoldenRecord{
+		Fields: make(map[string]FieldValue),
+		Specs:  make(map[string]FieldValue),
+	}
+	fields := make(map[string][]FieldValue)
+	var fieldOrder []string
+	add := func(field, value, source string) {
+		if value = strings.TrimSpace(value); value == "" {
+			return
+		}
+		if _, ok := fields[field]; !ok {
+			fieldOrder = append(fieldOrder, field)
+		}
+		fields[field] = append(fields[field], FieldValue{Value: value, Source: source})
+	}
+	specNames := make(map[string]string)
+	for _, spec := range c.specs {
+		record.Sources = append(record.Sources, spec.Source)
+		record.Members = append(record.Members, spec.Key())
+		add("brand", spec.Brand, spec.Source)
+		add("model", spec.Model, spec.Source)
+		add("type", spec.Moto, spec.Source)
+		add("year", spec.Year, spec.Source)
+		add("url", spec.URL, spec.Source)
+		add("description", spec.Description, spec.Source)
+		add("segment", spec.Segment, spec.Source)
+		add("body_style", spec.BodyStyle, spec.Source)
+		keys := make([]string, 0, len(spec.Specs))
+		for key := range spec.Specs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			name := strings.ToLower(strings.Trim(key, ": "))
+			if _, ok := specNames[name]; !ok {
+				specNames[name] = strings.Trim(key, ": ")
+			}
+			add("specs."+name, spec.Specs[key], spec.Source)
+		}
+	}
+	for _, field := range fieldOrder {
+		candidates := fields[field]
+		value := r.pick(strings.TrimPrefix(field, "specs."), candidates)
+		if conflicting(candidates) {
+			if record.Conflicts == nil {
+				record.Conflicts = make(map[string][]FieldValue)
+			}
+			record.Conflicts[field] = candidates
+		}
+		if strings.HasPrefix(field, "specs.") {
+			record.Specs[specNames[strings.TrimPrefix(field, "specs.")]] = value
+		} else {
+			record.Fields[field] = value
+		}
+	}
+	record.Normalized = c.norms[0]
+	for _, n := range c.norms[1:] {
+		if record.Normalized.YearFrom == 0 {
+			record.Normalized.YearFrom, record.Normalized.YearTo = n.YearFrom, n.YearTo
+		}
+		if record.Normalized.Displacement == 0 {
+			record.Normalized.Displacement = n.Displacement
+		}
+		if record.Normalized.Power == 0 {
+			record.Normalized.Power = n.Power
+		}
+		if record.Normalized.Weight == 0 {
+			record.Normalized.Weight = n.Weight
+		}
+	}
+	year := ""
+	if record.Normalized.YearFrom > 0 {
+		year = fmt.Sprint(record.Normalized.YearFrom)
+	}
+	record.ID = slugID(record.Normalized.Brand, record.Normalized.Model, year)
+	return record
+}
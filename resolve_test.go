@@ -0,0 +1,84 @@
+package motospec
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestResolverScore(t *testing.T) {
+	base := Normalized{Brand: "ducati", Model: "monster 821", YearFrom: 2014, YearTo: 2017, Displacement: 821, Power: 109}
+	tests := []struct {
+		name  string
+		other Normalized
+		want  float64
+	}{
+		{"same bike", base, 1},
+		{"spaced model", Normalized{Brand: "ducati", Model: "monster821", YearFrom: 2015, YearTo: 2015}, 1},
+		{"other brand", Normalized{Brand: "bmw", Model: "monster 821"}, 0},
+		{"years apart", Normalized{Brand: "ducati", Model: "monster 821", YearFrom: 2020, YearTo: 2021}, 0},
+		{"year within slack", Normalized{Brand: "ducati", Model: "monster 821", YearFrom: 2018, YearTo: 2018}, 1},
+		{"half the words", Normalized{Brand: "ducati", Model: "monster 1200"}, 0.5},
+		{"power off", Normalized{Brand: "ducati", Model: "monster 821", Displacement: 821, Power: 150}, 0.8},
+	}
+	r := NewResolver()
+	for _, test := range tests {
+		if got := r.Score(base, test.other); math.Abs(got-test.want) > 0.005 {
+			t.Errorf("%s: got %g, want %g", test.name, got, test.want)
+		}
+	}
+}
+
+func TestResolverPick(t *testing.T) {
+	candidates := []FieldValue{{"Steel trellis", "a"}, {"Steel trellis frame", "b"}, {"steel  TRELLIS", "c"}}
+	tests := []struct {
+		rules map[string]string
+		want  string
+	}{
+		{map[string]string{}, "a"},
+		{map[string]string{"*": RuleLongest}, "b"},
+		{map[string]string{"*": RuleLongest, "frame": RuleMajority}, "a"},
+		{map[string]string{"frame": RulePrefer, "*": RuleLongest}, "a"},
+	}
+	for _, test := range tests {
+		r := NewResolver()
+		r.Rules = test.rules
+		if got := r.pick("frame", candidates); got.Source != test.want {
+			t.Errorf("%v: got %+v, want the value of %s", test.rules, got, test.want)
+		}
+	}
+	if !conflicting(candidates) || conflicting(candidates[0:1]) || conflicting([]FieldValue{candidates[0], candidates[2]}) {
+		t.Error("conflicting does not fold case and spaces")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	specs := []Spec{
+		{Source: "motorcycle.com", Brand: "Ducati", Model: "Monster", Moto: "Monster 821", Year: "2017",
+			Specs: map[string]string{"Power": "109 hp", "Frame": "Steel trellis frame"}},
+		{Source: "autoevolution", Brand: "Ducati", Model: "Ducati Monster", Moto: "Ducati Monster 821", Year: "2014 - 2017",
+			URL: "https://www.autoevolution.com/moto/ducati-monster-821-2014.html", Specs: map[string]string{"Power:": "109 hp", "Frame:": "Steel trellis"}},
+		{Source: "autoevolution", Brand: "Ducati", Model: "Ducati Monster", Moto: "Ducati Monster 1200", Year: "2014 - 2016",
+			URL: "https://www.autoevolution.com/moto/ducati-monster-1200-2014.html", Specs: map[string]string{"Power:": "135 hp"}},
+		{Source: "autoevolution", Brand: "Ducati", Model: "Ducati Monster", Moto: "Ducati Monster 821", Year: "2014 - 2017",
+			URL: "https://www.autoevolution.com/moto/ducati-monster-821-dark-2014.html", Specs: map[string]string{"Power:": "109 hp"}},
+	}
+	r := NewResolver()
+	r.Sources = []string{"autoevolution", "motorcycle.com"}
+	records := r.Resolve(specs)
+	got := []string{}
+	for _, record := range records {
+		got = append(got, record.ID+" "+strings.Join(record.Sources, "+"))
+	}
+	want := []string{"ducati-monster-1200-2014 autoevolution", "ducati-monster-821-2014 autoevolution+motorcycle.com", "ducati-monster-821-2014-2 autoevolution"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	merged := records[1]
+	if merged.Specs["Frame"].Value != "Steel trellis" || merged.Fields["url"].Source != "autoevolution" || len(merged.Conflicts["specs.frame"]) != 2 {
+		t.Errorf("merged %+v", merged)
+	}
+	if merged.Normalized.Power != 109 || merged.Normalized.YearFrom != 2014 {
+		t.Errorf("normalized %+v", merged.Normalized)
+	}
+}
@@ -0,0 +1,271 @@
+package motospec
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ErrDisallowed struct {
+	URL string
+}
+
+func (e *ErrDisallowed) Error() string {
+	return fmt.Sprintf("%s: disallowed by robots.txt", e.URL)
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+type RobotsRules struct {
+	rules      []robotsRule
+	CrawlDelay time.Duration
+	Sitemaps   []string
+}
+
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+	return !anchored || rest == ""
+}
+
+// Allowed applies the longest matching rule, an Allow winning a tie.
+func (r *RobotsRules) Allowed(path string) bool {
+	best := -1
+	allowed := true
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > best || (len(rule.pattern) == best && rule.allow) {
+			best = len(rule.pattern)
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// ParseRobots keeps the group naming agent, or the "*" group when no group
+// names it. Sitemap lines are kept whatever group they appear in.
+func ParseRobots(r io.Reader, agent string) (*RobotsRules, error) {
+	agent = strings.ToLower(agent)
+	specific, wildcard := &RobotsRules{}, &RobotsRules{}
+	foundSpecific := false
+	var targets []*RobotsRules
+	inAgents := false
+	var sitemaps []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		value := strings.TrimSpace(kv[1])
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				targets = nil
+			}
+			inAgents = true
+			name := strings.ToLower(value)
+			if name == "*" {
+				targets = append(targets, wildcard)
+			} else if agent != "" && strings.Contains(agent, name) {
+				foundSpecific = true
+				targets = append(targets, specific)
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if value == "" {
+				continue
+			}
+			for _, t := range targets {
+				t.rules = append(t.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			inAgents = false
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				continue
+			}
+			for _, t := range targets {
+				t.CrawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			// not part of any group
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		default:
+			inAgents = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if foundSpecific {
+		specific.Sitemaps = sitemaps
+		return specific, nil
+	}
+	wildcard.Sitemaps = sitemaps
+	return wildcard, nil
+}
+
+// Robots fetches and caches robots.txt per host. The crawl delay of a host
+// is handed to Limiter as soon as its robots.txt is known. A robots.txt
+// that cannot be fetched for now, on a network error or a 5xx answer, is
+// tried Retries more times; when it still fails the host is disallowed for
+// Recheck only, then fetched again.
+type Robots struct {
+	Agent   string
+	Client  *http.Client
+	Headers *HeaderProfile
+	Limiter *RateLimiter
+	Retries int
+	Recheck time.Duration
+
+	mutex sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+type robotsEntry struct {
+	ready chan struct{}
+	rules *RobotsRules
+	// expires is set for the rules standing in for a failed fetch
+	expires time.Time
+}
+
+// stale tells a fetched entry whose rules have expired.
+func (e *robotsEntry) stale(now time.Time) bool {
+	select {
+	case <-e.ready:
+		return !e.expires.IsZero() && !now.Before(e.expires)
+	default:
+		return false
+	}
+}
+
+func NewRobots(agent string, client *http.Client, limiter *RateLimiter) *Robots {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Robots{
+		Agent:   agent,
+		Client:  client,
+		Limiter: limiter,
+		Retries: 2,
+		Recheck: time.Minute,
+		hosts:   make(map[string]*robotsEntry),
+	}
+}
+
+func (r *Robots) Rules(ctx context.Context, u *url.URL) *RobotsRules {
+	key := u.Scheme + "://" + u.Host
+	r.mutex.Lock()
+	entry, ok := r.hosts[key]
+	if !ok || entry.stale(time.Now()) {
+		ok = false
+		entry = &robotsEntry{ready: make(chan struct{})}
+		r.hosts[key] = entry
+	}
+	r.mutex.Unlock()
+	if ok {
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			return &RobotsRules{rules: []robotsRule{{allow: false, pattern: "/"}}}
+		}
+		return entry.rules
+	}
+	rules, temporary := r.fetch(ctx, key)
+	for attempt := 1; temporary && attempt <= r.Retries && ctx.Err() == nil; attempt++ {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(attempt) * time.Second):
+			rules, temporary = r.fetch(ctx, key)
+		}
+	}
+	entry.rules = rules
+	if temporary {
+		entry.expires = time.Now().Add(r.Recheck)
+	}
+	if r.Limiter != nil && entry.rules.CrawlDelay > 0 {
+		r.Limiter.SetMinDelay(u.Host, entry.rules.CrawlDelay)
+	}
+	close(entry.ready)
+	return entry.rules
+}
+
+// fetch follows the usual conventions: a missing robots.txt allows
+// everything, an unreachable or failing one disallows everything. It tells
+// whether the failure may be temporary.
+func (r *Robots) fetch(ctx context.Context, base string) (*RobotsRules, bool) {
+	disallowAll := &RobotsRules{rules: []robotsRule{{allow: false, pattern: "/"}}}
+	req, err := http.NewRequest("GET", base+"/robots.txt", nil)
+	if err != nil {
+		return disallowAll, false
+	}
+	if r.Headers != nil {
+		r.Headers.Apply(req)
+	}
+	resp, err := r.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		ErrClientLogger.Printf("robots.txt of %s: %v", base, err)
+		return disallowAll, true
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		ErrClientLogger.Printf("robots.txt of %s: status %d", base, resp.StatusCode)
+		return disallowAll, true
+	case resp.StatusCode >= 400:
+		return &RobotsRules{}, false
+	}
+	rules, err := ParseRobots(io.LimitReader(resp.Body, 512*1024), r.Agent)
+	if err != nil {
+		ErrClientLogger.Printf("robots.txt of %s: %v", base, err)
+		return disallowAll, true
+	}
+	ProcessorLogger.Printf("Robots: %s has %d rules, crawl delay %s", base, len(rules.rules), rules.CrawlDelay)
+	return rules, false
+}
+
+func (r *Robots) Allowed(ctx context.Context, u *url.URL) bool {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return r.Rules(ctx, u).Allowed(path)
+}
@@ -0,0 +1,134 @@
+package motospec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/", "/moto/", true},
+		{"/moto/", "/moto/ducati.html", true},
+		{"/moto/", "/motorcycles", false},
+		{"/*.php", "/index.php?x=1", true},
+		{"/*.php$", "/index.php", true},
+		{"/*.php$", "/index.php?x=1", false},
+		{"/search$", "/search", true},
+		{"/search$", "/search/", false},
+		{"/a*b*c", "/axxbyyc", true},
+		{"/a*b*c", "/axxcyyb", false},
+	}
+	for _, test := range tests {
+		if got := robotsMatch(test.pattern, test.path); got != test.want {
+			t.Errorf("%s %s: got %v", test.pattern, test.path, got)
+		}
+	}
+}
+
+const testRobots = `
+# comment
+User-agent: *
+Disallow: /private/
+Crawl-delay: 2
+
+User-agent: motospec
+User-agent: otherbot
+Disallow: /moto/
+Allow: /moto/*.html$
+Crawl-delay: 0.5
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		agent   string
+		path    string
+		allowed bool
+		delay   time.Duration
+	}{
+		{"motospec/1.0", "/moto/ducati.html", true, 500 * time.Millisecond},
+		{"motospec/1.0", "/moto/", false, 500 * time.Millisecond},
+		{"motospec/1.0", "/private/", true, 500 * time.Millisecond},
+		{"somebot", "/private/x", false, 2 * time.Second},
+		{"somebot", "/moto/", true, 2 * time.Second},
+		{"", "/private/", false, 2 * time.Second},
+	}
+	for _, test := range tests {
+		rules, err := ParseRobots(strings.NewReader(testRobots), test.agent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rules.Allowed(test.path); got != test.allowed {
+			t.Errorf("%s %s: allowed %v, want %v", test.agent, test.path, got, test.allowed)
+		}
+		if rules.CrawlDelay != test.delay {
+			t.Errorf("%s: crawl delay %s, want %s", test.agent, rules.CrawlDelay, test.delay)
+		}
+		if len(rules.Sitemaps) != 1 {
+			t.Errorf("%s: sitemaps %v", test.agent, rules.Sitemaps)
+		}
+	}
+}
+
+// robotsServer answers robots.txt with the statuses given, then with 200.
+func robotsServer(statuses ...int) (*httptest.Server, *int) {
+	var mutex sync.Mutex
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		fetches++
+		n := fetches
+		mutex.Unlock()
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+	}))
+	return server, &fetches
+}
+
+func TestRobotsTemporaryFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		first    bool
+		later    bool
+		fetches  int
+	}{
+		{"ok", nil, 0, true, true, 1},
+		{"missing", []int{404}, 0, true, true, 1},
+		{"5xx then rechecked", []int{503}, 0, false, true, 2},
+		{"5xx then retried", []int{500}, 1, true, true, 2},
+	}
+	for _, test := range tests {
+		server, fetches := robotsServer(test.statuses...)
+		robots := NewRobots("motospec", nil, nil)
+		robots.Retries = test.retries
+		robots.Recheck = 10 * time.Millisecond
+		u, _ := url.Parse(server.URL + "/moto/")
+		if got := robots.Allowed(context.Background(), u); got != test.first {
+			t.Errorf("%s: first allowed %v, want %v", test.name, got, test.first)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if got := robots.Allowed(context.Background(), u); got != test.later {
+			t.Errorf("%s: later allowed %v, want %v", test.name, got, test.later)
+		}
+		robots.Allowed(context.Background(), u)
+		if *fetches != test.fetches {
+			t.Errorf("%s: %d fetches, want %d", test.name, *fetches, test.fetches)
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,191 @@
+package motospec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaFields are the identity fields a schema can require, by their json
+// names.
+var SchemaFields = map[string]func(Spec) string{
+	"source": func(s Spec) string { return s.Source },
+	"brand":  func(s Spec) string { return s.Brand },
+	"model":  func(s Spec) string { return s.Model },
+	"type":   func(s Spec) string { return s.Moto },
+	"year":   func(s Spec) string { return s.Year },
+	"url":    func(s Spec) string { return s.URL },
+}
+
+// SchemaRanges are the normalized numbers a schema can bound.
+var SchemaRanges = map[string]func(Normalized) float64{
+	"year":         func(n Normalized) float64 { return float64(n.YearFrom) },
+	"displacement": func(n Normalized) float64 { return n.Displacement },
+	"power":        func(n Normalized) float64 { return n.Power },
+	"weight":       func(n Normalized) float64 { return n.Weight },
+}
+
+// Powertrains are the values of Powertrain.
+var Powertrains = []string{"electric", "two-stroke", "four-stroke", "other"}
+
+// KeyRules name the specs a powertrain must and must not have a value for,
+// matched as SpecValue matches names.
+type KeyRules struct {
+	Required  []string `yaml:"required"`
+	Forbidden []string `yaml:"forbidden"`
+}
+
+// SchemaConfig is the declarative schema of the validate stage. Ranges hold
+// ParseRange ranges of the normalized year, displacement (cc), power (hp)
+// and weight (kg); a value that could not be normalized is not checked.
+type SchemaConfig struct {
+	Required    []string            `yaml:"required"`
+	MinSpecs    int                 `yaml:"min_specs"`
+	Powertrains map[string]KeyRules `yaml:"powertrains"`
+	Ranges      map[string]string   `yaml:"ranges"`
+}
+
+func (c SchemaConfig) Validate() []string {
+	errs := []string{}
+	for i, field := range c.Required {
+		if _, ok := SchemaFields[field]; !ok {
+			errs = append(errs, fmt.Sprintf("required[%d]: unknown field %q", i, field))
+		}
+	}
+	if c.MinSpecs < 0 {
+		errs = append(errs, "min_specs: must not be negative")
+	}
+	for name := range c.Powertrains {
+		known := false
+		for _, p := range Powertrains {
+			known = known || p == name
+		}
+		if !known {
+			errs = append(errs, fmt.Sprintf("powertrains: unknown powertrain %q, known are %s", name, strings.Join(Powertrains, ", ")))
+		}
+	}
+	for name, s := range c.Ranges {
+		if _, ok := SchemaRanges[name]; !ok {
+			errs = append(errs, fmt.Sprintf("ranges: unknown value %q", name))
+		} else if _, err := ParseRange(s); err != nil {
+			errs = append(errs, fmt.Sprintf("ranges.%s: %v", name, err))
+		}
+	}
+	sort.Strings(errs)
+	return errs
+}
+
+type Schema struct {
+	Required    []string
+	MinSpecs    int
+	Powertrains map[string]KeyRules
+	Ranges      map[string]Range
+}
+
+func (c SchemaConfig) Build() (*Schema, error) {
+	if errs := c.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("schema: %s", strings.Join(errs, "; "))
+	}
+	schema := &Schema{
+		Required:    c.Required,
+		MinSpecs:    c.MinSpecs,
+		Powertrains: c.Powertrains,
+		Ranges:      make(map[string]Range, len(c.Ranges)),
+	}
+	for name, s := range c.Ranges {
+		schema.Ranges[name], _ = ParseRange(s)
+	}
+	return schema, nil
+}
+
+// Check returns the reasons spec breaks the schema, none when it is valid.
+// Placeholder values count as missing.
+func (s *Schema) Check(spec Spec) []string {
+	reasons := []string{}
+	for _, field := range s.Required {
+		if value := SchemaFields[field](spec); strings.TrimSpace(value) == "" || IsPlaceholder(value) {
+			reasons = append(reasons, field+": required")
+		}
+	}
+	values := 0
+	for _, value := range spec.Specs {
+		if strings.TrimSpace(value) != "" && !IsPlaceholder(value) {
+			values++
+		}
+	}
+	if values < s.MinSpecs {
+		reasons = append(reasons, fmt.Sprintf("specs: %d values, at least %d required", values, s.MinSpecs))
+	}
+	has := func(name string) bool {
+		value := SpecValue(spec, name)
+		return strings.TrimSpace(value) != "" && !IsPlaceholder(value)
+	}
+	powertrain := Powertrain(spec)
+	rules := s.Powertrains[powertrain]
+	for _, name := range rules.Required {
+		if !has(name) {
+			reasons = append(reasons, fmt.Sprintf("%s: required for %s", name, powertrain))
+		}
+	}
+	for _, name := range rules.Forbidden {
+		if has(name) {
+			reasons = append(reasons, fmt.Sprintf("%s: not allowed for %s", name, powertrain))
+		}
+	}
+	n := Normalize(spec)
+	names := make([]string, 0, len(s.Ranges))
+	for name := range s.Ranges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r := s.Ranges[name]
+		if value := SchemaRanges[name](n); value != 0 && !r.Contains(value) {
+			reasons = append(reasons, fmt.Sprintf("%s: %g out of range %s", name, value, formatRange(r)))
+		}
+	}
+	return reasons
+}
+
+func formatRange(r Range) string {
+	if r.Max == 0 {
+		return fmt.Sprintf("%g-", r.Min)
+	}
+	return fmt.Sprintf("%g-%g", r.Min, r.Max)
+}
+
+// Quarantined is the record the quarantine sink receives for an invalid
+// spec.
+type Quarantined struct {
+	Reasons []string `json:"reasons"`
+	Spec    Spec     `json:"spec"`
+}
+
+// ErrQuarantined tells a spec failed the schema. The pipeline's error
+// handler records it in the quarantine sink.
+type ErrQuarantined struct {
+	Spec    Spec
+	Reasons []string
+}
+
+func (e *ErrQuarantined) Error() string {
+	return fmt.Sprintf("quarantined %s %s %s: %s", e.Spec.Brand, e.Spec.Moto, e.Spec.Year, strings.Join(e.Reasons, "; "))
+}
+
+// ValidateFunc builds the post-processing stage passing on the specs valid
+// under schema and quarantining the others.
+func ValidateFunc(schema *Schema) ProcessFunc {
+	return func(p *Processor, input interface{}) {
+		spec, ok := input.(Spec)
+		if !ok {
+			p.Error <- fmt.Errorf("%v is not a valid Spec", input)
+			return
+		}
+		reasons := schema.Check(spec)
+		if len(reasons) == 0 {
+			p.Output <- spec
+			return
+		}
+		p.Error <- &ErrQuarantined{Spec: spec, Reasons: reasons}
+	}
+}
@@ -0,0 +1,73 @@
+package motospec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSchemaConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config SchemaConfig
+		want   string
+	}{
+		{"valid", SchemaConfig{Required: []string{"brand", "url"}, MinSpecs: 3,
+			Powertrains: map[string]KeyRules{"electric": {Required: []string{"battery"}}},
+			Ranges:      map[string]string{"year": "1885-", "weight": "40-600"}}, ""},
+		{"unknown field", SchemaConfig{Required: []string{"brand", "colour"}}, `required[1]: unknown field "colour"`},
+		{"negative min specs", SchemaConfig{MinSpecs: -1}, "min_specs: must not be negative"},
+		{"unknown powertrain", SchemaConfig{Powertrains: map[string]KeyRules{"steam": {}}},
+			`powertrains: unknown powertrain "steam", known are electric, two-stroke, four-stroke, other`},
+		{"unknown range", SchemaConfig{Ranges: map[string]string{"torque": "1-200"}}, `ranges: unknown value "torque"`},
+		{"invalid range", SchemaConfig{Ranges: map[string]string{"power": "lots"}}, `ranges.power: invalid range "lots"`},
+	}
+	for _, test := range tests {
+		if got := strings.Join(test.config.Validate(), "; "); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+		if _, err := test.config.Build(); (err == nil) != (test.want == "") {
+			t.Errorf("%s: Build %v", test.name, err)
+		}
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	schema, err := SchemaConfig{
+		Required: []string{"brand", "model", "year"},
+		MinSpecs: 2,
+		Powertrains: map[string]KeyRules{
+			"electric":    {Required: []string{"battery"}, Forbidden: []string{"displacement"}},
+			"four-stroke": {Required: []string{"displacement"}},
+		},
+		Ranges: map[string]string{"year": "1885-", "power": "-400", "weight": "40-600"},
+	}.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		spec Spec
+		want string
+	}{
+		{"valid", Spec{Brand: "Ducati", Model: "Monster", Year: "2017",
+			Specs: map[string]string{"Type": "L-twin, four-stroke", "Displacement": "821 cc", "Weight": "206 kg"}}, ""},
+		{"placeholder identity", Spec{Brand: "Ducati", Model: "-", Year: "",
+			Specs: map[string]string{"Type": "V-twin", "Weight": "206 kg"}}, "model: required; year: required"},
+		{"too few specs", Spec{Brand: "Ducati", Model: "Monster", Year: "2017",
+			Specs: map[string]string{"Type": "V-twin", "Weight": "-", "Power": "n/a"}}, "specs: 1 values, at least 2 required"},
+		{"powertrain keys", Spec{Brand: "Zero", Model: "SR/F", Year: "2020",
+			Specs: map[string]string{"Type": "Electric motor", "Displacement": "0 cc", "Weight": "220 kg"}},
+			"battery: required for electric; displacement: not allowed for electric"},
+		{"four-stroke without displacement", Spec{Brand: "Honda", Model: "CB", Year: "2020",
+			Specs: map[string]string{"Type": "Inline-four, four-stroke", "Displacement": "-", "Weight": "220 kg"}},
+			"displacement: required for four-stroke"},
+		{"out of range", Spec{Brand: "Ducati", Model: "Monster", Year: "1850",
+			Specs: map[string]string{"Type": "V-twin", "Power": "500 hp", "Weight": "20 kg"}},
+			"power: 500 out of range 0-400; weight: 20 out of range 40-600; year: 1850 out of range 1885-"},
+	}
+	for _, test := range tests {
+		if got := strings.Join(schema.Check(test.spec), "; "); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
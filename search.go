@@ -0,0 +1,313 @@
+package motospec
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SearchFields names the specs whose values are indexed, by a part of the
+// spec name, besides the names and the description.
+var SearchFields = []string{
+	"type", "engine", "gearbox", "transmission", "clutch", "brake", "fuel system",
+	"cooling", "final drive", "frame", "suspension",
+}
+
+// Facets, in the order they are reported.
+var Facets = []string{"brand", "decade", "powertrain", "displacement"}
+
+// field weights of a term
+const (
+	nameWeight        = 3
+	descriptionWeight = 1
+	specWeight        = 1
+)
+
+type SearchDoc struct {
+	ID     string            `json:"id"`
+	Source string            `json:"source,omitempty"`
+	Brand  string            `json:"brand"`
+	Model  string            `json:"model"`
+	Moto   string            `json:"type"`
+	Year   string            `json:"year"`
+	URL    string            `json:"url,omitempty"`
+	Facets map[string]string `json:"facets"`
+}
+
+type Posting struct {
+	Doc    int
+	Weight float64
+}
+
+// SearchIndex is an inverted index of specs, terms being the lower case
+// words of the indexed fields.
+type SearchIndex struct {
+	Docs  []SearchDoc
+	Terms map[string][]Posting
+}
+
+// Powertrain tells electric bikes from two and four stroke ones.
+func Powertrain(spec Spec) string {
+	engine := strings.ToLower(SpecValue(spec, "type", "engine type", "engine"))
+	engine = strings.Replace(engine, "- ", "-", -1)
+	battery := strings.Trim(SpecValue(spec, "power pack", "battery"), " -")
+	switch {
+	case battery != "" || strings.Contains(engine, "electric motor"):
+		return "electric"
+	case strings.Contains(engine, "two-stroke") || strings.Contains(engine, "2-stroke"):
+		return "two-stroke"
+	case strings.Contains(engine, "four-stroke") || strings.Contains(engine, "4-stroke"):
+		return "four-stroke"
+	}
+	return "other"
+}
+
+var displacementBuckets = []struct {
+	max  float64
+	name string
+}{{125, "<125"}, {250, "125-250"}, {500, "250-500"}, {750, "500-750"}, {1000, "750-1000"}}
+
+func DisplacementBucket(cc float64) string {
+	if cc == 0 {
+		return "unknown"
+	}
+	for _, bucket := range displacementBuckets {
+		if cc <= bucket.max {
+			return bucket.name
+		}
+	}
+	return "1000+"
+}
+
+func Decade(year int) string {
+	if year == 0 {
+		return "unknown"
+	}
+	return strconv.Itoa(year/10*10) + "s"
+}
+
+// SpecFacets returns the facet values of a spec, the brand normalized so it
+// groups the spellings of all sources.
+func SpecFacets(spec Spec, n Normalized) map[string]string {
+	return map[string]string{
+		"brand":        n.Brand,
+		"decade":       Decade(n.YearFrom),
+		"powertrain":   Powertrain(spec),
+		"displacement": DisplacementBucket(n.Displacement),
+	}
+}
+
+func terms(text string) []string {
+	return words(tagPattern.ReplaceAllString(text, " "))
+}
+
+func BuildSearchIndex(specs []Spec) *SearchIndex {
+	index := &SearchIndex{Docs: make([]SearchDoc, len(specs)), Terms: make(map[string][]Posting)}
+	for i, spec := range specs {
+		n := Normalize(spec)
+		index.Docs[i] = SearchDoc{
+			ID:     SpecID(spec),
+			Source: spec.Source,
+			Brand:  spec.Brand,
+			Model:  spec.Model,
+			Moto:   spec.Moto,
+			Year:   spec.Year,
+			URL:    spec.URL,
+			Facets: SpecFacets(spec, n),
+		}
+		weights := make(map[string]float64)
+		add := func(text string, weight float64) {
+			for _, term := range terms(text) {
+				weights[term] += weight
+			}
+		}
+		add(spec.Brand+" "+spec.Model+" "+spec.Moto, nameWeight)
+		add(spec.Description, descriptionWeight)
+		for key, value := range spec.Specs {
+			name := strings.ToLower(key)
+			for _, field := range SearchFields {
+				if strings.Contains(name, field) {
+					add(value, specWeight)
+					break
+				}
+			}
+		}
+		for term, weight := range weights {
+			index.Terms[term] = append(index.Terms[term], Posting{Doc: i, Weight: weight})
+		}
+	}
+	return index
+}
+
+func LoadSearchIndex(path string) (*SearchIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	index := &SearchIndex{}
+	if err := gob.NewDecoder(file).Decode(index); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return index, nil
+}
+
+func (index *SearchIndex) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(file).Encode(index); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// SearchQuery matches the docs holding every word of Text, a word ending
+// in * matching the terms it starts, and the value of every facet in
+// Filters.
+type SearchQuery struct {
+	Text    string
+	Filters map[string]string
+	From    int
+	Size    int
+}
+
+type SearchHit struct {
+	SearchDoc
+	Score float64 `json:"score"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type SearchResult struct {
+	Total  int                     `json:"total"`
+	Hits   []SearchHit             `json:"hits"`
+	Facets map[string][]FacetCount `json:"facets"`
+}
+
+// postings returns the postings of a query word, summed over the terms it
+// matches by prefix.
+func (index *SearchIndex) postings(word string) map[int]float64 {
+	found := make(map[int]float64)
+	add := func(term string) {
+		idf := math.Log(1 + float64(len(index.Docs))/float64(len(index.Terms[term])))
+		for _, posting := range index.Terms[term] {
+			found[posting.Doc] += posting.Weight * idf
+		}
+	}
+	if strings.HasSuffix(word, "*") {
+		prefix := strings.TrimSuffix(word, "*")
+		for term := range index.Terms {
+			if strings.HasPrefix(term, prefix) {
+				add(term)
+			}
+		}
+	} else {
+		add(word)
+	}
+	return found
+}
+
+// Search ranks the matching docs by score and counts the facet values of
+// all of them, not only of the returned page.
+func (index *SearchIndex) Search(q SearchQuery) SearchResult {
+	var scores map[int]float64
+	words := strings.Fields(strings.ToLower(q.Text))
+	for _, word := range words {
+		prefix := strings.HasSuffix(word, "*")
+		for _, term := range terms(word) {
+			if prefix {
+				term += "*"
+			}
+			found := index.postings(term)
+			if scores == nil {
+				scores = found
+				continue
+			}
+			for doc, score := range scores {
+				if extra, ok := found[doc]; ok {
+					scores[doc] = score + extra
+				} else {
+					delete(scores, doc)
+				}
+			}
+		}
+	}
+	if scores == nil {
+		scores = make(map[int]float64, len(index.Docs))
+		// words of punctuation only leave no terms and match nothing
+		if len(words) == 0 {
+			for doc := range index.Docs {
+				scores[doc] = 0
+			}
+		}
+	}
+	hits := []SearchHit{}
+	counts := make(map[string]map[string]int)
+	for doc, score := range scores {
+		d := index.Docs[doc]
+		if !matchFacets(d, q.Filters) {
+			continue
+		}
+		hits = append(hits, SearchHit{SearchDoc: d, Score: score})
+		for name, value := range d.Facets {
+			if counts[name] == nil {
+				counts[name] = make(map[string]int)
+			}
+			counts[name][value]++
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	result := SearchResult{Total: len(hits), Facets: make(map[string][]FacetCount)}
+	for name, values := range counts {
+		for value, count := range values {
+			result.Facets[name] = append(result.Facets[name], FacetCount{Value: value, Count: count})
+		}
+		sort.Slice(result.Facets[name], func(i, j int) bool {
+			a, b := result.Facets[name][i], result.Facets[name][j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Value < b.Value
+		})
+	}
+	from, size := q.From, q.Size
+	if size <= 0 {
+		size = DefaultPerPage
+	}
+	if from > len(hits) {
+		from = len(hits)
+	}
+	end := from + size
+	if end > len(hits) {
+		end = len(hits)
+	}
+	result.Hits = hits[from:end]
+	return result
+}
+
+func matchFacets(doc SearchDoc, filters map[string]string) bool {
+	for name, value := range filters {
+		if name == "brand" {
+			value = NormalizeBrand(value)
+		}
+		if !strings.EqualFold(doc.Facets[name], value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package motospec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPowertrain(t *testing.T) {
+	tests := []struct {
+		specs map[string]string
+		want  string
+	}{
+		{map[string]string{"Type": "L-twin, four-stroke"}, "four-stroke"},
+		{map[string]string{"Engine": "Single cylinder, 2- stroke"}, "two-stroke"},
+		{map[string]string{"Type": "Electric motor"}, "electric"},
+		{map[string]string{"Type": "-", "Battery": "Lithium-ion, 14.4 kWh"}, "electric"},
+		{map[string]string{"Type": "-", "Battery": "-"}, "other"},
+		{map[string]string{}, "other"},
+	}
+	for _, test := range tests {
+		if got := Powertrain(Spec{Specs: test.specs}); got != test.want {
+			t.Errorf("%v: got %s, want %s", test.specs, got, test.want)
+		}
+	}
+}
+
+func TestFacetBuckets(t *testing.T) {
+	buckets := []struct {
+		cc   float64
+		want string
+	}{
+		{0, "unknown"}, {49, "<125"}, {125, "<125"}, {126, "125-250"}, {749, "500-750"}, {1000, "750-1000"}, {1802, "1000+"},
+	}
+	for _, test := range buckets {
+		if got := DisplacementBucket(test.cc); got != test.want {
+			t.Errorf("%g cc: got %s, want %s", test.cc, got, test.want)
+		}
+	}
+	decades := []struct {
+		year int
+		want string
+	}{
+		{0, "unknown"}, {1957, "1950s"}, {2020, "2020s"},
+	}
+	for _, test := range decades {
+		if got := Decade(test.year); got != test.want {
+			t.Errorf("%d: got %s, want %s", test.year, got, test.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	specs := append([]Spec{}, apiSpecs...)
+	specs[0].Description = "The naked Monster with a trellis frame."
+	specs[3].Specs = map[string]string{"Displacement": "1802 cc", "Type": "Boxer, four-stroke"}
+	index := BuildSearchIndex(specs)
+	tests := []struct {
+		name   string
+		query  SearchQuery
+		total  int
+		first  string
+		facets string
+	}{
+		{"all", SearchQuery{}, 4, "", "ducati 3, bmw 1"},
+		{"word", SearchQuery{Text: "monster"}, 2, "", "ducati 2"},
+		{"every word", SearchQuery{Text: "Monster 1200"}, 1, "Ducati Monster 1200", "ducati 1"},
+		{"description ranks lower", SearchQuery{Text: "trellis"}, 1, "Ducati Monster 821", "ducati 1"},
+		{"prefix", SearchQuery{Text: "scram*"}, 1, "Ducati Scrambler Icon", "ducati 1"},
+		{"spec value", SearchQuery{Text: "boxer"}, 1, "BMW R 18", "bmw 1"},
+		{"no match", SearchQuery{Text: "monster boxer"}, 0, "", ""},
+		{"punctuation", SearchQuery{Text: "-- ?!"}, 0, "", ""},
+		{"bare star", SearchQuery{Text: "*"}, 0, "", ""},
+		{"brand filter", SearchQuery{Filters: map[string]string{"brand": "Ducati Motor"}}, 3, "", "ducati 3"},
+		{"facet filter", SearchQuery{Text: "ducati", Filters: map[string]string{"displacement": "1000+", "decade": "2010s"}}, 1, "Ducati Monster 1200", "ducati 1"},
+		{"page", SearchQuery{From: 3, Size: 2}, 4, "", "ducati 3, bmw 1"},
+	}
+	for _, test := range tests {
+		result := index.Search(test.query)
+		if result.Total != test.total {
+			t.Errorf("%s: %d hits, want %d", test.name, result.Total, test.total)
+		}
+		if test.first != "" && (len(result.Hits) == 0 || result.Hits[0].Moto != test.first) {
+			t.Errorf("%s: hits %+v, want %s first", test.name, result.Hits, test.first)
+		}
+		facets := []string{}
+		for _, count := range result.Facets["brand"] {
+			facets = append(facets, fmt.Sprintf("%s %d", count.Value, count.Count))
+		}
+		if strings.Join(facets, ", ") != test.facets {
+			t.Errorf("%s: brand facets %q, want %q", test.name, facets, test.facets)
+		}
+	}
+	if hits := index.Search(SearchQuery{From: 3, Size: 2}).Hits; len(hits) != 1 {
+		t.Errorf("last page: %d hits", len(hits))
+	}
+}
+
+func TestSearchIndexSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.gob")
+	index := BuildSearchIndex(apiSpecs)
+	if err := index.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSearchIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, index) {
+		t.Error("the loaded index differs from the saved one")
+	}
+	ioutil.WriteFile(path, []byte("not gob"), 0664)
+	if _, err := LoadSearchIndex(path); err == nil {
+		t.Error("loaded a corrupt index")
+	}
+}
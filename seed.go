@@ -0,0 +1,190 @@
+package motospec
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	BrandStage = iota
+	ModelStage
+	MotoStage
+	SpecStage
+)
+
+var DefaultStages = []ProcessFunc{BrandFunc, ModelFunc, MotoFunc, SpecFunc}
+
+type Seed struct {
+	Stage int
+	Item  interface{}
+}
+
+// ParseSeed maps an autoevolution URL onto the stage that consumes it:
+//
+//	/moto/                            brand index -> BrandFunc
+//	/moto/<brand>/                    brand page  -> ModelFunc
+//	/moto/<brand>/<model>/            model page  -> MotoFunc
+//	/moto/<brand>-<moto>-<year>.html  spec page   -> SpecFunc
+//
+// Names the page would normally inherit from upstream are derived from the
+// URL slugs.
+func ParseSeed(rawURL string) (Seed, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Seed{}, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return Seed{}, fmt.Errorf("%s is not an absolute url", rawURL)
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) == 0 || segments[0] != "moto" {
+		return Seed{}, fmt.Errorf("%s is not a moto url", rawURL)
+	}
+	segments = segments[1:]
+	switch {
+	case len(segments) == 0:
+		return Seed{Stage: BrandStage, Item: rawURL}, nil
+	case len(segments) == 1 && strings.HasSuffix(segments[0], ".html"):
+		return Seed{Stage: SpecStage, Item: MotoURLFromSlug(rawURL, segments[0])}, nil
+	case len(segments) == 1:
+		return Seed{Stage: ModelStage, Item: BrandURL{Brand: SlugName(segments[0]), URL: rawURL}}, nil
+	case len(segments) == 2:
+		return Seed{Stage: MotoStage, Item: ModelURL{
+			Brand: SlugName(segments[0]),
+			Model: SlugName(segments[0]) + " " + SlugName(segments[1]),
+			URL:   rawURL,
+		}}, nil
+	}
+	return Seed{}, fmt.Errorf("%s does not match any stage", rawURL)
+}
+
+func MotoURLFromSlug(rawURL, slug string) MotoURL {
+	words := strings.Split(strings.TrimSuffix(slug, ".html"), "-")
+	moto := MotoURL{URL: rawURL}
+	if n := len(words); n > 1 && isYear(words[n-1]) {
+		moto.Year = words[n-1]
+		words = words[:n-1]
+	}
+	if len(words) > 0 {
+		moto.Brand = SlugName(words[0])
+		moto.Model = SlugName(strings.Join(words, "-"))
+		moto.Moto = SlugName(strings.Join(words[1:], "-"))
+	}
+	return moto
+}
+
+// BrandNames maps the url slug of every brand of a site to its name as the
+// brand listing spells it, e.g. harley-davidson to HARLEY-DAVIDSON.
+type BrandNames map[string]string
+
+func NewBrandNames(brands []BrandURL) BrandNames {
+	names := make(BrandNames, len(brands))
+	for _, brand := range brands {
+		if slug := path.Base(strings.TrimSuffix(brand.URL, "/")); slug != "" && slug != "." {
+			names[slug] = brand.Brand
+		}
+	}
+	return names
+}
+
+// Resolve gives moto the brand whose slug its page slug starts with, the
+// longest match winning, so harley-davidson-sportster-2019.html is not
+// taken for a Harley. A page of no known brand keeps the first word.
+func (b BrandNames) Resolve(moto MotoURL) MotoURL {
+	slug := strings.TrimSuffix(path.Base(moto.URL), ".html")
+	words := strings.Split(slug, "-")
+	if n := len(words); n > 1 && isYear(words[n-1]) {
+		words = words[:n-1]
+	}
+	for n := len(words); n > 0; n-- {
+		if name, ok := b[strings.Join(words[:n], "-")]; ok {
+			moto.Brand = name
+			moto.Moto = SlugName(strings.Join(words[n:], "-"))
+			return moto
+		}
+	}
+	return moto
+}
+
+// SiteBrands reads the brand listing of every seed of the current site
+// served by its first stage, fetching the way p does.
+func SiteBrands(ctx context.Context, config *Config, p *Processor) (BrandNames, error) {
+	stages, err := config.StageFuncList()
+	if err != nil {
+		return nil, err
+	}
+	site := config.CurrentSite()
+	adapter := config.Adapter()
+	brands := []BrandURL{}
+	for _, rawURL := range site.Seeds {
+		if seed, err := adapter.ParseSeed(rawURL); err != nil || seed.Stage != 0 {
+			continue
+		}
+		outputs, errs := runOnce(ctx, stages[0], rawURL, func(q *Processor) {
+			q.Configure(config, site.Stages[0])
+			q.Interval = 0
+			q.Workers = 1
+			q.Session = p.Session
+			q.Robots = p.Robots
+			q.Limiter = p.Limiter
+		})
+		if len(outputs) == 0 && len(errs) > 0 {
+			return nil, errs[0]
+		}
+		for _, output := range outputs {
+			if brand, ok := output.(BrandURL); ok {
+				brands = append(brands, brand)
+			}
+		}
+	}
+	return NewBrandNames(brands), nil
+}
+
+func isYear(s string) bool {
+	if len(s) != 4 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func SlugName(slug string) string {
+	words := strings.FieldsFunc(slug, func(r rune) bool { return r == '-' || r == '_' })
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func StageOf(item interface{}) int {
+	switch item.(type) {
+	case BrandURL:
+		return ModelStage
+	case ModelURL:
+		return MotoStage
+	case MotoURL:
+		return SpecStage
+	}
+	return BrandStage
+}
+
+func InputURL(item interface{}) string {
+	switch i := item.(type) {
+	case string:
+		return i
+	case BrandURL:
+		return i.URL
+	case ModelURL:
+		return i.URL
+	case MotoURL:
+		return i.URL
+	}
+	return fmt.Sprint(item)
+}
@@ -0,0 +1,79 @@
+package motospec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSeed(t *testing.T) {
+	tests := []struct {
+		url  string
+		want Seed
+		ok   bool
+	}{
+		{"https://www.autoevolution.com/moto/", Seed{Stage: BrandStage, Item: "https://www.autoevolution.com/moto/"}, true},
+		{"https://www.autoevolution.com/moto/harley-davidson/", Seed{Stage: ModelStage, Item: BrandURL{
+			Brand: "Harley Davidson", URL: "https://www.autoevolution.com/moto/harley-davidson/"}}, true},
+		{"https://www.autoevolution.com/moto/ducati/monster/", Seed{Stage: MotoStage, Item: ModelURL{
+			Brand: "Ducati", Model: "Ducati Monster", URL: "https://www.autoevolution.com/moto/ducati/monster/"}}, true},
+		{"https://www.autoevolution.com/moto/ducati-monster-821-2017.html", Seed{Stage: SpecStage, Item: MotoURL{
+			Brand: "Ducati", Model: "Ducati Monster 821", Moto: "Monster 821", Year: "2017",
+			URL: "https://www.autoevolution.com/moto/ducati-monster-821-2017.html"}}, true},
+		{"/moto/ducati/", Seed{}, false},
+		{"https://www.autoevolution.com/cars/", Seed{}, false},
+		{"https://www.autoevolution.com/moto/ducati/monster/821/", Seed{}, false},
+	}
+	for _, test := range tests {
+		seed, err := ParseSeed(test.url)
+		if (err == nil) != test.ok || !reflect.DeepEqual(seed, test.want) {
+			t.Errorf("%s: got %+v %v, want %+v", test.url, seed, err, test.want)
+		}
+		if err == nil && test.want.Stage != BrandStage && StageOf(seed.Item) != seed.Stage {
+			t.Errorf("%s: StageOf %d, want %d", test.url, StageOf(seed.Item), seed.Stage)
+		}
+		if err == nil && InputURL(seed.Item) != test.url {
+			t.Errorf("%s: InputURL %s", test.url, InputURL(seed.Item))
+		}
+	}
+}
+
+func TestMotoURLFromSlug(t *testing.T) {
+	tests := []struct {
+		slug string
+		want MotoURL
+	}{
+		{"bmw-r-1250-gs-2019.html", MotoURL{Brand: "Bmw", Model: "Bmw R 1250 Gs", Moto: "R 1250 Gs", Year: "2019"}},
+		{"ducati-1199-panigale.html", MotoURL{Brand: "Ducati", Model: "Ducati 1199 Panigale", Moto: "1199 Panigale"}},
+		{"ducati-2019.html", MotoURL{Brand: "Ducati", Model: "Ducati", Year: "2019"}},
+	}
+	for _, test := range tests {
+		if got := MotoURLFromSlug("", test.slug); got != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.slug, got, test.want)
+		}
+	}
+}
+
+func TestBrandNamesResolve(t *testing.T) {
+	brands := NewBrandNames([]BrandURL{
+		{Brand: "HARLEY-DAVIDSON", URL: "https://www.autoevolution.com/moto/harley-davidson/"},
+		{Brand: "MV", URL: "https://www.autoevolution.com/moto/mv/"},
+		{Brand: "MV AGUSTA", URL: "https://www.autoevolution.com/moto/mv-agusta/"},
+		{Brand: "DUCATI", URL: "https://www.autoevolution.com/moto/ducati/"},
+	})
+	tests := []struct {
+		slug        string
+		brand, moto string
+	}{
+		{"harley-davidson-sportster-883-2019.html", "HARLEY-DAVIDSON", "Sportster 883"},
+		{"mv-agusta-f4-2010.html", "MV AGUSTA", "F4"},
+		{"mv-250-1955.html", "MV", "250"},
+		{"ducati-2019.html", "DUCATI", ""},
+		{"royal-enfield-bullet-500-2015.html", "Royal", "Enfield Bullet 500"},
+	}
+	for _, test := range tests {
+		moto := brands.Resolve(MotoURLFromSlug("https://www.autoevolution.com/moto/"+test.slug, test.slug))
+		if moto.Brand != test.brand || moto.Moto != test.moto {
+			t.Errorf("%s: got %q %q, want %q %q", test.slug, moto.Brand, moto.Moto, test.brand, test.moto)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package motospec
+
+import "reflect"
+
+type Selectors struct {
+	BrandList       string `yaml:"brand_list"`
+	BrandLink       string `yaml:"brand_link"`
+	BrandLogo       string `yaml:"brand_logo"`
+	BrandModelCount string `yaml:"brand_model_count"`
+	BrandInfo       string `yaml:"brand_info"`
+	ModelList       string `yaml:"model_list"`
+	ModelName       string `yaml:"model_name"`
+	ModelImage      string `yaml:"model_image"`
+	ModelYears      string `yaml:"model_years"`
+	ModelStatus     string `yaml:"model_status"`
+	MotoList        string `yaml:"moto_list"`
+	MotoName        string `yaml:"moto_name"`
+	MotoYears       string `yaml:"moto_years"`
+	MotoLink        string `yaml:"moto_link"`
+	SpecTable       string `yaml:"spec_table"`
+	SpecKey         string `yaml:"spec_key"`
+	SpecValue       string `yaml:"spec_value"`
+	Description     string `yaml:"description"`
+	ModelInfo       string `yaml:"model_info"`
+	Gallery         string `yaml:"gallery"`
+	Related         string `yaml:"related"`
+	Option          string `yaml:"option"`
+	SpecCell        string `yaml:"spec_cell"`
+}
+
+var DefaultSelectors = Selectors{
+	BrandList:       `.carman`,
+	BrandLink:       `h5 a`,
+	BrandLogo:       `img`,
+	BrandModelCount: `.modelcount`,
+	BrandInfo:       `.brandinfo dl`,
+	ModelList:       `.carmod a`,
+	ModelName:       `h4`,
+	ModelImage:      `img`,
+	ModelYears:      `.years`,
+	ModelStatus:     `.prodstatus`,
+	MotoList:        `.carmodel`,
+	MotoName:        `span[itemprop="name"]`,
+	MotoYears:       `p[class="years"]`,
+	MotoLink:        `a[itemprop="url"]`,
+	SpecTable:       `.enginedata`,
+	SpecKey:         `dt em`,
+	SpecValue:       `dd`,
+	Description:     `div[itemprop="description"] p`,
+	ModelInfo:       `.modelinfo dl`,
+	Gallery:         `.s_gallery a`,
+	Related:         `.relatedmodels a`,
+	Option:          `option`,
+	SpecCell:        `.table_info td`,
+}
+
+// WithDefaults fills every empty selector from DefaultSelectors, so a
+// config only has to name the selectors it changes.
+func (s Selectors) WithDefaults() Selectors {
+	v := reflect.ValueOf(&s).Elem()
+	d := reflect.ValueOf(DefaultSelectors)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).String() == "" {
+			v.Field(i).SetString(d.Field(i).String())
+		}
+	}
+	return s
+}
@@ -0,0 +1,178 @@
+package motospec
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+type ErrStatus struct {
+	URL        string
+	StatusCode int
+	Header     http.Header
+}
+
+func (e *ErrStatus) Error() string {
+	return fmt.Sprintf("%s: unexpected status %d", e.URL, e.StatusCode)
+}
+
+type ErrFetch struct {
+	URL     string
+	Err     error
+	Timeout bool
+}
+
+func (e *ErrFetch) Error() string {
+	return fmt.Sprintf("%s: %v", e.URL, e.Err)
+}
+
+// Session is the state one site shares across all processors: a cookie jar
+// and the http client using it.
+type Session struct {
+	Name       string
+	Jar        *Jar
+	Client     *http.Client
+	CookieFile string
+	Proxies    *ProxyPool
+	Limiter    *RateLimiter
+	Backoff    time.Duration
+}
+
+func NewSession(name, cookieFile string, timeout int) (*Session, error) {
+	jar := NewJar()
+	if cookieFile != "" {
+		var err error
+		if jar, err = LoadJar(cookieFile); err != nil {
+			return nil, fmt.Errorf("load cookies of %s: %v", name, err)
+		}
+	}
+	return &Session{
+		Name:       name,
+		Jar:        jar,
+		Client:     &http.Client{Jar: jar, Timeout: time.Duration(timeout) * time.Second},
+		CookieFile: cookieFile,
+		Backoff:    time.Second,
+	}, nil
+}
+
+func (s *Session) UseProxies(pool *ProxyPool) {
+	s.Proxies = pool
+	s.Client.Transport = pool.Transport()
+}
+
+func (s *Session) Save() error {
+	if s.CookieFile == "" {
+		return nil
+	}
+	return s.Jar.Save(s.CookieFile)
+}
+
+// Do sends req, retrying network errors, 429 and 5xx responses, and returns
+// the body of a 200 response. Without a limiter, which spaces the retries
+// itself, it waits Backoff before the first retry, doubled every time, or
+// what Retry-After asks.
+func (s *Session) Do(ctx context.Context, req *http.Request, retries int) ([]byte, error) {
+	var err error
+	wait := s.Backoff
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 && s.Limiter == nil {
+			if e, ok := err.(*ErrStatus); ok {
+				if after, ok := RetryAfter(e.Header, time.Now()); ok && after > wait {
+					wait = after
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+			wait *= 2
+		}
+		var body []byte
+		body, err = s.do(ctx, req)
+		if err == nil {
+			return body, nil
+		}
+		if e, ok := err.(*ErrStatus); ok && e.StatusCode < 500 && e.StatusCode != http.StatusTooManyRequests {
+			// through a pool another proxy may still get past a block
+			if s.Proxies == nil || e.StatusCode != http.StatusForbidden {
+				return nil, err
+			}
+		}
+	}
+	return nil, err
+}
+
+func (s *Session) do(ctx context.Context, req *http.Request) ([]byte, error) {
+	host := req.URL.Host
+	if s.Limiter != nil {
+		if err := s.Limiter.Wait(ctx, host); err != nil {
+			return nil, err
+		}
+	}
+	var proxy *Proxy
+	if s.Proxies != nil {
+		proxy = s.Proxies.Pick(host)
+		ctx = context.WithValue(ctx, proxyKey{}, proxy)
+	}
+	start := time.Now()
+	body, err := s.send(ctx, req)
+	if proxy != nil {
+		s.Proxies.Report(proxy, host, err)
+	}
+	if s.Limiter != nil {
+		s.Limiter.Observe(host, err, time.Since(start))
+	}
+	return body, err
+}
+
+func (s *Session) send(ctx context.Context, req *http.Request) ([]byte, error) {
+	if req.GetBody != nil {
+		// a retried POST needs its body again
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+	resp, err := s.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		timeout := false
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			timeout = true
+		}
+		return nil, &ErrFetch{URL: req.URL.String(), Err: err, Timeout: timeout}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		ioutil.ReadAll(resp.Body)
+		return nil, &ErrStatus{URL: req.URL.String(), StatusCode: resp.StatusCode, Header: resp.Header}
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &ErrFetch{URL: req.URL.String(), Err: err}
+	}
+	return body, nil
+}
+
+// WarmUp visits urls in order so the site can hand out its session and
+// consent cookies before the crawl starts.
+func (s *Session) WarmUp(ctx context.Context, urls []string, headers *HeaderProfile) error {
+	for _, u := range urls {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			return err
+		}
+		if headers != nil {
+			headers.Apply(req)
+		}
+		if _, err := s.Do(ctx, req, 1); err != nil {
+			return fmt.Errorf("warm up %s: %v", s.Name, err)
+		}
+		ProcessorLogger.Printf("Session %s: warmed up with %s", s.Name, u)
+	}
+	return nil
+}
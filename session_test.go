@@ -0,0 +1,69 @@
+package motospec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// statusServer answers with the statuses given, then with 200, and a
+// Retry-After of retryAfter on 429 and 503.
+func statusServer(retryAfter string, statuses ...int) (*httptest.Server, *int) {
+	var mutex sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		status := http.StatusOK
+		if requests < len(statuses) {
+			status = statuses[requests]
+		}
+		requests++
+		if retryAfter != "" && (status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable) {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte("<html></html>"))
+	}))
+	return server, &requests
+}
+
+func TestSessionDo(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		ok         bool
+		requests   int
+		wait       time.Duration
+	}{
+		{"ok", nil, "", true, 1, 0},
+		{"429", []int{429}, "", true, 2, 0},
+		{"429 retry after", []int{429}, "1", true, 2, time.Second},
+		{"503 twice", []int{503, 503}, "", true, 3, 0},
+		{"500 beyond retries", []int{500, 500, 500}, "", false, 3, 0},
+		{"404", []int{404}, "", false, 1, 0},
+		{"403 without proxies", []int{403}, "", false, 1, 0},
+	}
+	for _, test := range tests {
+		server, requests := statusServer(test.retryAfter, test.statuses...)
+		session, err := NewSession("test", "", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		session.Backoff = time.Millisecond
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		start := time.Now()
+		_, err = session.Do(context.Background(), req, 2)
+		server.Close()
+		if (err == nil) != test.ok || *requests != test.requests {
+			t.Errorf("%s: error %v after %d requests, want %d", test.name, err, *requests, test.requests)
+		}
+		if elapsed := time.Since(start); elapsed < test.wait {
+			t.Errorf("%s: retried after %s, want %s", test.name, elapsed, test.wait)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package motospec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type Sink interface {
+	Write(record interface{}) error
+	Close() error
+}
+
+type JSONSink struct {
+	file    *os.File
+	encoder *json.Encoder
+	mutex   sync.Mutex
+}
+
+func NewJSONSink(path string) (*JSONSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0664)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONSink{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+func (s *JSONSink) Write(record interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.encoder.Encode(record)
+}
+
+func (s *JSONSink) Close() error {
+	return s.file.Close()
+}
+
+type EntitySink struct {
+	Brands Sink
+	Models Sink
+	Specs  Sink
+}
+
+func (s *EntitySink) Write(record interface{}) error {
+	var sink Sink
+	switch record.(type) {
+	case Brand:
+		sink = s.Brands
+	case Model:
+		sink = s.Models
+	case Spec:
+		sink = s.Specs
+	default:
+		return fmt.Errorf("no sink for %T", record)
+	}
+	if sink == nil {
+		return nil
+	}
+	return sink.Write(record)
+}
+
+func (s *EntitySink) Close() error {
+	var first error
+	for _, sink := range []Sink{s.Brands, s.Models, s.Specs} {
+		if sink == nil {
+			continue
+		}
+		if err := sink.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+type JSONArraySink struct {
+	file  *os.File
+	count int
+	mutex sync.Mutex
+}
+
+func NewJSONArraySink(path string) (*JSONArraySink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0664)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.WriteString("[\n"); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &JSONArraySink{file: file}, nil
+}
+
+func (s *JSONArraySink) Write(record interface{}) error {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.count > 0 {
+		if _, err := s.file.WriteString(",\n"); err != nil {
+			return err
+		}
+	}
+	s.count++
+	_, err = s.file.Write(content)
+	return err
+}
+
+func (s *JSONArraySink) Close() error {
+	if _, err := s.file.WriteString("\n]\n"); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+func NewFileSink(path, format string) (Sink, error) {
+	switch format {
+	case "", "ndjson":
+		return NewJSONSink(path)
+	case "json":
+		return NewJSONArraySink(path)
+	}
+	return nil, fmt.Errorf("unknown sink format %q", format)
+}
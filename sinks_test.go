@@ -0,0 +1,108 @@
+package motospec
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memorySink keeps what it is written, for tests.
+type memorySink struct {
+	records []interface{}
+	mutex   sync.Mutex
+}
+
+func (s *memorySink) Write(record interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+func TestEntitySink(t *testing.T) {
+	brands, models, specs := &memorySink{}, &memorySink{}, &memorySink{}
+	tests := []struct {
+		name   string
+		sink   *EntitySink
+		record interface{}
+		want   *memorySink
+		ok     bool
+	}{
+		{"brand", &EntitySink{Brands: brands, Models: models, Specs: specs}, Brand{Name: "Ducati"}, brands, true},
+		{"model", &EntitySink{Brands: brands, Models: models, Specs: specs}, Model{Brand: "Ducati", Name: "Monster"}, models, true},
+		{"spec", &EntitySink{Brands: brands, Models: models, Specs: specs}, Spec{Brand: "Ducati", Model: "Monster"}, specs, true},
+		{"no model sink", &EntitySink{Specs: specs}, Model{Brand: "Ducati", Name: "Monster"}, nil, true},
+		{"unknown record", &EntitySink{Brands: brands, Models: models, Specs: specs}, "Ducati", nil, false},
+	}
+	for _, test := range tests {
+		counts := []int{len(brands.records), len(models.records), len(specs.records)}
+		err := test.sink.Write(test.record)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: got %v", test.name, err)
+		}
+		for i, sink := range []*memorySink{brands, models, specs} {
+			want := 0
+			if sink == test.want {
+				want = 1
+			}
+			if written := len(sink.records) - counts[i]; written != want {
+				t.Errorf("%s: %d records written to sink %d, want %d", test.name, written, i, want)
+			}
+		}
+	}
+}
+
+func TestNewFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		format  string
+		records int
+		want    string
+		ok      bool
+	}{
+		{"", 2, "{\"name\":\"Ducati\",\"url\":\"\",\"model_count\":0}\n{\"name\":\"Ducati\",\"url\":\"\",\"model_count\":0}\n", true},
+		{"json", 0, "[\n\n]\n", true},
+		{"json", 2, "[\n{\"name\":\"Ducati\",\"url\":\"\",\"model_count\":0},\n{\"name\":\"Ducati\",\"url\":\"\",\"model_count\":0}\n]\n", true},
+		{"csv", 0, "", false},
+	}
+	for i, test := range tests {
+		path := filepath.Join(dir, strings.Repeat("x", i+1))
+		sink, err := NewFileSink(path, test.format)
+		if (err == nil) != test.ok {
+			t.Errorf("%q: got %v", test.format, err)
+		}
+		if err != nil {
+			continue
+		}
+		for j := 0; j < test.records; j++ {
+			if err := sink.Write(Brand{Name: "Ducati"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadFile(path)
+		if string(content) != test.want {
+			t.Errorf("%q: wrote %q, want %q", test.format, content, test.want)
+		}
+		if test.format == "json" {
+			var brands []Brand
+			if err := json.Unmarshal(content, &brands); err != nil || len(brands) != test.records {
+				t.Errorf("%q: %d brands read back, %v", test.format, len(brands), err)
+			}
+		}
+	}
+}
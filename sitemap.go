@@ -0,0 +1,217 @@
+package motospec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type SitemapEntry struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type sitemapLoc struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+type sitemapDoc struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+var lastModLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+}
+
+func parseLastMod(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// MaxSitemapSize is the largest sitemap read, after decompression: the
+// sitemaps protocol caps a sitemap at 50MB.
+const MaxSitemapSize = 50 * 1024 * 1024
+
+// readLimited reads r, failing when it holds more than MaxSitemapSize.
+func readLimited(r io.Reader) ([]byte, error) {
+	content, err := ioutil.ReadAll(io.LimitReader(r, MaxSitemapSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > MaxSitemapSize {
+		return nil, fmt.Errorf("sitemap larger than %d bytes", MaxSitemapSize)
+	}
+	return content, nil
+}
+
+// ParseSitemap reads a urlset or a sitemapindex, gzipped or not. A urlset
+// fills pages, a sitemapindex fills sitemaps.
+func ParseSitemap(r io.Reader) (pages, sitemaps []SitemapEntry, err error) {
+	content, err := readLimited(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(content) > 2 && content[0] == 0x1f && content[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, nil, err
+		}
+		if content, err = readLimited(gz); err != nil {
+			return nil, nil, err
+		}
+	}
+	doc := sitemapDoc{}
+	if err := xml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, err
+	}
+	switch doc.XMLName.Local {
+	case "urlset", "sitemapindex":
+	default:
+		return nil, nil, fmt.Errorf("unexpected root element <%s>", doc.XMLName.Local)
+	}
+	for _, loc := range doc.URLs {
+		if loc.Loc = strings.TrimSpace(loc.Loc); loc.Loc != "" {
+			pages = append(pages, SitemapEntry{Loc: loc.Loc, LastMod: parseLastMod(loc.LastMod)})
+		}
+	}
+	for _, loc := range doc.Sitemaps {
+		if loc.Loc = strings.TrimSpace(loc.Loc); loc.Loc != "" {
+			sitemaps = append(sitemaps, SitemapEntry{Loc: loc.Loc, LastMod: parseLastMod(loc.LastMod)})
+		}
+	}
+	return pages, sitemaps, nil
+}
+
+// SitemapReader walks sitemaps and sitemap indexes and yields the pages
+// matching one of Patterns, every page when there are none.
+type SitemapReader struct {
+	Session  *Session
+	Headers  *HeaderProfile
+	Robots   *Robots
+	Patterns []*regexp.Regexp
+	Since    time.Time
+	Retries  int
+	MaxDepth int
+}
+
+func NewSitemapReader(session *Session, patterns []*regexp.Regexp) *SitemapReader {
+	return &SitemapReader{
+		Session:  session,
+		Patterns: patterns,
+		Retries:  3,
+		MaxDepth: 4,
+	}
+}
+
+func (s *SitemapReader) match(entry SitemapEntry) bool {
+	if !s.Since.IsZero() && !entry.LastMod.IsZero() && entry.LastMod.Before(s.Since) {
+		return false
+	}
+	return len(s.Patterns) == 0 || matchAny(s.Patterns, entry.Loc)
+}
+
+// Discover returns the sitemaps robots.txt announces for the host of
+// rawURL, or its /sitemap.xml when there are none.
+func (s *SitemapReader) Discover(ctx context.Context, rawURL string) ([]string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if s.Robots != nil {
+		if rules := s.Robots.Rules(ctx, u); len(rules.Sitemaps) > 0 {
+			return rules.Sitemaps, nil
+		}
+	}
+	return []string{u.Scheme + "://" + u.Host + "/sitemap.xml"}, nil
+}
+
+// Walk calls fn with every matching page below the given sitemaps. A
+// sitemap failing below an index is logged and skipped, fn failing stops
+// the walk.
+func (s *SitemapReader) Walk(ctx context.Context, sitemaps []string, fn func(SitemapEntry) error) error {
+	seen := make(map[string]bool)
+	for _, sitemap := range sitemaps {
+		if err := s.walk(ctx, sitemap, 0, seen, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SitemapReader) walk(ctx context.Context, sitemap string, depth int, seen map[string]bool, fn func(SitemapEntry) error) error {
+	if seen[sitemap] {
+		return nil
+	}
+	seen[sitemap] = true
+	pages, children, err := s.fetch(ctx, sitemap)
+	if err != nil {
+		if depth == 0 {
+			return err
+		}
+		ErrClientLogger.Printf("Sitemap %s: %v", sitemap, err)
+		return nil
+	}
+	ProcessorLogger.Printf("Sitemap %s: %d pages, %d sitemaps", sitemap, len(pages), len(children))
+	for _, page := range pages {
+		if !s.match(page) {
+			continue
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	for _, child := range children {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if depth+1 >= s.MaxDepth {
+			ErrClientLogger.Printf("Sitemap %s: nested deeper than %d, skipped", child.Loc, s.MaxDepth)
+			continue
+		}
+		// an index entry older than Since cannot list newer pages
+		if !s.Since.IsZero() && !child.LastMod.IsZero() && child.LastMod.Before(s.Since) {
+			continue
+		}
+		if err := s.walk(ctx, child.Loc, depth+1, seen, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SitemapReader) fetch(ctx context.Context, sitemap string) ([]SitemapEntry, []SitemapEntry, error) {
+	req, err := http.NewRequest("GET", sitemap, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.Robots != nil && !s.Robots.Allowed(ctx, req.URL) {
+		return nil, nil, &ErrDisallowed{URL: sitemap}
+	}
+	if s.Headers != nil {
+		s.Headers.Apply(req)
+	}
+	body, err := s.Session.Do(ctx, req, s.Retries)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ParseSitemap(bytes.NewReader(body))
+}
@@ -0,0 +1,93 @@
+package motospec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+)
+
+func gzipped(content []byte) []byte {
+	var buffer bytes.Buffer
+	gz := gzip.NewWriter(&buffer)
+	gz.Write(content)
+	gz.Close()
+	return buffer.Bytes()
+}
+
+const testURLSet = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc> https://example.com/moto/a.html </loc><lastmod>2026-01-02</lastmod></url>
+  <url><loc>https://example.com/moto/b.html</loc><lastmod>2026-01-02T10:00:00+00:00</lastmod></url>
+  <url><loc></loc></url>
+</urlset>`
+
+const testSitemapIndex = `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/sitemap-1.xml.gz</loc></sitemap>
+</sitemapindex>`
+
+func TestParseSitemap(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         []byte
+		pages, sitemaps int
+		ok              bool
+	}{
+		{"urlset", []byte(testURLSet), 2, 0, true},
+		{"gzipped urlset", gzipped([]byte(testURLSet)), 2, 0, true},
+		{"index", []byte(testSitemapIndex), 0, 1, true},
+		{"html", []byte("<html><body></body></html>"), 0, 0, false},
+		{"broken", []byte("<urlset><url>"), 0, 0, false},
+		{"gzip bomb", gzipped(make([]byte, MaxSitemapSize+1)), 0, 0, false},
+	}
+	for _, test := range tests {
+		pages, sitemaps, err := ParseSitemap(bytes.NewReader(test.content))
+		if (err == nil) != test.ok {
+			t.Errorf("%s: got %v", test.name, err)
+			continue
+		}
+		if len(pages) != test.pages || len(sitemaps) != test.sitemaps {
+			t.Errorf("%s: %d pages and %d sitemaps, want %d and %d", test.name, len(pages), len(sitemaps), test.pages, test.sitemaps)
+		}
+	}
+}
+
+func TestParseLastMod(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2026-01-02", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{" 2026-01-02T10:30Z ", time.Date(2026, 1, 2, 10, 30, 0, 0, time.UTC)},
+		{"2026-01-02T10:30:00+00:00", time.Date(2026, 1, 2, 10, 30, 0, 0, time.UTC)},
+		{"yesterday", time.Time{}},
+	}
+	for _, test := range tests {
+		if got := parseLastMod(test.value); !got.Equal(test.want) {
+			t.Errorf("%q: got %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func TestSitemapMatch(t *testing.T) {
+	patterns, err := CompilePatterns([]string{`/moto/[^/]+\.html$`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := NewSitemapReader(nil, patterns)
+	reader.Since = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		entry SitemapEntry
+		want  bool
+	}{
+		{SitemapEntry{Loc: "https://example.com/moto/a.html", LastMod: reader.Since.AddDate(0, 0, 1)}, true},
+		{SitemapEntry{Loc: "https://example.com/moto/a.html"}, true},
+		{SitemapEntry{Loc: "https://example.com/moto/a.html", LastMod: reader.Since.AddDate(0, 0, -1)}, false},
+		{SitemapEntry{Loc: "https://example.com/moto/brand/"}, false},
+	}
+	for _, test := range tests {
+		if got := reader.match(test.entry); got != test.want {
+			t.Errorf("%s %s: got %v", test.entry.Loc, test.entry.LastMod, got)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package motospec
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot is an immutable version of the dataset. It stores only the
+// records that changed since Parent; a partial snapshot, of a crawl that
+// did not cover the whole site, removes none.
+type Snapshot struct {
+	ID      string    `json:"id"`
+	Parent  string    `json:"parent,omitempty"`
+	Time    time.Time `json:"time"`
+	Partial bool      `json:"partial,omitempty"`
+	Specs   int       `json:"specs"`
+	Added   int       `json:"added"`
+	Changed int       `json:"changed"`
+	Removed int       `json:"removed"`
+}
+
+// SnapshotRecord is a change of one variant, keyed by Spec.Key, its url.
+type SnapshotRecord struct {
+	Key     string `json:"key"`
+	Spec    *Spec  `json:"spec,omitempty"`
+	Removed bool   `json:"removed,omitempty"`
+}
+
+// SnapshotStore keeps snapshots in a directory: snapshots.json lists them
+// in order and <id>.json holds the records of each, one per line.
+type SnapshotStore struct {
+	Dir       string
+	snapshots []Snapshot
+}
+
+func OpenSnapshotStore(dir string) (*SnapshotStore, error) {
+	store := &SnapshotStore{Dir: dir, snapshots: []Snapshot{}}
+	content, err := ioutil.ReadFile(store.manifest())
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &store.snapshots); err != nil {
+		return nil, fmt.Errorf("%s: %v", store.manifest(), err)
+	}
+	return store, nil
+}
+
+func (s *SnapshotStore) manifest() string {
+	return filepath.Join(s.Dir, "snapshots.json")
+}
+
+func (s *SnapshotStore) recordsPath(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+func (s *SnapshotStore) Snapshots() []Snapshot {
+	return append([]Snapshot{}, s.snapshots...)
+}
+
+// Snapshot finds a snapshot by id or by a unique prefix of it; "latest"
+// names the last one.
+func (s *SnapshotStore) Snapshot(id string) (Snapshot, int, error) {
+	if id == "latest" && len(s.snapshots) > 0 {
+		return s.snapshots[len(s.snapshots)-1], len(s.snapshots) - 1, nil
+	}
+	found := -1
+	for i, snapshot := range s.snapshots {
+		if id != "" && strings.HasPrefix(snapshot.ID, id) {
+			if found >= 0 {
+				return Snapshot{}, 0, fmt.Errorf("snapshot %q is ambiguous", id)
+			}
+			found = i
+		}
+	}
+	if found < 0 {
+		return Snapshot{}, 0, fmt.Errorf("no snapshot %q", id)
+	}
+	return s.snapshots[found], found, nil
+}
+
+func (s *SnapshotStore) records(id string) ([]SnapshotRecord, error) {
+	file, err := os.Open(s.recordsPath(id))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	records := []SnapshotRecord{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record SnapshotRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s: %v", s.recordsPath(id), err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// replay applies the records of the snapshots up to and including index,
+// calling visit, when given, for every record on the way.
+func (s *SnapshotStore) replay(index int, visit func(Snapshot, SnapshotRecord)) (map[string]Spec, error) {
+	state := make(map[string]Spec)
+	for _, snapshot := range s.snapshots[:index+1] {
+		records, err := s.records(snapshot.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if record.Removed {
+				delete(state, record.Key)
+			} else if record.Spec != nil {
+				state[record.Key] = *record.Spec
+			}
+			if visit != nil {
+				visit(snapshot, record)
+			}
+		}
+	}
+	return state, nil
+}
+
+// As returns the dataset as of the snapshot id, ordered by key.
+func (s *SnapshotStore) As(id string) ([]Spec, error) {
+	_, index, err := s.Snapshot(id)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.replay(index, nil)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(state))
+	for key := range state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	specs := make([]Spec, len(keys))
+	for i, key := range keys {
+		specs[i] = state[key]
+	}
+	return specs, nil
+}
+
+func sameSpec(a, b Spec) bool {
+	ja, err1 := json.Marshal(a)
+	jb, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && bytes.Equal(ja, jb)
+}
+
+// CarryOver adds to specs the variants of the latest snapshot that specs
+// lacks and missed holds, so the next commit keeps them instead of
+// removing them.
+func (s *SnapshotStore) CarryOver(specs []Spec, missed func(Spec) bool) ([]Spec, error) {
+	if len(s.snapshots) == 0 {
+		return specs, nil
+	}
+	previous, err := s.replay(len(s.snapshots)-1, nil)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		current[spec.Key()] = true
+	}
+	for key, spec := range previous {
+		if !current[key] && missed(spec) {
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
+}
+
+// Commit stores specs as a new snapshot taken at t. Every crawl gets its
+// snapshot, also one that changed nothing.
+func (s *SnapshotStore) Commit(specs []Spec, t time.Time, partial bool) (Snapshot, error) {
+	previous := make(map[string]Spec)
+	snapshot := Snapshot{Time: t.UTC(), Partial: partial}
+	if len(s.snapshots) > 0 {
+		var err error
+		if previous, err = s.replay(len(s.snapshots)-1, nil); err != nil {
+			return Snapshot{}, err
+		}
+		snapshot.Parent = s.snapshots[len(s.snapshots)-1].ID
+	}
+	current := make(map[string]Spec, len(specs))
+	for _, spec := range specs {
+		current[spec.Key()] = spec
+	}
+	records := []SnapshotRecord{}
+	for key, spec := range current {
+		spec := spec
+		old, ok := previous[key]
+		switch {
+		case !ok:
+			snapshot.Added++
+		case !sameSpec(old, spec):
+			snapshot.Changed++
+		default:
+			continue
+		}
+		records = append(records, SnapshotRecord{Key: key, Spec: &spec})
+	}
+	snapshot.Specs = len(current)
+	if partial {
+		for key := range previous {
+			if _, ok := current[key]; !ok {
+				snapshot.Specs++
+			}
+		}
+	} else {
+		for key := range previous {
+			if _, ok := current[key]; !ok {
+				records = append(records, SnapshotRecord{Key: key, Removed: true})
+				snapshot.Removed++
+			}
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return Snapshot{}, err
+		}
+	}
+	sum := sha1.New()
+	fmt.Fprintf(sum, "%s\n%s\n", snapshot.Parent, snapshot.Time.Format(time.RFC3339Nano))
+	sum.Write(body.Bytes())
+	snapshot.ID = hex.EncodeToString(sum.Sum(nil)[:6])
+	if err := os.MkdirAll(s.Dir, 0775); err != nil {
+		return Snapshot{}, err
+	}
+	// the records go first, so a failed commit leaves no snapshot pointing
+	// at missing records
+	if err := ioutil.WriteFile(s.recordsPath(snapshot.ID), body.Bytes(), 0664); err != nil {
+		return Snapshot{}, err
+	}
+	content, err := json.MarshalIndent(append(s.Snapshots(), snapshot), "", "  ")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	tmp := s.manifest() + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(content, '\n'), 0664); err != nil {
+		return Snapshot{}, err
+	}
+	if err := os.Rename(tmp, s.manifest()); err != nil {
+		return Snapshot{}, err
+	}
+	s.snapshots = append(s.snapshots, snapshot)
+	return snapshot, nil
+}
+
+// FieldVersion is the value a field had from Snapshot on. Removed marks
+// the snapshot the variant left the dataset in.
+type FieldVersion struct {
+	Snapshot string    `json:"snapshot"`
+	Time     time.Time `json:"time"`
+	Value    string    `json:"value"`
+	Removed  bool      `json:"removed,omitempty"`
+}
+
+// SpecField reads a field of a spec as diff names it: brand, model, type,
+// year, description or specs.<key>. Any other name is looked up among the
+// specs like SpecValue does.
+func SpecField(spec Spec, field string) string {
+	switch field {
+	case "brand":
+		return spec.Brand
+	case "model":
+		return spec.Model
+	case "type":
+		return spec.Moto
+	case "year":
+		return spec.Year
+	case "description":
+		return spec.Description
+	}
+	if strings.HasPrefix(field, "specs.") {
+		if value, ok := spec.Specs[strings.TrimPrefix(field, "specs.")]; ok {
+			return value
+		}
+		field = strings.TrimPrefix(field, "specs.")
+	}
+	return SpecValue(spec, strings.ToLower(field))
+}
+
+// History returns the values field of variant took over all snapshots, a
+// version per change. variant is the key of a spec, its url, or its SpecID.
+func (s *SnapshotStore) History(variant, field string) ([]FieldVersion, error) {
+	versions := []FieldVersion{}
+	if len(s.snapshots) == 0 {
+		return versions, nil
+	}
+	_, err := s.replay(len(s.snapshots)-1, func(snapshot Snapshot, record SnapshotRecord) {
+		if record.Key != variant && keyID(record.Key) != variant {
+			return
+		}
+		version := FieldVersion{Snapshot: snapshot.ID, Time: snapshot.Time, Removed: record.Removed}
+		if record.Spec != nil {
+			version.Value = SpecField(*record.Spec, field)
+		}
+		if n := len(versions); n > 0 && versions[n-1].Value == version.Value && versions[n-1].Removed == version.Removed {
+			return
+		}
+		versions = append(versions, version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
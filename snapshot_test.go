@@ -0,0 +1,149 @@
+package motospec
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func testSpec(url, power string) Spec {
+	return Spec{Brand: "Ducati", Model: "Monster", Moto: url, Year: "2020", URL: "https://example.com/" + url,
+		Specs: map[string]string{"Power": power}}
+}
+
+func openTestStore(t *testing.T) (*SnapshotStore, func()) {
+	dir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenSnapshotStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func TestSnapshotCommit(t *testing.T) {
+	store, cleanup := openTestStore(t)
+	defer cleanup()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := []struct {
+		specs                          []Spec
+		partial                        bool
+		total, added, changed, removed int
+		datasetAfter                   int
+	}{
+		{specs: []Spec{testSpec("a", "100 HP"), testSpec("b", "110 HP"), testSpec("c", "120 HP")}, total: 3, added: 3, datasetAfter: 3},
+		// a crawl of part of the site: partial, nothing removed
+		{specs: []Spec{testSpec("a", "101 HP")}, partial: true, total: 3, changed: 1, datasetAfter: 3},
+		{specs: []Spec{testSpec("a", "101 HP"), testSpec("b", "110 HP")}, total: 2, removed: 1, datasetAfter: 2},
+		{specs: []Spec{testSpec("a", "101 HP"), testSpec("b", "110 HP"), testSpec("c", "120 HP")}, total: 3, added: 1, datasetAfter: 3},
+	}
+	for i, c := range commits {
+		snapshot, err := store.Commit(c.specs, start.Add(time.Duration(i)*time.Hour), c.partial)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snapshot.Specs != c.total || snapshot.Added != c.added || snapshot.Changed != c.changed || snapshot.Removed != c.removed {
+			t.Errorf("commit %d: got %d specs, %d added, %d changed, %d removed, want %d, %d, %d, %d", i,
+				snapshot.Specs, snapshot.Added, snapshot.Changed, snapshot.Removed, c.total, c.added, c.changed, c.removed)
+		}
+		specs, err := store.As(snapshot.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(specs) != c.datasetAfter {
+			t.Errorf("commit %d: dataset of %d specs, want %d", i, len(specs), c.datasetAfter)
+		}
+	}
+	reopened, err := OpenSnapshotStore(store.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(reopened.Snapshots()); n != len(commits) {
+		t.Errorf("reopened store has %d snapshots, want %d", n, len(commits))
+	}
+}
+
+func TestSnapshotCarryOver(t *testing.T) {
+	store, cleanup := openTestStore(t)
+	defer cleanup()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := store.Commit([]Spec{testSpec("a", "100 HP"), testSpec("b", "110 HP"), testSpec("c", "120 HP")}, start, false); err != nil {
+		t.Fatal(err)
+	}
+	// the page of b failed and c is gone from the site
+	missed := &Missed{Inputs: []interface{}{MotoURL{URL: "https://example.com/b"}}}
+	specs, err := store.CarryOver([]Spec{testSpec("a", "101 HP")}, missed.Has)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := store.Commit(specs, start.Add(time.Hour), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Specs != 2 || snapshot.Changed != 1 || snapshot.Removed != 1 {
+		t.Errorf("got %d specs, %d changed, %d removed, want 2, 1, 1", snapshot.Specs, snapshot.Changed, snapshot.Removed)
+	}
+	dataset, err := store.As(snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := make(map[string]string)
+	for _, spec := range dataset {
+		kept[spec.Moto] = spec.Specs["Power"]
+	}
+	if len(kept) != 2 || kept["a"] != "101 HP" || kept["b"] != "110 HP" {
+		t.Errorf("dataset holds %v, want a at 101 HP and b at 110 HP", kept)
+	}
+}
+
+func TestSnapshotHistory(t *testing.T) {
+	store, cleanup := openTestStore(t)
+	defer cleanup()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	runs := []struct {
+		specs   []Spec
+		partial bool
+	}{
+		{[]Spec{testSpec("a", "100 HP"), testSpec("b", "110 HP")}, false},
+		{[]Spec{testSpec("a", "100 HP")}, true},
+		{[]Spec{testSpec("a", "105 HP"), testSpec("b", "110 HP")}, false},
+		{[]Spec{testSpec("a", "105 HP")}, false},
+	}
+	ids := []string{}
+	for i, run := range runs {
+		snapshot, err := store.Commit(run.specs, start.Add(time.Duration(i)*time.Hour), run.partial)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, snapshot.ID)
+	}
+	tests := []struct {
+		variant, field string
+		want           []FieldVersion
+	}{
+		{"https://example.com/a", "specs.Power", []FieldVersion{{Snapshot: ids[0], Value: "100 HP"}, {Snapshot: ids[2], Value: "105 HP"}}},
+		{SpecID(Spec{URL: "https://example.com/a"}), "power", []FieldVersion{{Snapshot: ids[0], Value: "100 HP"}, {Snapshot: ids[2], Value: "105 HP"}}},
+		// the partial run left b out without removing it
+		{"https://example.com/b", "model", []FieldVersion{{Snapshot: ids[0], Value: "Monster"}, {Snapshot: ids[3], Removed: true}}},
+		{"https://example.com/none", "model", []FieldVersion{}},
+	}
+	for _, test := range tests {
+		versions, err := store.History(test.variant, test.field)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != len(test.want) {
+			t.Errorf("%s %s: got %+v, want %+v", test.variant, test.field, versions, test.want)
+			continue
+		}
+		for i, want := range test.want {
+			got := versions[i]
+			if got.Snapshot != want.Snapshot || got.Value != want.Value || got.Removed != want.Removed {
+				t.Errorf("%s %s version %d: got %+v, want %+v", test.variant, test.field, i, got, want)
+			}
+		}
+	}
+}
@@ -1,39 +1,19 @@
 package motospec
 
-// type Manufacturer struct {
-// 	Name  string
-// 	Value string
-// }
-//
-// type Category struct {
-// 	Manufacturer Manufacturer
-// 	Name         string
-// 	Value        string
-// }
-//
-// type Year struct {
-// 	Category Category
-// 	Name     string
-// 	Value    string
-// }
-//
-// type Model struct {
-// 	Year  Year
-// 	Name  string
-// 	Value string
-// }
-
-// type Spec map[string]string
-
 type BrandURL struct {
-	Brand string
-	URL   string
+	Brand      string
+	URL        string
+	Logo       string
+	ModelCount int
 }
 
 type ModelURL struct {
-	Brand string
-	Model string
-	URL   string
+	Brand  string
+	Model  string
+	URL    string
+	Image  string
+	Years  string
+	Status string
 }
 
 type MotoURL struct {
@@ -44,10 +24,53 @@ type MotoURL struct {
 	URL   string
 }
 
+type Brand struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Logo       string `json:"logo,omitempty"`
+	Country    string `json:"country,omitempty"`
+	Founded    string `json:"founded,omitempty"`
+	ModelCount int    `json:"model_count"`
+	Status     string `json:"status,omitempty"`
+}
+
+type Model struct {
+	Brand        string `json:"brand"`
+	Name         string `json:"name"`
+	URL          string `json:"url"`
+	Image        string `json:"image,omitempty"`
+	Years        string `json:"years,omitempty"`
+	Status       string `json:"status,omitempty"`
+	VariantCount int    `json:"variant_count"`
+}
+
+type Image struct {
+	URL  string `json:"url"`
+	Hash string `json:"hash,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type Link struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type SpecPage struct {
+	Description string  `json:"description,omitempty"`
+	Segment     string  `json:"segment,omitempty"`
+	BodyStyle   string  `json:"body_style,omitempty"`
+	Images      []Image `json:"images,omitempty"`
+	Related     []Link  `json:"related,omitempty"`
+}
+
 type Spec struct {
-	Brand string            `json:"brand"`
-	Model string            `json:"model"`
-	Moto  string            `json:"type"`
-	Year  string            `json:"year"`
-	Specs map[string]string `json:"specs"`
+	Source string            `json:"source,omitempty"`
+	Brand  string            `json:"brand"`
+	Model  string            `json:"model"`
+	Moto   string            `json:"type"`
+	Year   string            `json:"year"`
+	URL    string            `json:"url,omitempty"`
+	Specs  map[string]string `json:"specs"`
+	SpecPage
+	Derived *Derived `json:"derived,omitempty"`
 }